@@ -4,8 +4,10 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -20,16 +22,50 @@ var subResources map[string]bool = map[string]bool{
 	"partNumber":    true,
 	"delete":        true,
 	"deduplication": true,
+
+	"response-content-type":        true,
+	"response-content-language":    true,
+	"response-expires":             true,
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
 }
 
 func SignRequest(request *http.Request, publicKey string, secretKey string,
 	bucket string, encodedObject string) string {
 
+	return SignRequestWithToken(request, publicKey, secretKey, "", bucket, encodedObject)
+}
+
+// SignRequestWithToken is like SignRequest but for temporary credentials:
+// a non-empty securityToken is set as the request's x-nos-security-token
+// header, which is signed along with the other x-nos- headers.
+func SignRequestWithToken(request *http.Request, publicKey string, secretKey string, securityToken string,
+	bucket string, encodedObject string) string {
+
+	if securityToken != "" {
+		request.Header.Set(nosconst.X_NOS_SECURITY_TOKEN, securityToken)
+	}
+	stringToSign := getStringToSign(request, request.Header.Get("Date"), bucket, encodedObject)
+	return "NOS " + publicKey + ":" + sign(secretKey, stringToSign)
+}
+
+// PresignRequest returns the signature for a query-string authenticated URL.
+// It follows the same rules as SignRequest, except that the expiry time, in
+// seconds since the epoch, takes the place of the Date header.
+func PresignRequest(request *http.Request, secretKey string, bucket string,
+	encodedObject string, expires int64) string {
+
+	stringToSign := getStringToSign(request, strconv.FormatInt(expires, 10), bucket, encodedObject)
+	return sign(secretKey, stringToSign)
+}
+
+func getStringToSign(request *http.Request, date string, bucket string, encodedObject string) string {
 	stringToSign := ""
 	stringToSign += (request.Method + "\n")
 	stringToSign += (request.Header.Get("Content-MD5") + "\n")
 	stringToSign += (request.Header.Get("Content-Type") + "\n")
-	stringToSign += (request.Header.Get("Date") + "\n")
+	stringToSign += (date + "\n")
 
 	var headerKeys sort.StringSlice
 	for origKey, _ := range request.Header {
@@ -71,10 +107,14 @@ func SignRequest(request *http.Request, publicKey string, secretKey string,
 			stringToSign += "&"
 		}
 	}
+	return stringToSign
+}
+
+func sign(secretKey string, stringToSign string) string {
 	key := []byte(secretKey)
 	h := hmac.New(sha256.New, key)
 	h.Write([]byte(stringToSign))
-	return "NOS " + publicKey + ":" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
 func getResource(bucket string, encodedObject string) string {
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosclient"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type bucketEntry struct {
+	Name         string `json:"name"`
+	CreationDate string `json:"creationDate"`
+}
+
+type objectEntry struct {
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	LastModified string `json:"lastModified"`
+	Etag         string `json:"etag"`
+}
+
+type listing struct {
+	Objects  []objectEntry `json:"objects"`
+	Prefixes []string      `json:"prefixes"`
+}
+
+func (a *app) ls(args []string) error {
+	flags := flag.NewFlagSet("ls", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "list every object below the prefix")
+	if err := a.parseFlags(flags, args, 0, 1); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		result, err := a.client.ListBucketsWithContext(a.ctx)
+		if err != nil {
+			return err
+		}
+		buckets := []bucketEntry{}
+		for _, bucket := range result.Buckets {
+			buckets = append(buckets, bucketEntry{Name: bucket.Name, CreationDate: bucket.CreationDate})
+		}
+		return a.output(buckets, func(w io.Writer) {
+			for _, bucket := range buckets {
+				fmt.Fprintf(w, "%-24s  %s%s\n", bucket.CreationDate, nosScheme, bucket.Name)
+			}
+		})
+	}
+
+	u, err := parseNosUrl(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	request := &model.ListObjectsRequest{
+		Bucket: u.bucket,
+		Prefix: u.key,
+	}
+	if !*recursive {
+		request.Delimiter = "/"
+	}
+
+	result := listing{Objects: []objectEntry{}, Prefixes: []string{}}
+	paginator := a.client.NewObjectsPaginator(a.ctx, request)
+	for paginator.Next() {
+		for _, contents := range paginator.Page().Contents {
+			result.Objects = append(result.Objects, objectEntry{
+				Key:          contents.Key,
+				Size:         contents.Size,
+				LastModified: contents.LastModified,
+				Etag:         strings.Trim(contents.Etag, `"`),
+			})
+		}
+		for _, prefix := range paginator.Page().CommonPrefixes {
+			result.Prefixes = append(result.Prefixes, prefix.Prefix)
+		}
+	}
+	if err := paginator.Err(); err != nil {
+		return err
+	}
+
+	return a.output(result, func(w io.Writer) {
+		for _, prefix := range result.Prefixes {
+			fmt.Fprintf(w, "%-24s %12s  %s\n", "", "DIR", nosUrl{u.bucket, prefix})
+		}
+		for _, object := range result.Objects {
+			fmt.Fprintf(w, "%-24s %12d  %s\n", object.LastModified, object.Size, nosUrl{u.bucket, object.Key})
+		}
+	})
+}
+
+// transfer is a file or object copied or moved by cp or mv.
+type transfer struct {
+	Op          string `json:"op"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Size        int64  `json:"size"`
+
+	srcUrl, dstUrl nosUrl
+}
+
+func (a *app) cp(args []string) error {
+	return a.copy("cp", args, false)
+}
+
+func (a *app) mv(args []string) error {
+	return a.copy("mv", args, true)
+}
+
+func (a *app) copy(name string, args []string, move bool) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "copy every file below a directory or object below a prefix")
+	if err := a.parseFlags(flags, args, 2, 2); err != nil {
+		return err
+	}
+
+	transfers, err := a.planTransfers(flags.Arg(0), flags.Arg(1), *recursive)
+	if err != nil {
+		return err
+	}
+
+	done := []transfer{}
+	for _, t := range transfers {
+		if err := a.ctx.Err(); err != nil {
+			return err
+		}
+		if err := a.transfer(&t, move); err != nil {
+			return err
+		}
+		done = append(done, t)
+		if !a.json {
+			fmt.Fprintf(a.stdout, "%s %s -> %s\n", t.Op, t.Source, t.Destination)
+		}
+	}
+	if a.json {
+		return a.output(done, nil)
+	}
+	return nil
+}
+
+// planTransfers lists the transfers copying src to dst.
+func (a *app) planTransfers(src, dst string, recursive bool) ([]transfer, error) {
+	if !isNosUrl(src) && !isNosUrl(dst) {
+		return nil, fmt.Errorf("one of %s and %s must be a nos:// URL", src, dst)
+	}
+
+	var dstUrl nosUrl
+	if isNosUrl(dst) {
+		var err error
+		dstUrl, err = parseNosUrl(dst)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Each source is named by its path relative to src.
+	type source struct {
+		rel  string
+		path string
+		url  nosUrl
+		size int64
+	}
+	var sources []source
+
+	switch {
+	case !isNosUrl(src) && !recursive:
+		fi, err := os.Stat(src)
+		if err != nil {
+			return nil, err
+		}
+		if fi.IsDir() {
+			return nil, fmt.Errorf("%s is a directory (use -r)", src)
+		}
+		sources = append(sources, source{rel: filepath.Base(src), path: src, size: fi.Size()})
+
+	case !isNosUrl(src):
+		err := filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(src, file)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, source{rel: filepath.ToSlash(rel), path: file, size: info.Size()})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+	case !recursive:
+		srcUrl, err := parseObjectUrl(src)
+		if err != nil {
+			return nil, err
+		}
+		metadata, err := a.client.GetObjectMetaDataWithContext(a.ctx, &model.ObjectRequest{
+			Bucket: srcUrl.bucket,
+			Object: srcUrl.key,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source{rel: path.Base(srcUrl.key), url: srcUrl, size: metadata.ContentLength})
+
+	default:
+		srcUrl, err := parseNosUrl(src)
+		if err != nil {
+			return nil, err
+		}
+		prefix := dirPrefix(srcUrl.key)
+		objects, err := a.listObjects(srcUrl.bucket, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			rel := strings.TrimPrefix(object.Key, prefix)
+			if !isNosUrl(dst) && !utils.IsRelativePath(rel) {
+				// Directory markers and keys such as "../x" cannot be
+				// written below the destination directory.
+				continue
+			}
+			sources = append(sources, source{rel: rel, url: nosUrl{srcUrl.bucket, object.Key}, size: object.Size})
+		}
+	}
+
+	transfers := make([]transfer, 0, len(sources))
+	for _, s := range sources {
+		t := transfer{Size: s.size, srcUrl: s.url}
+		if isNosUrl(src) {
+			t.Source = s.url.String()
+		} else {
+			t.Source = s.path
+		}
+
+		if isNosUrl(dst) {
+			t.dstUrl = nosUrl{bucket: dstUrl.bucket}
+			switch {
+			case recursive:
+				t.dstUrl.key = dirPrefix(dstUrl.key) + s.rel
+			case dstUrl.key == "" || strings.HasSuffix(dstUrl.key, "/"):
+				t.dstUrl.key = dstUrl.key + s.rel
+			default:
+				t.dstUrl.key = dstUrl.key
+			}
+			t.Destination = t.dstUrl.String()
+		} else {
+			t.Destination = dst
+			if recursive {
+				t.Destination = filepath.Join(dst, filepath.FromSlash(s.rel))
+			} else if fi, err := os.Stat(dst); (err == nil && fi.IsDir()) || strings.HasSuffix(dst, string(filepath.Separator)) {
+				t.Destination = filepath.Join(dst, s.rel)
+			}
+		}
+
+		switch {
+		case !isNosUrl(src):
+			t.Op = "upload"
+		case !isNosUrl(dst):
+			t.Op = "download"
+		default:
+			t.Op = "copy"
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, nil
+}
+
+// transfer copies, or moves, one file or object.
+func (a *app) transfer(t *transfer, move bool) error {
+	switch t.Op {
+	case "upload":
+		var err error
+		if t.Size <= nosconst.DEFAULT_PARTSIZE {
+			_, err = a.client.PutObjectByFileWithContext(a.ctx, &model.PutObjectRequest{
+				Bucket:   t.dstUrl.bucket,
+				Object:   t.dstUrl.key,
+				FilePath: t.Source,
+			})
+		} else {
+			_, err = nosclient.NewUploader(a.client).UploadWithContext(a.ctx, &model.UploadRequest{
+				Bucket:   t.dstUrl.bucket,
+				Object:   t.dstUrl.key,
+				FilePath: t.Source,
+			})
+		}
+		if err == nil && move {
+			err = os.Remove(t.Source)
+		}
+		return err
+
+	case "download":
+		if err := os.MkdirAll(filepath.Dir(t.Destination), 0755); err != nil {
+			return err
+		}
+		metadata, err := nosclient.NewDownloader(a.client).DownloadWithContext(a.ctx, &model.DownloadRequest{
+			Bucket:   t.srcUrl.bucket,
+			Object:   t.srcUrl.key,
+			FilePath: t.Destination,
+		})
+		if err != nil {
+			return err
+		}
+		t.Size = metadata.ContentLength
+		if move {
+			return a.client.DeleteObjectWithContext(a.ctx, &model.ObjectRequest{
+				Bucket: t.srcUrl.bucket,
+				Object: t.srcUrl.key,
+			})
+		}
+		return nil
+
+	default:
+		if move {
+			t.Op = "move"
+			return a.client.MoveObjectWithContext(a.ctx, &model.MoveObjectRequest{
+				SrcBucket:  t.srcUrl.bucket,
+				SrcObject:  t.srcUrl.key,
+				DestBucket: t.dstUrl.bucket,
+				DestObject: t.dstUrl.key,
+			})
+		}
+		return a.client.CopyObjectWithContext(a.ctx, &model.CopyObjectRequest{
+			SrcBucket:  t.srcUrl.bucket,
+			SrcObject:  t.srcUrl.key,
+			DestBucket: t.dstUrl.bucket,
+			DestObject: t.dstUrl.key,
+		})
+	}
+}
+
+func (a *app) rm(args []string) error {
+	flags := flag.NewFlagSet("rm", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "delete every object below the prefix")
+	if err := a.parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+
+	var u nosUrl
+	var err error
+	if *recursive {
+		u, err = parseNosUrl(flags.Arg(0))
+	} else {
+		u, err = parseObjectUrl(flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	keys := []string{u.key}
+	if *recursive {
+		objects, err := a.listObjects(u.bucket, dirPrefix(u.key))
+		if err != nil {
+			return err
+		}
+		keys = keys[:0]
+		for _, object := range objects {
+			keys = append(keys, object.Key)
+		}
+		_, err = a.client.DeleteObjectsWithContext(a.ctx, u.bucket, keys)
+	} else {
+		err = a.client.DeleteObjectWithContext(a.ctx, &model.ObjectRequest{Bucket: u.bucket, Object: u.key})
+	}
+	if err != nil {
+		return err
+	}
+
+	deleted := []string{}
+	for _, key := range keys {
+		deleted = append(deleted, nosUrl{u.bucket, key}.String())
+	}
+	return a.output(deleted, func(w io.Writer) {
+		for _, url := range deleted {
+			fmt.Fprintln(w, "delete "+url)
+		}
+	})
+}
+
+type objectStat struct {
+	Bucket   string            `json:"bucket"`
+	Key      string            `json:"key"`
+	Size     int64             `json:"size"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+func (a *app) stat(args []string) error {
+	flags := flag.NewFlagSet("stat", flag.ContinueOnError)
+	if err := a.parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+	u, err := parseObjectUrl(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	metadata, err := a.client.GetObjectMetaDataWithContext(a.ctx, &model.ObjectRequest{
+		Bucket: u.bucket,
+		Object: u.key,
+	})
+	if err != nil {
+		return err
+	}
+
+	result := objectStat{
+		Bucket:   u.bucket,
+		Key:      u.key,
+		Size:     metadata.ContentLength,
+		Metadata: metadata.Metadata,
+	}
+	return a.output(result, func(w io.Writer) {
+		fmt.Fprintf(w, "%-20s %s\n", "Object:", u)
+		fmt.Fprintf(w, "%-20s %d\n", "Size:", result.Size)
+		names := make([]string, 0, len(result.Metadata))
+		for name := range result.Metadata {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "%-20s %s\n", name+":", result.Metadata[name])
+		}
+	})
+}
+
+func (a *app) cat(args []string) error {
+	flags := flag.NewFlagSet("cat", flag.ContinueOnError)
+	if err := a.parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+	u, err := parseObjectUrl(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	result, err := a.client.GetObjectWithContext(a.ctx, &model.GetObjectRequest{Bucket: u.bucket, Object: u.key})
+	if err != nil {
+		return err
+	}
+	defer result.Body.Close()
+
+	_, err = io.Copy(a.stdout, result.Body)
+	return err
+}
+
+func (a *app) mb(args []string) error {
+	flags := flag.NewFlagSet("mb", flag.ContinueOnError)
+	locationName := flags.String("location", nosconst.HZ.String(), "location constraint")
+	aclName := flags.String("acl", nosconst.PRIVATE.String(), "canned ACL")
+	if err := a.parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+	u, err := parseNosUrl(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	location, ok := nosconst.ParseLocation(*locationName)
+	if !ok {
+		return fmt.Errorf("unknown location %q", *locationName)
+	}
+	acl, ok := nosconst.ParseAcl(*aclName)
+	if !ok {
+		return fmt.Errorf("unknown ACL %q", *aclName)
+	}
+
+	if err := a.client.CreateBucketWithContext(a.ctx, u.bucket, location, acl); err != nil {
+		return err
+	}
+	return a.output(map[string]string{"bucket": u.bucket}, func(w io.Writer) {
+		fmt.Fprintln(w, "make_bucket "+nosScheme+u.bucket)
+	})
+}
+
+func (a *app) rb(args []string) error {
+	flags := flag.NewFlagSet("rb", flag.ContinueOnError)
+	force := flags.Bool("f", false, "delete the objects in the bucket first")
+	if err := a.parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+	u, err := parseNosUrl(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *force {
+		objects, err := a.listObjects(u.bucket, "")
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(objects))
+		for _, object := range objects {
+			keys = append(keys, object.Key)
+		}
+		if _, err := a.client.DeleteObjectsWithContext(a.ctx, u.bucket, keys); err != nil {
+			return err
+		}
+	}
+
+	if err := a.client.DeleteBucketWithContext(a.ctx, u.bucket); err != nil {
+		return err
+	}
+	return a.output(map[string]string{"bucket": u.bucket}, func(w io.Writer) {
+		fmt.Fprintln(w, "remove_bucket "+nosScheme+u.bucket)
+	})
+}
+
+func (a *app) presign(args []string) error {
+	flags := flag.NewFlagSet("presign", flag.ContinueOnError)
+	method := flags.String("method", "GET", "GET, PUT or HEAD")
+	expires := flags.Duration("expires", time.Hour, "how long the URL stays valid")
+	if err := a.parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+	u, err := parseObjectUrl(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	request := &model.PresignRequest{
+		Bucket:  u.bucket,
+		Object:  u.key,
+		Expires: *expires,
+	}
+	var url string
+	switch strings.ToUpper(*method) {
+	case "GET":
+		url, err = a.client.PresignGetObjectWithContext(a.ctx, request)
+	case "PUT":
+		url, err = a.client.PresignPutObjectWithContext(a.ctx, request)
+	case "HEAD":
+		url, err = a.client.PresignHeadObjectWithContext(a.ctx, request)
+	default:
+		return fmt.Errorf("cannot presign %s requests", *method)
+	}
+	if err != nil {
+		return err
+	}
+	return a.output(map[string]string{"url": url}, func(w io.Writer) {
+		fmt.Fprintln(w, url)
+	})
+}
+
+// listObjects lists every object below prefix.
+func (a *app) listObjects(bucket, prefix string) ([]model.Contents, error) {
+	var objects []model.Contents
+	paginator := a.client.NewObjectsPaginator(a.ctx, &model.ListObjectsRequest{
+		Bucket:  bucket,
+		Prefix:  prefix,
+		MaxKeys: nosconst.DEFAULTVALUE,
+	})
+	for paginator.Next() {
+		objects = append(objects, paginator.Page().Contents...)
+	}
+	return objects, paginator.Err()
+}
+
+// dirPrefix returns key as a directory prefix, ending with "/" unless it is
+// empty.
+func dirPrefix(key string) string {
+	if key == "" || strings.HasSuffix(key, "/") {
+		return key
+	}
+	return key + "/"
+}
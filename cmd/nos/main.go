@@ -0,0 +1,263 @@
+// Command nos manages buckets and objects in NOS.
+//
+// Usage:
+//
+//	nos [flags] <command> [command flags] [arguments]
+//
+// The commands are:
+//
+//	ls      [-r] [nos://bucket[/prefix]]   list buckets, or the objects below a prefix
+//	cp      [-r] <source> <destination>    copy files and objects
+//	mv      [-r] <source> <destination>    move files and objects
+//	rm      [-r] nos://bucket/key          delete objects
+//	stat    nos://bucket/key               show the metadata of an object
+//	cat     nos://bucket/key               write an object to standard output
+//	mb      [-location HZ] [-acl private] nos://bucket
+//	rb      [-f] nos://bucket              delete a bucket, with its objects if -f
+//	presign [-method GET] [-expires 1h] nos://bucket/key
+//
+// Objects are named nos://bucket/key; any other argument is a local path.
+// With -r, a source names every object below a key prefix, or every file
+// below a directory.
+//
+// The settings, such as the endpoint and credentials, come from the flags,
+// then from the environment variables read by config.LoadEnv, such as
+// NOS_ENDPOINT, NOS_ACCESS_KEY, NOS_SECRET_KEY and NOS_SECURITY_TOKEN, then
+// from a profile of a config file, as read by config.LoadFile. The config file
+// is named by -config or NOS_CONFIG_FILE, or else is ~/.nos/config.json if it
+// exists; the profile is named by -profile or NOS_PROFILE, or else is
+// "default":
+//
+//	{"endpoint": "nos-eastchina1.126.net", "accessKey": "...", "secretKey": "...", "isSubDomain": true}
+//
+// With -json, commands write their result as JSON instead of text.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/logger"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosclient"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const nosScheme = "nos://"
+
+// errUsage reports a command line error, whose message has been printed
+// along with the usage.
+var errUsage = errors.New("usage")
+
+type app struct {
+	ctx    context.Context
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+
+	json   bool
+	client *nosclient.NosClient
+}
+
+type command struct {
+	run   func(a *app, args []string) error
+	usage string
+}
+
+var commands = map[string]command{
+	"ls":      {(*app).ls, "ls [-r] [nos://bucket[/prefix]]"},
+	"cp":      {(*app).cp, "cp [-r] <source> <destination>"},
+	"mv":      {(*app).mv, "mv [-r] <source> <destination>"},
+	"rm":      {(*app).rm, "rm [-r] nos://bucket/key"},
+	"stat":    {(*app).stat, "stat nos://bucket/key"},
+	"cat":     {(*app).cat, "cat nos://bucket/key"},
+	"mb":      {(*app).mb, "mb [-location HZ] [-acl private] nos://bucket"},
+	"rb":      {(*app).rb, "rb [-f] nos://bucket"},
+	"presign": {(*app).presign, "presign [-method GET] [-expires 1h] nos://bucket/key"},
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		cancel()
+	}()
+
+	a := &app{
+		ctx:    ctx,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		getenv: os.Getenv,
+	}
+	os.Exit(a.run(os.Args[1:]))
+}
+
+// run runs the command line args and returns the exit status.
+func (a *app) run(args []string) int {
+	flags := flag.NewFlagSet("nos", flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	configFile := flags.String("config", "", "config file (default $NOS_CONFIG_FILE or ~/.nos/config.json)")
+	profile := flags.String("profile", "", "profile of the config file (default $NOS_PROFILE or default)")
+	endpoint := flags.String("endpoint", "", "NOS endpoint, a host or a URL")
+	accessKey := flags.String("access-key", "", "access key")
+	secretKey := flags.String("secret-key", "", "secret key")
+	subDomain := flags.Bool("subdomain", true, "address buckets as subdomains of the endpoint")
+	debug := flags.Bool("debug", false, "log requests and responses")
+	flags.BoolVar(&a.json, "json", false, "write results as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(a.stderr, "usage: nos [flags] <command> [command flags] [arguments]\n\ncommands:")
+		for _, name := range []string{"ls", "cp", "mv", "rm", "stat", "cat", "mb", "rb", "presign"} {
+			fmt.Fprintln(a.stderr, "  "+commands[name].usage)
+		}
+		fmt.Fprintln(a.stderr, "\nflags:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(a.stderr, "nos: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	// The flags that are set override the settings of the config file and
+	// of the environment.
+	settings := map[string]string{}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "endpoint":
+			settings["endpoint"] = *endpoint
+		case "access-key":
+			settings["accessKey"] = *accessKey
+		case "secret-key":
+			settings["secretKey"] = *secretKey
+		case "subdomain":
+			settings["subDomain"] = strconv.FormatBool(*subDomain)
+		}
+	})
+
+	conf, err := a.loadConfig(*configFile, *profile, settings)
+	if err == nil {
+		if *debug {
+			conf.LogLevel = logger.LogLevel(logger.DEBUG)
+		} else {
+			conf.LogLevel = logger.LogLevel(logger.LOGOFF)
+		}
+		a.client, err = nosclient.New(conf)
+	}
+	if err == nil {
+		err = cmd.run(a, flags.Args()[1:])
+	}
+
+	switch {
+	case err == errUsage:
+		fmt.Fprintln(a.stderr, "usage: nos "+cmd.usage)
+		return 2
+	case err != nil:
+		fmt.Fprintln(a.stderr, "nos: "+err.Error())
+		return 1
+	}
+	return 0
+}
+
+// loadConfig returns the configuration from the config file, overridden by
+// the environment and then by settings. A missing config file is only an
+// error if it was named explicitly.
+func (a *app) loadConfig(configFile, profile string, settings map[string]string) (*config.Config, error) {
+	if configFile == "" {
+		configFile = a.getenv("NOS_CONFIG_FILE")
+	}
+	if configFile == "" {
+		if home := a.getenv("HOME"); home != "" {
+			configFile = filepath.Join(home, ".nos", "config.json")
+			if _, err := os.Stat(configFile); os.IsNotExist(err) {
+				configFile = ""
+			}
+		}
+	}
+
+	loader := &config.Loader{
+		File:     configFile,
+		Profile:  profile,
+		Getenv:   a.getenv,
+		Settings: settings,
+	}
+	return loader.Load()
+}
+
+// parseFlags parses the flags of a command, leaving the arguments in
+// flags.Args(), and checks that there are between min and max of them.
+func (a *app) parseFlags(flags *flag.FlagSet, args []string, min, max int) error {
+	flags.SetOutput(a.stderr)
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() < min || flags.NArg() > max {
+		return errUsage
+	}
+	return nil
+}
+
+// nosUrl is a parsed nos://bucket/key argument.
+type nosUrl struct {
+	bucket string
+	key    string
+}
+
+func (u nosUrl) String() string {
+	return nosScheme + u.bucket + "/" + u.key
+}
+
+func isNosUrl(arg string) bool {
+	return strings.HasPrefix(arg, nosScheme)
+}
+
+func parseNosUrl(arg string) (nosUrl, error) {
+	if !isNosUrl(arg) {
+		return nosUrl{}, fmt.Errorf("%s: not a nos://bucket/key URL", arg)
+	}
+	rest := strings.TrimPrefix(arg, nosScheme)
+	u := nosUrl{bucket: rest}
+	if i := strings.Index(rest, "/"); i >= 0 {
+		u.bucket, u.key = rest[:i], rest[i+1:]
+	}
+	if u.bucket == "" {
+		return nosUrl{}, fmt.Errorf("%s: missing bucket", arg)
+	}
+	return u, nil
+}
+
+// parseObjectUrl parses a URL that must name an object.
+func parseObjectUrl(arg string) (nosUrl, error) {
+	u, err := parseNosUrl(arg)
+	if err == nil && u.key == "" {
+		err = fmt.Errorf("%s: missing key", arg)
+	}
+	return u, err
+}
+
+// output writes value as JSON with -json, and calls text otherwise.
+func (a *app) output(value interface{}, text func(w io.Writer)) error {
+	if !a.json {
+		text(a.stdout)
+		return nil
+	}
+	encoder := json.NewEncoder(a.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
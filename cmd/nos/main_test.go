@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosclient"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const TEST_BUCKET = "cli-test-bucket"
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type CliTestSuite struct {
+	server    *nostest.Server
+	nosClient *nosclient.NosClient
+	env       map[string]string
+}
+
+var _ = Suite(&CliTestSuite{})
+
+func (s *CliTestSuite) SetUpTest(c *C) {
+	s.server = nostest.NewServer()
+	s.server.CreateBucket(TEST_BUCKET)
+
+	var err error
+	s.nosClient, err = nosclient.New(s.server.Config())
+	c.Assert(err, IsNil)
+
+	s.env = map[string]string{
+		"HOME":           c.MkDir(),
+		"NOS_ENDPOINT":   s.server.URL,
+		"NOS_ACCESS_KEY": s.server.AccessKey,
+		"NOS_SECRET_KEY": s.server.SecretKey,
+		"NOS_SUBDOMAIN":  "false",
+	}
+}
+
+func (s *CliTestSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+// run runs the command line args and returns its exit status, standard
+// output and standard error.
+func (s *CliTestSuite) run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	a := &app{
+		ctx:    context.Background(),
+		stdout: &stdout,
+		stderr: &stderr,
+		getenv: func(name string) string {
+			return s.env[name]
+		},
+	}
+	status := a.run(args)
+	return status, stdout.String(), stderr.String()
+}
+
+func (s *CliTestSuite) mustRun(c *C, args ...string) string {
+	status, stdout, stderr := s.run(args...)
+	c.Assert(status, Equals, 0, Commentf("stderr: %s", stderr))
+	return stdout
+}
+
+func (s *CliTestSuite) putObject(c *C, object, content string) {
+	_, err := s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: object,
+		Body:   strings.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *CliTestSuite) TestLs(c *C) {
+	s.putObject(c, "a.txt", "a")
+	s.putObject(c, "dir/b.txt", "bb")
+
+	stdout := s.mustRun(c, "ls")
+	c.Assert(stdout, Matches, "(?s).*nos://"+TEST_BUCKET+"\n")
+
+	stdout = s.mustRun(c, "ls", "nos://"+TEST_BUCKET)
+	c.Assert(stdout, Matches, "(?s).* DIR  nos://"+TEST_BUCKET+"/dir/\n.* 1  nos://"+TEST_BUCKET+"/a.txt\n")
+
+	var result listing
+	stdout = s.mustRun(c, "-json", "ls", "-r", "nos://"+TEST_BUCKET+"/dir/")
+	c.Assert(json.Unmarshal([]byte(stdout), &result), IsNil)
+	c.Assert(result.Prefixes, HasLen, 0)
+	c.Assert(result.Objects, HasLen, 1)
+	c.Assert(result.Objects[0].Key, Equals, "dir/b.txt")
+	c.Assert(result.Objects[0].Size, Equals, int64(2))
+}
+
+func (s *CliTestSuite) TestCpAndMv(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(dir, "src", "sub"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "src", "a.txt"), []byte("a"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "src", "sub", "b.txt"), []byte("b"), 0644), IsNil)
+
+	// Upload a single file into a "directory", then a whole tree.
+	s.mustRun(c, "cp", filepath.Join(dir, "src", "a.txt"), "nos://"+TEST_BUCKET+"/single/")
+	var transfers []transfer
+	stdout := s.mustRun(c, "-json", "cp", "-r", filepath.Join(dir, "src"), "nos://"+TEST_BUCKET+"/tree")
+	c.Assert(json.Unmarshal([]byte(stdout), &transfers), IsNil)
+	c.Assert(transfers, HasLen, 2)
+	c.Assert(transfers[1].Op, Equals, "upload")
+	c.Assert(transfers[1].Destination, Equals, "nos://"+TEST_BUCKET+"/tree/sub/b.txt")
+	c.Assert(s.mustRun(c, "cat", "nos://"+TEST_BUCKET+"/single/a.txt"), Equals, "a")
+
+	// Copy within the bucket and download the copy.
+	s.mustRun(c, "cp", "-r", "nos://"+TEST_BUCKET+"/tree", "nos://"+TEST_BUCKET+"/copy/")
+	s.mustRun(c, "cp", "-r", "nos://"+TEST_BUCKET+"/copy", filepath.Join(dir, "dst"))
+	content, err := ioutil.ReadFile(filepath.Join(dir, "dst", "sub", "b.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "b")
+
+	// Moving a file removes the source.
+	stdout = s.mustRun(c, "mv", filepath.Join(dir, "src", "a.txt"), "nos://"+TEST_BUCKET+"/moved.txt")
+	c.Assert(stdout, Equals, "upload "+filepath.Join(dir, "src", "a.txt")+" -> nos://"+TEST_BUCKET+"/moved.txt\n")
+	_, err = os.Stat(filepath.Join(dir, "src", "a.txt"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	stdout = s.mustRun(c, "mv", "nos://"+TEST_BUCKET+"/moved.txt", filepath.Join(dir, "back.txt"))
+	c.Assert(stdout, Matches, "download .*\n")
+	status, _, stderr := s.run("stat", "nos://"+TEST_BUCKET+"/moved.txt")
+	c.Assert(status, Equals, 1)
+	c.Assert(stderr, Matches, "(?s).*404.*")
+
+	status, _, stderr = s.run("cp", filepath.Join(dir, "src"), "nos://"+TEST_BUCKET+"/x")
+	c.Assert(status, Equals, 1)
+	c.Assert(stderr, Matches, ".*is a directory.*\n")
+}
+
+func (s *CliTestSuite) TestRmAndStat(c *C) {
+	s.putObject(c, "dir/a", "a")
+	s.putObject(c, "dir/b", "b")
+	s.putObject(c, "dirty", "c")
+
+	var stat objectStat
+	stdout := s.mustRun(c, "-json", "stat", "nos://"+TEST_BUCKET+"/dir/a")
+	c.Assert(json.Unmarshal([]byte(stdout), &stat), IsNil)
+	c.Assert(stat.Size, Equals, int64(1))
+	c.Assert(stat.Metadata["Etag"], Not(Equals), "")
+
+	stdout = s.mustRun(c, "rm", "-r", "nos://"+TEST_BUCKET+"/dir")
+	c.Assert(stdout, Equals, "delete nos://"+TEST_BUCKET+"/dir/a\ndelete nos://"+TEST_BUCKET+"/dir/b\n")
+	stdout = s.mustRun(c, "ls", "-r", "nos://"+TEST_BUCKET)
+	c.Assert(stdout, Matches, ".* 1  nos://"+TEST_BUCKET+"/dirty\n")
+
+	s.mustRun(c, "rm", "nos://"+TEST_BUCKET+"/dirty")
+	status, _, _ := s.run("rm", "nos://"+TEST_BUCKET)
+	c.Assert(status, Equals, 1)
+}
+
+func (s *CliTestSuite) TestBuckets(c *C) {
+	c.Assert(s.mustRun(c, "mb", "-acl", "public-read", "nos://new-bucket"), Equals, "make_bucket nos://new-bucket\n")
+	status, _, stderr := s.run("mb", "-location", "XX", "nos://other-bucket")
+	c.Assert(status, Equals, 1)
+	c.Assert(stderr, Matches, ".*unknown location.*\n")
+
+	_, err := s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: "new-bucket",
+		Object: "object",
+		Body:   strings.NewReader("data"),
+	})
+	c.Assert(err, IsNil)
+
+	status, _, stderr = s.run("rb", "nos://new-bucket")
+	c.Assert(status, Equals, 1)
+	c.Assert(stderr, Matches, ".*BucketNotEmpty.*\n")
+	c.Assert(s.mustRun(c, "rb", "-f", "nos://new-bucket"), Equals, "remove_bucket nos://new-bucket\n")
+}
+
+func (s *CliTestSuite) TestPresign(c *C) {
+	s.putObject(c, "object", "presigned")
+
+	url := strings.TrimSpace(s.mustRun(c, "presign", "-expires", "10m", "nos://"+TEST_BUCKET+"/object"))
+	resp, err := http.Get(url)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	content, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "presigned")
+}
+
+func (s *CliTestSuite) TestConfig(c *C) {
+	configFile := filepath.Join(c.MkDir(), "config.json")
+	content := `{"endpoint": "` + s.server.URL + `", "accessKey": "` + s.server.AccessKey +
+		`", "secretKey": "wrong", "isSubDomain": false}`
+	c.Assert(ioutil.WriteFile(configFile, []byte(content), 0600), IsNil)
+	s.env = map[string]string{"NOS_CONFIG_FILE": configFile}
+
+	// The flags override the config file.
+	status, _, stderr := s.run("ls")
+	c.Assert(status, Equals, 1)
+	c.Assert(stderr, Matches, "(?s).*403.*")
+	s.mustRun(c, "-secret-key", s.server.SecretKey, "ls")
+
+	status, _, stderr = s.run("-config", filepath.Join(c.MkDir(), "missing.json"), "ls")
+	c.Assert(status, Equals, 1)
+	c.Assert(stderr, Matches, ".*missing.json.*\n")
+
+	// Any format and profile of config.LoadFile is understood.
+	configFile = filepath.Join(c.MkDir(), "nos.yaml")
+	content = "default:\n  endpoint: localhost:1\nstaging:\n  endpoint: " + s.server.URL +
+		"\n  access_key: " + s.server.AccessKey + "\n  secret_key: " + s.server.SecretKey +
+		"\n  sub_domain: false\n"
+	c.Assert(ioutil.WriteFile(configFile, []byte(content), 0600), IsNil)
+	s.env = map[string]string{"NOS_CONFIG_FILE": configFile, "NOS_PROFILE": "staging"}
+	s.mustRun(c, "ls")
+	s.env["NOS_PROFILE"] = ""
+	s.mustRun(c, "-profile", "staging", "ls")
+	status, _, stderr = s.run("-profile", "production", "ls")
+	c.Assert(status, Equals, 1)
+	c.Assert(stderr, Matches, `.*no profile "production".*\n`)
+
+	status, _, stderr = s.run("frobnicate")
+	c.Assert(status, Equals, 2)
+	c.Assert(stderr, Matches, "(?s)nos: unknown command.*")
+
+	status, _, stderr = s.run("cat")
+	c.Assert(status, Equals, 2)
+	c.Assert(stderr, Equals, "usage: nos cat nos://bucket/key\n")
+}
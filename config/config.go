@@ -1,16 +1,35 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/credentials"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/logger"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
 )
 
 type Config struct {
+	// Endpoint is either a host[:port], or a full URL such as
+	// "https://nos-eastchina1.126.net:443" whose scheme overrides Scheme.
 	Endpoint      string
 	AccessKey     string
 	SecretKey     string
 
+	// Credentials, if set, is consulted for the keys every time a request
+	// is signed, in place of AccessKey and SecretKey.
+	Credentials credentials.Provider
+
+	// Scheme is "http" (the default) or "https".
+	Scheme string
+
+	// TLS customizes certificate verification for https endpoints.
+	TLS *TLSConfig
+
 	NosServiceConnectTimeout    int
 	NosServiceReadWriteTimeout  int
 	NosServiceMaxIdleConnection int
@@ -20,6 +39,168 @@ type Config struct {
 	Logger logger.Logger
 
 	IsSubDomain *bool
+
+	// Retry controls how failed requests are retried. A nil Retry uses
+	// the defaults filled in by Check.
+	Retry *RetryConfig
+
+	// VerifyIntegrity makes the client check content end to end: the MD5 of
+	// uploaded objects and parts is computed and sent as Content-MD5 (and
+	// as X-Nos-Object-Md5 when completing multipart uploads), and whole
+	// objects read with GetObject are checked against their ETag.
+	VerifyIntegrity bool
+
+	// Compression, if set, names the codec that object content is
+	// compressed with on every upload, such as "gzip"; see
+	// nosclient.RegisterCodec. The codec is recorded in the object's
+	// metadata, along with the uncompressed length and MD5 when they are
+	// known, and GetObject, the Downloader and the ObjectReader decompress
+	// objects that carry it, whatever the client's own setting. The listed
+	// size and ETag of such objects are those of the compressed content, and
+	// they cannot be read by range. PutObjectByStream compresses its body
+	// into a temporary file before sending it, so that the disk rather than
+	// memory holds it, and the compressed content is limited to the size of
+	// a single PUT; the Uploader and the ObjectWriter compress content larger
+	// than that as they send it part by part.
+	Compression string
+
+	// tlsConfig is built from TLS by Check.
+	tlsConfig *tls.Config
+}
+
+type TLSConfig struct {
+	// RootCAs, if set, replaces the system roots. PEM certificates read from
+	// RootCAFiles are added to it (or to the system roots if it is nil).
+	RootCAs     *x509.CertPool
+	RootCAFiles []string
+
+	// Certificates are presented to the server for client authentication,
+	// along with the PEM pair loaded from CertFile/KeyFile when both are set.
+	Certificates []tls.Certificate
+	CertFile     string
+	KeyFile      string
+
+	// MinVersion is the minimum TLS version, e.g. tls.VersionTLS12.
+	MinVersion uint16
+
+	// ServerName overrides the host name used to verify the server
+	// certificate.
+	ServerName string
+
+	InsecureSkipVerify bool
+}
+
+// Build returns the crypto/tls configuration described by tlsConf.
+func (tlsConf *TLSConfig) Build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		RootCAs:            tlsConf.RootCAs,
+		MinVersion:         tlsConf.MinVersion,
+		ServerName:         tlsConf.ServerName,
+		InsecureSkipVerify: tlsConf.InsecureSkipVerify,
+	}
+
+	if len(tlsConf.RootCAFiles) > 0 {
+		if tlsConfig.RootCAs == nil {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			tlsConfig.RootCAs = pool
+		}
+		for _, file := range tlsConf.RootCAFiles {
+			pem, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, utils.WrapClientError(noserror.ERROR_CODE_CFG_TLS, "", "", err)
+			}
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, utils.ProcessClientError(noserror.ERROR_CODE_CFG_TLS, "", "",
+					"no certificates found in "+file)
+			}
+		}
+	}
+
+	tlsConfig.Certificates = append(tlsConfig.Certificates, tlsConf.Certificates...)
+	if tlsConf.CertFile != "" || tlsConf.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(tlsConf.CertFile, tlsConf.KeyFile)
+		if err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_CFG_TLS, "", "", err)
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+	}
+
+	return tlsConfig, nil
+}
+
+// RetryErrorClass identifies a family of transport errors that may be
+// retried; see noserror.ShouldRetry.
+type RetryErrorClass = noserror.RetryErrorClass
+
+const (
+	RETRY_ERROR_TIMEOUT    = noserror.RETRY_ERROR_TIMEOUT
+	RETRY_ERROR_CONNECTION = noserror.RETRY_ERROR_CONNECTION
+)
+
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts for one operation,
+	// including the first one. 1 disables retries.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry; each later retry doubles
+	// it, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Jitter is the fraction (0 to 1) of each delay that is randomized. If
+	// zero, it is 0.5; a negative Jitter disables jitter.
+	Jitter float64
+
+	// RetryableStatusCodes and RetryableErrors select the failures that are
+	// retried; if nil, they are noserror.DefaultRetryableStatusCodes and
+	// noserror.DefaultRetryableErrors.
+	RetryableStatusCodes []int
+	RetryableErrors      []RetryErrorClass
+
+	// RetryNonIdempotent allows retrying POST requests, such as initiating or
+	// completing a multipart upload. A retried POST may have succeeded the
+	// first time, and initiate a second upload, so they are sent once unless
+	// this is set.
+	RetryNonIdempotent bool
+}
+
+func (retry *RetryConfig) IsRetryableStatus(statusCode int) bool {
+	for _, code := range retry.RetryableStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+func (retry *RetryConfig) IsRetryableClass(class RetryErrorClass) bool {
+	for _, c := range retry.RetryableErrors {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay returns the backoff before the given retry (1 for the first retry),
+// with rnd in [0, 1) used to apply jitter.
+func (retry *RetryConfig) Delay(retryNum int, rnd float64) time.Duration {
+	delay := retry.BaseDelay
+	for i := 1; i < retryNum && delay < retry.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retry.MaxDelay {
+		delay = retry.MaxDelay
+	}
+
+	if retry.Jitter <= 0 {
+		return delay
+	}
+	jitter := time.Duration(float64(delay) * retry.Jitter * rnd)
+	return delay - time.Duration(float64(delay)*retry.Jitter) + jitter
 }
 
 func (conf *Config) SetIsSubDomain(isSubDomain bool) error {
@@ -35,21 +216,94 @@ func (conf *Config) GetIsSubDomain() bool {
 	}
 }
 
+// TLSClientConfig returns the configuration built from TLS by the last call
+// to Check, or nil if TLS is not set, so that certificate files are only read
+// once.
+func (conf *Config) TLSClientConfig() *tls.Config {
+	return conf.tlsConfig
+}
+
+// CheckError is returned by Check when a configuration has several
+// problems; a single problem is returned as it is.
+type CheckError struct {
+	Errors []error
+}
+
+func (checkError *CheckError) Error() string {
+	messages := make([]string, len(checkError.Errors))
+	for i, err := range checkError.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the problems, so that errors.Is and errors.As match any
+// of them.
+func (checkError *CheckError) Unwrap() []error {
+	return checkError.Errors
+}
+
+// Check validates the configuration, reporting all of its problems at once,
+// and fills in the defaults of the settings that are not set.
 func (conf *Config) Check() error {
+	var errs []error
+	invalid := func(code int) {
+		errs = append(errs, utils.ProcessClientError(code, "", "", ""))
+	}
+
 	if conf.Endpoint == "" {
-		return utils.ProcessClientError(noserror.ERROR_CODE_CFG_ENDPOINT, "", "", "")
+		invalid(noserror.ERROR_CODE_CFG_ENDPOINT)
+	}
+
+	if strings.Contains(conf.Endpoint, "://") {
+		endpoint, err := url.Parse(conf.Endpoint)
+		if err != nil || endpoint.Host == "" || strings.Trim(endpoint.Path, "/") != "" {
+			invalid(noserror.ERROR_CODE_CFG_ENDPOINT)
+		} else {
+			conf.Scheme = endpoint.Scheme
+			conf.Endpoint = endpoint.Host
+		}
+	}
+
+	conf.Scheme = strings.ToLower(conf.Scheme)
+	if conf.Scheme == "" {
+		conf.Scheme = "http"
+	}
+
+	if conf.Scheme != "http" && conf.Scheme != "https" {
+		invalid(noserror.ERROR_CODE_CFG_SCHEME)
+	}
+
+	conf.tlsConfig = nil
+	if conf.TLS != nil {
+		tlsConfig, err := conf.TLS.Build()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		conf.tlsConfig = tlsConfig
 	}
 
 	if conf.NosServiceConnectTimeout < 0 {
-		return utils.ProcessClientError(noserror.ERROR_CODE_CFG_CONNECT_TIMEOUT, "", "", "")
+		invalid(noserror.ERROR_CODE_CFG_CONNECT_TIMEOUT)
 	}
 
 	if conf.NosServiceReadWriteTimeout < 0 {
-		return utils.ProcessClientError(noserror.ERROR_CODE_CFG_READWRITE_TIMEOUT, "", "", "")
+		invalid(noserror.ERROR_CODE_CFG_READWRITE_TIMEOUT)
 	}
 
 	if conf.NosServiceMaxIdleConnection < 0 {
-		return utils.ProcessClientError(noserror.ERROR_CODE_CFG_MAXIDLECONNECT, "", "", "")
+		invalid(noserror.ERROR_CODE_CFG_MAXIDLECONNECT)
+	}
+
+	if conf.Retry != nil && (conf.Retry.MaxAttempts < 0 || conf.Retry.BaseDelay < 0 ||
+		conf.Retry.MaxDelay < 0 || conf.Retry.Jitter > 1) {
+		invalid(noserror.ERROR_CODE_CFG_RETRY)
+	}
+
+	if len(errs) == 1 {
+		return errs[0]
+	} else if len(errs) > 1 {
+		return &CheckError{Errors: errs}
 	}
 
 	if conf.NosServiceConnectTimeout == 0 {
@@ -64,6 +318,34 @@ func (conf *Config) Check() error {
 		conf.NosServiceMaxIdleConnection = 60
 	}
 
+	if conf.Retry == nil {
+		conf.Retry = &RetryConfig{}
+	}
+
+	if conf.Retry.MaxAttempts == 0 {
+		conf.Retry.MaxAttempts = 3
+	}
+
+	if conf.Retry.BaseDelay == 0 {
+		conf.Retry.BaseDelay = 200 * time.Millisecond
+	}
+
+	if conf.Retry.MaxDelay == 0 {
+		conf.Retry.MaxDelay = 5 * time.Second
+	}
+
+	if conf.Retry.Jitter == 0 {
+		conf.Retry.Jitter = 0.5
+	}
+
+	if conf.Retry.RetryableStatusCodes == nil {
+		conf.Retry.RetryableStatusCodes = append([]int(nil), noserror.DefaultRetryableStatusCodes...)
+	}
+
+	if conf.Retry.RetryableErrors == nil {
+		conf.Retry.RetryableErrors = append([]RetryErrorClass(nil), noserror.DefaultRetryableErrors...)
+	}
+
 	if conf.Logger == nil {
 		conf.Logger = logger.NewDefaultLogger()
 	}
@@ -1,10 +1,12 @@
 package config
 
 import (
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/logger"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
 	. "gopkg.in/check.v1"
-	"nos-golang-sdk/logger"
-	"nos-golang-sdk/noserror"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test(t *testing.T) { TestingT(t) }
@@ -103,3 +105,132 @@ func (s *ConfigTestSuite) TestConfigError4(c *C) {
 	err := config.Check()
 	c.Assert(err.Error(), Equals, "StatusCode = 423, Resource = , Message = Config: InvalidMaxIdleConnect")
 }
+
+func (s *ConfigTestSuite) TestConfigRetryDefaults(c *C) {
+	config := Config{
+		Endpoint:  "nos.netease.com",
+		AccessKey: "12345",
+		SecretKey: "12345",
+	}
+
+	err := config.Check()
+	c.Assert(err, IsNil)
+	c.Assert(config.Retry.MaxAttempts, Equals, 3)
+	c.Assert(config.Retry.IsRetryableStatus(503), Equals, true)
+	c.Assert(config.Retry.IsRetryableStatus(429), Equals, true)
+	c.Assert(config.Retry.IsRetryableStatus(404), Equals, false)
+	c.Assert(config.Retry.IsRetryableClass(RETRY_ERROR_TIMEOUT), Equals, true)
+	c.Assert(config.Retry.Jitter, Equals, 0.5)
+	c.Assert(config.Retry.RetryNonIdempotent, Equals, false)
+}
+
+func (s *ConfigTestSuite) TestConfigRetryDelay(c *C) {
+	retry := &RetryConfig{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  time.Second,
+	}
+	c.Assert(retry.Delay(1, 0.5), Equals, 100*time.Millisecond)
+	c.Assert(retry.Delay(3, 0.5), Equals, 400*time.Millisecond)
+	c.Assert(retry.Delay(10, 0.5), Equals, time.Second)
+
+	retry.Jitter = 0.5
+	c.Assert(retry.Delay(1, 0), Equals, 50*time.Millisecond)
+	c.Assert(retry.Delay(1, 0.99) < 100*time.Millisecond, Equals, true)
+
+	retry.Jitter = -1
+	c.Assert(retry.Delay(1, 0), Equals, 100*time.Millisecond)
+}
+
+func (s *ConfigTestSuite) TestConfigRetryError(c *C) {
+	config := Config{
+		Endpoint:  "nos.netease.com",
+		AccessKey: "12345",
+		SecretKey: "12345",
+		Retry:     &RetryConfig{MaxAttempts: -1},
+	}
+
+	err := config.Check()
+	c.Assert(err.Error(), Equals, "StatusCode = 424, Resource = , Message = Config: InvalidRetry")
+}
+
+func (s *ConfigTestSuite) TestConfigEndpointURL(c *C) {
+	config := Config{
+		Endpoint:  "https://nos.netease.com:8443",
+		AccessKey: "12345",
+		SecretKey: "12345",
+	}
+
+	err := config.Check()
+	c.Assert(err, IsNil)
+	c.Assert(config.Scheme, Equals, "https")
+	c.Assert(config.Endpoint, Equals, "nos.netease.com:8443")
+
+	config = Config{
+		Endpoint: "nos.netease.com",
+	}
+	err = config.Check()
+	c.Assert(err, IsNil)
+	c.Assert(config.Scheme, Equals, "http")
+}
+
+func (s *ConfigTestSuite) TestConfigSchemeError(c *C) {
+	config := Config{
+		Endpoint: "ftp://nos.netease.com",
+	}
+
+	err := config.Check()
+	c.Assert(err.Error(), Equals, "StatusCode = 425, Resource = , Message = Config: InvalidScheme")
+
+	config = Config{
+		Endpoint: "https://nos.netease.com/bucket",
+	}
+	err = config.Check()
+	c.Assert(err.Error(), Equals, "StatusCode = 420, Resource = , Message = Config: InvalidEndpoint")
+}
+
+func (s *ConfigTestSuite) TestConfigTLSError(c *C) {
+	config := Config{
+		Endpoint: "https://nos.netease.com",
+		TLS: &TLSConfig{
+			RootCAFiles: []string{"not-exist.pem"},
+		},
+	}
+
+	err := config.Check()
+	c.Assert(strings.HasPrefix(err.Error(), "StatusCode = 426, Resource = , Message = Config: InvalidTLS"), Equals, true)
+}
+
+func (s *ConfigTestSuite) TestConfigErrors(c *C) {
+	config := Config{
+		Endpoint:                 "",
+		NosServiceConnectTimeout: -2,
+		Retry:                    &RetryConfig{Jitter: 2},
+	}
+
+	err := config.Check()
+	checkError, ok := err.(*CheckError)
+	c.Assert(ok, Equals, true)
+	c.Assert(checkError.Errors, HasLen, 3)
+	c.Assert(err.Error(), Equals, "StatusCode = 420, Resource = , Message = Config: InvalidEndpoint; "+
+		"StatusCode = 421, Resource = , Message = Config: InvalidConnectionTimeout; "+
+		"StatusCode = 424, Resource = , Message = Config: InvalidRetry")
+}
+
+func (s *ConfigTestSuite) TestConfigTLS(c *C) {
+	config := Config{
+		Endpoint: "https://nos.netease.com",
+		TLS: &TLSConfig{
+			ServerName: "nos.example.com",
+		},
+	}
+
+	err := config.Check()
+	c.Assert(err, IsNil)
+	c.Assert(config.TLSClientConfig(), NotNil)
+	c.Assert(config.TLSClientConfig().ServerName, Equals, "nos.example.com")
+
+	config.TLS = nil
+	err = config.Check()
+	c.Assert(err, IsNil)
+	c.Assert(config.TLSClientConfig(), IsNil)
+}
@@ -0,0 +1,184 @@
+// Package credentials provides the keys NOS requests are signed with.
+//
+// A Provider is consulted every time a request is signed, so that keys can
+// be rotated without rebuilding clients. Providers read the keys from the
+// environment, from a shared credentials file, or from an external
+// command; a ChainProvider tries several of them in order and caches the
+// result until it is about to expire, or for a few minutes if it does not
+// expire.
+package credentials
+
+import (
+	"context"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ENV_ACCESS_KEY              = "NOS_ACCESS_KEY"
+	ENV_SECRET_KEY              = "NOS_SECRET_KEY"
+	ENV_SECURITY_TOKEN          = "NOS_SECURITY_TOKEN"
+	ENV_SHARED_CREDENTIALS_FILE = "NOS_SHARED_CREDENTIALS_FILE"
+	ENV_PROFILE                 = "NOS_PROFILE"
+
+	DEFAULT_PROFILE = "default"
+
+	// DEFAULT_EXPIRY_WINDOW is how long before they expire cached
+	// credentials are refreshed.
+	DEFAULT_EXPIRY_WINDOW = time.Minute
+
+	// DEFAULT_REFRESH_INTERVAL is how long cached credentials that do not
+	// expire are used before they are retrieved again.
+	DEFAULT_REFRESH_INTERVAL = 5 * time.Minute
+)
+
+// Credentials are the keys a request is signed with.
+type Credentials struct {
+	AccessKey string
+	SecretKey string
+
+	// SecurityToken accompanies temporary keys, and is sent with every
+	// request signed with them.
+	SecurityToken string
+
+	// Expires is when the keys stop being valid. The zero time means they
+	// do not expire.
+	Expires time.Time
+}
+
+// Valid reports whether both keys are set.
+func (creds Credentials) Valid() bool {
+	return creds.AccessKey != "" && creds.SecretKey != ""
+}
+
+// Expired reports whether the credentials have expired.
+func (creds Credentials) Expired() bool {
+	return creds.expired(time.Now(), 0)
+}
+
+// expired reports whether the credentials expire within window of now.
+func (creds Credentials) expired(now time.Time, window time.Duration) bool {
+	return !creds.Expires.IsZero() && !now.Add(window).Before(creds.Expires)
+}
+
+func credentialsError(msg string) error {
+	return utils.ProcessClientError(noserror.ERROR_CODE_CREDENTIALS_ERROR, "", "", msg)
+}
+
+// Provider retrieves credentials. Retrieve is called for every request, and
+// must be safe for concurrent use; providers that are slow to retrieve
+// credentials should be wrapped in a ChainProvider, which caches them.
+type Provider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// StaticProvider always returns the same credentials.
+type StaticProvider struct {
+	Credentials Credentials
+}
+
+func NewStaticProvider(accessKey, secretKey string) *StaticProvider {
+	return &StaticProvider{
+		Credentials: Credentials{AccessKey: accessKey, SecretKey: secretKey},
+	}
+}
+
+func (provider *StaticProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	if !provider.Credentials.Valid() {
+		return Credentials{}, credentialsError("static credentials are empty")
+	}
+	return provider.Credentials, nil
+}
+
+// ChainProvider returns the credentials of the first of its providers that
+// succeeds, and caches them until they come within ExpiryWindow of their
+// expiry; credentials that do not expire, such as those of the environment
+// or of a shared credentials file, are cached for RefreshInterval, so that
+// rotated keys are picked up. Temporary credentials are thus refreshed
+// before they expire; if the refresh fails, the cached credentials are used
+// until they actually do, and those that do not expire until a refresh
+// succeeds.
+type ChainProvider struct {
+	Providers []Provider
+
+	// ExpiryWindow is how long before they expire credentials are
+	// refreshed.
+	ExpiryWindow time.Duration
+
+	// RefreshInterval is how long credentials that do not expire are cached.
+	// If zero, they are retrieved again on every call.
+	RefreshInterval time.Duration
+
+	mu        sync.Mutex
+	cached    *Credentials
+	retrieved time.Time
+
+	// now returns the current time; it is replaced by tests.
+	now func() time.Time
+}
+
+func NewChainProvider(providers ...Provider) *ChainProvider {
+	return &ChainProvider{
+		Providers:       providers,
+		ExpiryWindow:    DEFAULT_EXPIRY_WINDOW,
+		RefreshInterval: DEFAULT_REFRESH_INTERVAL,
+	}
+}
+
+// NewDefaultChainProvider returns a chain of the environment variables and
+// the shared credentials file, with its default location and profile.
+func NewDefaultChainProvider() *ChainProvider {
+	return NewChainProvider(NewEnvProvider(), NewFileProvider("", ""))
+}
+
+// Retrieve returns the cached credentials, or retrieves new ones. The
+// providers are tried one at a time, so concurrent calls share a single
+// refresh. If no provider succeeds, the error lists why each one failed.
+func (provider *ChainProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	now := time.Now
+	if provider.now != nil {
+		now = provider.now
+	}
+	if provider.cached != nil && !provider.cached.expired(now(), provider.ExpiryWindow) &&
+		(!provider.cached.Expires.IsZero() || now().Before(provider.retrieved.Add(provider.RefreshInterval))) {
+		return *provider.cached, nil
+	}
+
+	var errs []string
+	for _, p := range provider.Providers {
+		creds, err := p.Retrieve(ctx)
+		if err == nil && !creds.Valid() {
+			err = credentialsError("credentials are empty")
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		provider.cached = &creds
+		provider.retrieved = now()
+		return creds, nil
+	}
+
+	if provider.cached != nil && !provider.cached.expired(now(), 0) {
+		return *provider.cached, nil
+	}
+	provider.cached = nil
+	return Credentials{}, credentialsError("no provider returned credentials: [" + strings.Join(errs, "; ") + "]")
+}
+
+// Expire drops the cached credentials, so that the next call to Retrieve
+// retrieves them again.
+func (provider *ChainProvider) Expire() {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	provider.cached = nil
+}
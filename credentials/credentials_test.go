@@ -0,0 +1,235 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var envKeys = []string{ENV_ACCESS_KEY, ENV_SECRET_KEY, ENV_SECURITY_TOKEN, ENV_SHARED_CREDENTIALS_FILE, ENV_PROFILE}
+
+type CredentialsTestSuite struct {
+	env map[string]string
+}
+
+var _ = Suite(&CredentialsTestSuite{})
+
+func (s *CredentialsTestSuite) SetUpSuite(c *C) {
+	noserror.Init()
+}
+
+// SetUpTest clears the environment variables the providers read, and
+// TearDownTest restores them.
+func (s *CredentialsTestSuite) SetUpTest(c *C) {
+	s.env = map[string]string{}
+	for _, key := range envKeys {
+		if value, ok := os.LookupEnv(key); ok {
+			s.env[key] = value
+		}
+		os.Unsetenv(key)
+	}
+}
+
+func (s *CredentialsTestSuite) TearDownTest(c *C) {
+	for _, key := range envKeys {
+		os.Unsetenv(key)
+		if value, ok := s.env[key]; ok {
+			os.Setenv(key, value)
+		}
+	}
+}
+
+func (s *CredentialsTestSuite) TestEnvProvider(c *C) {
+	_, err := NewEnvProvider().Retrieve(context.Background())
+	c.Assert(err, ErrorMatches, "StatusCode = 451, .*NOS_ACCESS_KEY or NOS_SECRET_KEY is not set")
+
+	os.Setenv(ENV_ACCESS_KEY, "env-access")
+	os.Setenv(ENV_SECRET_KEY, "env-secret")
+	creds, err := NewEnvProvider().Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds, Equals, Credentials{AccessKey: "env-access", SecretKey: "env-secret"})
+
+	os.Setenv(ENV_SECURITY_TOKEN, "env-token")
+	creds, err = NewEnvProvider().Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds.SecurityToken, Equals, "env-token")
+}
+
+func (s *CredentialsTestSuite) TestFileProvider(c *C) {
+	filename := filepath.Join(c.MkDir(), "credentials")
+	content := `
+# Shared credentials
+[default]
+access_key = default-access
+secret_key = default-secret
+
+[production]
+access_key=production-access
+; no secret key
+
+[temporary]
+access_key = temporary-access
+secret_key = temporary-secret
+security_token = temporary-token
+`
+	c.Assert(ioutil.WriteFile(filename, []byte(content), 0600), IsNil)
+
+	creds, err := NewFileProvider(filename, "").Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds, Equals, Credentials{AccessKey: "default-access", SecretKey: "default-secret"})
+
+	creds, err = NewFileProvider(filename, "temporary").Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds.SecurityToken, Equals, "temporary-token")
+
+	_, err = NewFileProvider(filename, "production").Retrieve(context.Background())
+	c.Assert(err, ErrorMatches, ".*profile \"production\" has no access_key or secret_key")
+	_, err = NewFileProvider(filename, "missing").Retrieve(context.Background())
+	c.Assert(err, ErrorMatches, ".*no profile \"missing\"")
+
+	// The file and profile default to the environment variables.
+	os.Setenv(ENV_SHARED_CREDENTIALS_FILE, filename)
+	os.Setenv(ENV_PROFILE, "production")
+	_, err = NewFileProvider("", "").Retrieve(context.Background())
+	c.Assert(err, ErrorMatches, ".*profile \"production\".*")
+
+	// The file is read again on every call.
+	c.Assert(ioutil.WriteFile(filename, []byte("[production]\naccess_key = a\nsecret_key = b\n"), 0600), IsNil)
+	creds, err = NewFileProvider("", "").Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds, Equals, Credentials{AccessKey: "a", SecretKey: "b"})
+
+	c.Assert(ioutil.WriteFile(filename, []byte("access_key = a\n"), 0600), IsNil)
+	_, err = NewFileProvider(filename, "").Retrieve(context.Background())
+	c.Assert(err, ErrorMatches, ".*invalid line 1")
+}
+
+func (s *CredentialsTestSuite) TestProcessProvider(c *C) {
+	provider := NewProcessProvider("sh", "-c",
+		`echo '{"accessKey": "process-access", "secretKey": "process-secret", "securityToken": "process-token",`+
+			` "expiration": "2026-01-02T15:04:05Z"}'`)
+	creds, err := provider.Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds.AccessKey, Equals, "process-access")
+	c.Assert(creds.SecretKey, Equals, "process-secret")
+	c.Assert(creds.SecurityToken, Equals, "process-token")
+	c.Assert(creds.Expired(), Equals, true)
+	c.Assert(creds.Expires.Equal(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)), Equals, true)
+
+	_, err = NewProcessProvider("sh", "-c", "echo denied >&2; exit 3").Retrieve(context.Background())
+	c.Assert(err, ErrorMatches, ".*credential process sh: exit status 3: denied")
+
+	_, err = NewProcessProvider("sh", "-c", "echo '{}'").Retrieve(context.Background())
+	c.Assert(err, ErrorMatches, ".*returned no accessKey or secretKey")
+
+	provider = NewProcessProvider("sleep", "10")
+	provider.Timeout = 10 * time.Millisecond
+	_, err = provider.Retrieve(context.Background())
+	c.Assert(err, ErrorMatches, ".*credential process sleep: .*killed.*")
+}
+
+// countingProvider returns credentials that expire after ttl, numbered by
+// the number of calls, or err if it is set.
+type countingProvider struct {
+	calls int
+	ttl   time.Duration
+	now   func() time.Time
+	err   error
+}
+
+func (provider *countingProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	provider.calls++
+	if provider.err != nil {
+		return Credentials{}, provider.err
+	}
+	creds := Credentials{AccessKey: "access", SecretKey: "secret" + string(rune('0'+provider.calls))}
+	if provider.ttl > 0 {
+		creds.Expires = provider.now().Add(provider.ttl)
+	}
+	return creds, nil
+}
+
+func (s *CredentialsTestSuite) TestChainProvider(c *C) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	failing := &countingProvider{err: errors.New("unavailable")}
+	clock := func() time.Time { return now }
+	expiring := &countingProvider{ttl: 10 * time.Minute, now: clock}
+
+	chain := NewChainProvider(failing, expiring)
+	chain.now = clock
+
+	creds, err := chain.Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds.SecretKey, Equals, "secret1")
+
+	// The credentials are cached until they come within the expiry window.
+	now = now.Add(8 * time.Minute)
+	creds, err = chain.Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds.SecretKey, Equals, "secret1")
+	c.Assert(expiring.calls, Equals, 1)
+
+	now = now.Add(90 * time.Second)
+	creds, err = chain.Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds.SecretKey, Equals, "secret2")
+	c.Assert(failing.calls, Equals, 2)
+
+	// A failed refresh falls back on the cached credentials until they
+	// actually expire.
+	expiring.err = errors.New("refresh failed")
+	now = now.Add(9*time.Minute + 30*time.Second)
+	creds, err = chain.Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds.SecretKey, Equals, "secret2")
+	now = now.Add(time.Minute)
+	_, err = chain.Retrieve(context.Background())
+	c.Assert(err, ErrorMatches, ".*refresh failed.*")
+
+	// Credentials that do not expire are cached for the refresh interval,
+	// or until Expire.
+	static := &countingProvider{}
+	chain = NewChainProvider(static)
+	chain.now = clock
+	chain.Retrieve(context.Background())
+	now = now.Add(DEFAULT_REFRESH_INTERVAL - time.Second)
+	chain.Retrieve(context.Background())
+	c.Assert(static.calls, Equals, 1)
+	chain.Expire()
+	creds, err = chain.Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds.SecretKey, Equals, "secret2")
+
+	now = now.Add(DEFAULT_REFRESH_INTERVAL)
+	creds, err = chain.Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds.SecretKey, Equals, "secret3")
+
+	// If the refresh fails, they are used until one succeeds.
+	static.err = errors.New("file unreadable")
+	now = now.Add(DEFAULT_REFRESH_INTERVAL)
+	creds, err = chain.Retrieve(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(creds.SecretKey, Equals, "secret3")
+	c.Assert(static.calls, Equals, 4)
+
+	// With no refresh interval, they are retrieved on every call.
+	static.err = nil
+	chain.RefreshInterval = 0
+	chain.Retrieve(context.Background())
+	chain.Retrieve(context.Background())
+	c.Assert(static.calls, Equals, 6)
+
+	chain = NewChainProvider(failing, NewStaticProvider("", ""))
+	_, err = chain.Retrieve(context.Background())
+	c.Assert(err, ErrorMatches, "StatusCode = 451, .*no provider returned credentials: "+
+		`\[unavailable; .*static credentials are empty\]`)
+}
@@ -0,0 +1,232 @@
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DEFAULT_PROCESS_TIMEOUT bounds the run of a credential process.
+const DEFAULT_PROCESS_TIMEOUT = time.Minute
+
+// EnvProvider reads the keys from the NOS_ACCESS_KEY and NOS_SECRET_KEY
+// environment variables, and the security token, if any, from
+// NOS_SECURITY_TOKEN.
+type EnvProvider struct{}
+
+func NewEnvProvider() *EnvProvider {
+	return &EnvProvider{}
+}
+
+func (provider *EnvProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	creds := Credentials{
+		AccessKey:     os.Getenv(ENV_ACCESS_KEY),
+		SecretKey:     os.Getenv(ENV_SECRET_KEY),
+		SecurityToken: os.Getenv(ENV_SECURITY_TOKEN),
+	}
+	if !creds.Valid() {
+		return Credentials{}, credentialsError(ENV_ACCESS_KEY + " or " + ENV_SECRET_KEY + " is not set")
+	}
+	return creds, nil
+}
+
+// FileProvider reads the keys of a profile from a shared credentials file,
+// which is read again on every call. The file holds one section per
+// profile:
+//
+//	[default]
+//	access_key = ...
+//	secret_key = ...
+//	security_token = ...
+//
+// where security_token is optional. Lines starting with '#' or ';' are comments.
+type FileProvider struct {
+	// Filename is the path of the file. If empty, it is the value of
+	// NOS_SHARED_CREDENTIALS_FILE, or ~/.nos/credentials.
+	Filename string
+
+	// Profile is the section to read. If empty, it is the value of
+	// NOS_PROFILE, or "default".
+	Profile string
+}
+
+func NewFileProvider(filename, profile string) *FileProvider {
+	return &FileProvider{
+		Filename: filename,
+		Profile:  profile,
+	}
+}
+
+func (provider *FileProvider) filename() (string, error) {
+	if provider.Filename != "" {
+		return provider.Filename, nil
+	}
+	if filename := os.Getenv(ENV_SHARED_CREDENTIALS_FILE); filename != "" {
+		return filename, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", credentialsError(err.Error())
+	}
+	return filepath.Join(home, ".nos", "credentials"), nil
+}
+
+func (provider *FileProvider) profile() string {
+	if provider.Profile != "" {
+		return provider.Profile
+	}
+	if profile := os.Getenv(ENV_PROFILE); profile != "" {
+		return profile
+	}
+	return DEFAULT_PROFILE
+}
+
+func (provider *FileProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	filename, err := provider.filename()
+	if err != nil {
+		return Credentials{}, err
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Credentials{}, credentialsError(err.Error())
+	}
+	sections, err := ParseINI(content, nil)
+	if err != nil {
+		return Credentials{}, credentialsError(filename + ": " + err.Error())
+	}
+
+	profile := provider.profile()
+	section, ok := sections[profile]
+	if !ok {
+		return Credentials{}, credentialsError(filename + ": no profile " + strconv.Quote(profile))
+	}
+	creds := Credentials{
+		AccessKey:     section["access_key"],
+		SecretKey:     section["secret_key"],
+		SecurityToken: section["security_token"],
+	}
+	if !creds.Valid() {
+		return Credentials{}, credentialsError(filename + ": profile " + strconv.Quote(profile) +
+			" has no access_key or secret_key")
+	}
+	return creds, nil
+}
+
+// ParseINI parses the "key = value" lines of an INI file by section. The
+// lines before the first section are added to global, or are an error if
+// global is nil. Invalid lines are reported as a *LineError.
+func ParseINI(content []byte, global map[string]string) (map[string]map[string]string, error) {
+	sections := map[string]map[string]string{}
+	section := global
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if sections[name] == nil {
+				sections[name] = map[string]string{}
+			}
+			section = sections[name]
+		default:
+			equals := strings.IndexByte(line, '=')
+			if equals < 0 || section == nil {
+				return nil, &LineError{Line: number}
+			}
+			section[strings.TrimSpace(line[:equals])] = strings.TrimSpace(line[equals+1:])
+		}
+	}
+	return sections, scanner.Err()
+}
+
+// LineError reports the number of a line a configuration file parser could
+// not understand.
+type LineError struct {
+	Line int
+}
+
+func (err *LineError) Error() string {
+	return "invalid line " + strconv.Itoa(err.Line)
+}
+
+// ProcessProvider runs an external command and reads the credentials from
+// its standard output, a JSON object such as
+//
+//	{"accessKey": "...", "secretKey": "...", "securityToken": "...",
+//	 "expiration": "2026-01-02T15:04:05Z"}
+//
+// where the security token and the expiration, in RFC 3339 format, are
+// optional. The command is run
+// on every call, so it should be wrapped in a ChainProvider.
+type ProcessProvider struct {
+	// Command is the program and its arguments; it is not run by a shell.
+	Command []string
+
+	// Timeout bounds the run of the command. If zero, it is
+	// DEFAULT_PROCESS_TIMEOUT.
+	Timeout time.Duration
+}
+
+func NewProcessProvider(command ...string) *ProcessProvider {
+	return &ProcessProvider{
+		Command: command,
+	}
+}
+
+type processOutput struct {
+	AccessKey     string    `json:"accessKey"`
+	SecretKey     string    `json:"secretKey"`
+	SecurityToken string    `json:"securityToken"`
+	Expiration    time.Time `json:"expiration"`
+}
+
+func (provider *ProcessProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	if len(provider.Command) == 0 {
+		return Credentials{}, credentialsError("no credential process")
+	}
+
+	timeout := provider.Timeout
+	if timeout == 0 {
+		timeout = DEFAULT_PROCESS_TIMEOUT
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, provider.Command[0], provider.Command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := "credential process " + provider.Command[0] + ": " + err.Error()
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			msg += ": " + detail
+		}
+		return Credentials{}, credentialsError(msg)
+	}
+
+	var output processOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return Credentials{}, credentialsError("credential process " + provider.Command[0] + ": " + err.Error())
+	}
+	creds := Credentials{
+		AccessKey:     output.AccessKey,
+		SecretKey:     output.SecretKey,
+		SecurityToken: output.SecurityToken,
+		Expires:       output.Expiration,
+	}
+	if !creds.Valid() {
+		return Credentials{}, credentialsError("credential process " + provider.Command[0] +
+			" returned no accessKey or secretKey")
+	}
+	return creds, nil
+}
@@ -5,12 +5,14 @@ can be used as a built in replacement for the standard library's, providing:
 	* connection timeouts
 	* request timeouts
 
-This is a thin wrapper around `http.Transport` that sets dial timeouts and uses
-Go's internal timer scheduler to call the Go 1.1+ `CancelRequest()` API.
+This is a thin wrapper around `http.Transport` that sets dial timeouts and
+enforces request timeouts through a deadline on the request's context, so
+that callers can also cancel in-flight requests with their own context.
 */
 package httpclient
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"io"
@@ -187,15 +189,15 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 	t.starter.Do(t.lazyStart)
 
 	if t.RequestTimeout > 0 {
-		timer := time.AfterFunc(t.RequestTimeout, func() {
-			t.transport.CancelRequest(req)
-		})
+		// The deadline is derived from the request's own context, so a caller
+		// cancelling ctx and RequestTimeout expiring both abort the request.
+		ctx, cancel := context.WithTimeout(req.Context(), t.RequestTimeout)
 
-		resp, err = t.transport.RoundTrip(req)
+		resp, err = t.transport.RoundTrip(req.WithContext(ctx))
 		if err != nil {
-			timer.Stop()
+			cancel()
 		} else {
-			resp.Body = &bodyCloseInterceptor{ReadCloser: resp.Body, timer: timer}
+			resp.Body = &bodyCloseInterceptor{ReadCloser: resp.Body, cancel: cancel}
 		}
 	} else {
 		resp, err = t.transport.RoundTrip(req)
@@ -206,12 +208,13 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 
 type bodyCloseInterceptor struct {
 	io.ReadCloser
-	timer *time.Timer
+	cancel context.CancelFunc
 }
 
 func (bci *bodyCloseInterceptor) Close() error {
-	bci.timer.Stop()
-	return bci.ReadCloser.Close()
+	err := bci.ReadCloser.Close()
+	bci.cancel()
+	return err
 }
 
 // A net.Conn that sets a deadline for every Read or Write operation
@@ -1,6 +1,7 @@
 package httpclient
 
 import (
+	"context"
 	"crypto/tls"
 	"io"
 	"io/ioutil"
@@ -208,6 +209,30 @@ func TestSlowServer(t *testing.T) {
 	transport.Close()
 }
 
+func TestContextCancel(t *testing.T) {
+	starter.Do(func() { setupMockServer(t) })
+
+	transport := &Transport{
+		ConnectTimeout:   25 * time.Millisecond,
+		RequestTimeout:   5 * time.Second,
+		ReadWriteTimeout: 1 * time.Second,
+	}
+	client := &http.Client{Transport: transport}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://"+addr.String()+"/test", nil)
+	_, err := client.Do(req)
+	if err == nil {
+		t.Fatal("request should have been cancelled by its context")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatalf("context should have expired - %v", ctx.Err())
+	}
+	transport.Close()
+}
+
 func TestMultipleRequests(t *testing.T) {
 	starter.Do(func() { setupMockServer(t) })
 
@@ -181,6 +181,21 @@ func (nosLog NosLog) Debug(args ...interface{}) {
 }
 
 
+// DebugWith logs only when the configured LogLevel enables the given debug
+// sub level, e.g. LogDebugWithRequestRetries.
+func (nosLog NosLog) DebugWith(v LogLevelType, args ...interface{}) {
+	if !nosLog.LogLevel.Matches(v) {
+		return
+	}
+
+	if nosLog.Logger == nil {
+		return
+	}
+
+	nosLog.Logger.Log(args...)
+}
+
+
 func (nosLog NosLog) Trace(args ...interface{}) {
 	if nosLog.LogLevel.logOff(TRACE) {
 		return
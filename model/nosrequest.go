@@ -2,7 +2,9 @@ package model
 
 import (
 	"encoding/xml"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
 	"io"
+	"time"
 )
 
 // Create Bucket 
@@ -57,6 +59,53 @@ type PutObjectRequest struct {
 	Body     io.ReadSeeker
 	FilePath string
 	Metadata *ObjectMetadata
+
+	// Acl, if not nil, sets the object's ACL; otherwise the object follows
+	// the bucket's ACL.
+	Acl *nosconst.Acl
+}
+
+type PutObjectByReaderRequest struct {
+	Bucket string
+	Object string
+
+	// Body is read to its end; its length need not be known in advance.
+	Body io.Reader
+
+	// PartSize is the largest body sent with a single PUT, and the part
+	// size of the multipart upload used for larger ones. If zero,
+	// DEFAULT_PARTSIZE is used.
+	PartSize int64
+
+	// Concurrency is the number of parts of a multipart upload sent at the
+	// same time. If zero, DEFAULT_CONCURRENCY is used.
+	Concurrency int
+
+	Metadata *ObjectMetadata
+
+	// Acl, if not nil, sets the object's ACL; otherwise the object follows
+	// the bucket's ACL.
+	Acl *nosconst.Acl
+}
+
+type ObjectWriterRequest struct {
+	Bucket string
+	Object string
+
+	// PartSize is the size of the parts the written content is buffered
+	// into. Content that fits in one part is sent with a single PUT. If
+	// zero, DEFAULT_PARTSIZE is used.
+	PartSize int64
+
+	// Concurrency is the number of parts uploaded at the same time. If
+	// zero, DEFAULT_CONCURRENCY is used.
+	Concurrency int
+
+	Metadata *ObjectMetadata
+
+	// Acl, if not nil, sets the object's ACL; otherwise the object follows
+	// the bucket's ACL.
+	Acl *nosconst.Acl
 }
 
 type CopyObjectRequest struct {
@@ -83,11 +132,17 @@ type GetObjectRequest struct {
 	Object          string
 	ObjRange        string
 	IfModifiedSince string
+
+	// VersionId, if set, selects a version other than the latest one.
+	VersionId string
 }
 
 type ObjectRequest struct {
 	Bucket string
 	Object string
+
+	// VersionId, if set, selects a version other than the latest one.
+	VersionId string
 }
 
 type ListObjectsRequest struct {
@@ -102,6 +157,9 @@ type InitMultiUploadRequest struct {
 	Bucket   string
 	Object   string
 	Metadata *ObjectMetadata
+
+	// Acl, if not nil, sets the ACL of the completed object.
+	Acl *nosconst.Acl
 }
 
 type UploadPartRequest struct {
@@ -138,7 +196,142 @@ type ListUploadPartsRequest struct {
 }
 
 type ListMultiUploadsRequest struct {
-	Bucket     string
-	KeyMarker  string
-	MaxUploads int
+	Bucket         string
+	KeyMarker      string
+	UploadIdMarker string
+	MaxUploads     int
+}
+
+type UploadRequest struct {
+	Bucket string
+	Object string
+
+	// The content is read from FilePath, or from Body if FilePath is empty.
+	FilePath string
+	Body     io.Reader
+
+	// Size is the length of Body, if known, and is used to choose a part
+	// size. It is ignored for FilePath.
+	Size int64
+
+	Metadata *ObjectMetadata
+
+	// Acl, if not nil, sets the ACL of the uploaded object.
+	Acl *nosconst.Acl
+
+	// CheckpointFile, if set, makes an upload from FilePath resumable: the
+	// upload id and the completed parts are recorded in this file, and a later
+	// upload of the same, unmodified file with the same CheckpointFile skips
+	// the parts that are already on the server. The file is removed once the
+	// upload completes.
+	CheckpointFile string
+}
+
+// Bucket versioning
+type VersioningConfiguration struct {
+	XMLName xml.Name `xml:"VersioningConfiguration"`
+	Status  string   `xml:"Status,omitempty"`
+}
+
+type ListObjectVersionsRequest struct {
+	Bucket          string
+	Prefix          string
+	Delimiter       string
+	KeyMarker       string
+	VersionIdMarker string
+	MaxKeys         int
+}
+
+type PutObjectAclRequest struct {
+	Bucket string
+	Object string
+	Acl    nosconst.Acl
+}
+
+type DownloadRequest struct {
+	Bucket string
+	Object string
+
+	// The content is written to FilePath, or to Writer if FilePath is empty.
+	FilePath string
+	Writer   io.WriterAt
+
+	// CheckpointFile, if set, makes a download to FilePath resumable: the
+	// completed ranges are recorded in this file, and a later download of the
+	// same, unchanged object with the same CheckpointFile only fetches the
+	// missing ranges. The file is removed once the download completes.
+	CheckpointFile string
+}
+
+type ObjectReaderRequest struct {
+	Bucket string
+	Object string
+
+	// VersionId, if set, selects a version other than the latest one.
+	VersionId string
+
+	// BlockSize is the size of the ranges fetched from the object. If zero,
+	// DEFAULT_BLOCKSIZE is used.
+	BlockSize int64
+
+	// ReadAhead is the number of blocks fetched ahead of sequential reads.
+	// If zero, DEFAULT_READAHEAD is used; if negative, nothing is read ahead.
+	ReadAhead int
+
+	// CacheBlocks is the number of fetched blocks kept in memory. If zero,
+	// DEFAULT_CACHEBLOCKS is used. The cache always holds at least the
+	// blocks read ahead.
+	CacheBlocks int
+}
+
+type PresignRequest struct {
+	Bucket string
+	Object string
+
+	// Expires is how long the URL stays valid, counted from its generation.
+	Expires time.Duration
+
+	// ContentType and ContentMd5 are signed into PUT URLs; the uploader must
+	// send exactly the same headers.
+	ContentType string
+	ContentMd5  string
+
+	// Response header overrides, applied by the server to GET responses.
+	ResponseContentType        string
+	ResponseContentDisposition string
+	ResponseContentEncoding    string
+	ResponseContentLanguage    string
+	ResponseCacheControl       string
+	ResponseExpires            string
+}
+
+type SyncRequest struct {
+	Bucket string
+
+	// Prefix and LocalDir are the trees kept in sync: the file
+	// LocalDir/a/b corresponds to the object Prefix+"a/b". Prefix usually
+	// ends with "/".
+	Prefix   string
+	LocalDir string
+
+	// Include and Exclude are path.Match patterns, matched against the
+	// slash-separated path of a file below LocalDir, and also against its
+	// base name if the pattern has no slash. A file is synced if it matches
+	// one of the Include patterns, or there are none, and no Exclude
+	// pattern. Files that are not synced are never deleted either.
+	Include []string
+	Exclude []string
+
+	// By default a file and an object of the same size are the same if the
+	// MD5 of the file is the object's ETag. Objects uploaded in parts have
+	// no such ETag and, like all objects if CompareModTime is set, are
+	// compared by modification time instead. Compressed objects are compared
+	// by the uncompressed length and MD5 recorded when they were uploaded.
+	CompareModTime bool
+
+	// Delete removes the files or objects missing from the source.
+	Delete bool
+
+	// DryRun reports what would be done without changing anything.
+	DryRun bool
 }
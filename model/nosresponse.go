@@ -2,12 +2,16 @@ package model
 
 import (
 	"encoding/xml"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
 	"io"
 )
 
 type ObjectResult struct {
 	Etag      string
 	RequestId string
+
+	// VersionId is set if the bucket has versioning enabled or suspended.
+	VersionId string
 }
 
 type NOSObject struct {
@@ -17,6 +21,45 @@ type NOSObject struct {
 	Body           io.ReadCloser `type:"blob"`
 }
 
+type Bucket struct {
+	XMLName      xml.Name `xml:"Bucket"`
+	Name         string   `xml:"Name"`
+	CreationDate string   `xml:"CreationDate"`
+}
+
+type ListBucketsResult struct {
+	XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+	Owner   Owner    `xml:"Owner"`
+	Buckets []Bucket `xml:"Buckets>Bucket"`
+}
+
+type GetBucketLocationResult struct {
+	XMLName  xml.Name `xml:"LocationConstraint"`
+	Location string   `xml:",chardata"`
+}
+
+type Grantee struct {
+	XMLName     xml.Name `xml:"Grantee"`
+	Id          string   `xml:"ID,omitempty"`
+	DisplayName string   `xml:"DisplayName,omitempty"`
+	URI         string   `xml:"URI,omitempty"`
+}
+
+type Grant struct {
+	XMLName    xml.Name `xml:"Grant"`
+	Grantee    Grantee  `xml:"Grantee"`
+	Permission string   `xml:"Permission"`
+}
+
+type AclResult struct {
+	XMLName xml.Name `xml:"AccessControlPolicy"`
+	Owner   Owner    `xml:"Owner"`
+	Grants  []Grant  `xml:"AccessControlList>Grant"`
+
+	// Acl is the canned ACL the grants amount to.
+	Acl nosconst.Acl `xml:"-"`
+}
+
 type DeleteError struct {
 	XMLName xml.Name `xml:"Error"`
 	Key     string   `xml:"Key"`
@@ -102,6 +145,39 @@ type CommonPrefix struct {
 	Prefix  string   `xml:"Prefix"`
 }
 
+type ObjectVersion struct {
+	XMLName      xml.Name `xml:"Version"`
+	Key          string   `xml:"Key"`
+	VersionId    string   `xml:"VersionId"`
+	IsLatest     bool     `xml:"IsLatest"`
+	LastModified string   `xml:"LastModified"`
+	Etag         string   `xml:"ETag"`
+	Size         int64    `xml:"Size"`
+}
+
+type DeleteMarker struct {
+	XMLName      xml.Name `xml:"DeleteMarker"`
+	Key          string   `xml:"Key"`
+	VersionId    string   `xml:"VersionId"`
+	IsLatest     bool     `xml:"IsLatest"`
+	LastModified string   `xml:"LastModified"`
+}
+
+type ListVersionsResult struct {
+	XMLName             xml.Name        `xml:"ListVersionsResult"`
+	Bucket              string          `xml:"Name"`
+	Prefix              string          `xml:"Prefix"`
+	KeyMarker           string          `xml:"KeyMarker"`
+	VersionIdMarker     string          `xml:"VersionIdMarker"`
+	NextKeyMarker       string          `xml:"NextKeyMarker"`
+	NextVersionIdMarker string          `xml:"NextVersionIdMarker"`
+	MaxKeys             int             `xml:"MaxKeys"`
+	IsTruncated         bool            `xml:"IsTruncated"`
+	Versions            []ObjectVersion `xml:"Version"`
+	DeleteMarkers       []DeleteMarker  `xml:"DeleteMarker"`
+	CommonPrefixes      []CommonPrefix  `xml:"CommonPrefixes"`
+}
+
 type MultipartUpload struct {
 	XMLName      xml.Name `xml:"Upload"`
 	Key          string   `xml:"Key"`
@@ -112,9 +188,28 @@ type MultipartUpload struct {
 }
 
 type ListMultiUploadsResult struct {
-	XMLName       xml.Name          `xml:"ListMultipartUploadsResult"`
-	Bucket        string            `xml:"Bucket"`
-	NextKeyMarker string            `xml:"NextKeyMarker"`
-	IsTruncated   bool              `xml:"IsTruncated"`
-	Uploads       []MultipartUpload `xml:"Upload"`
+	XMLName            xml.Name          `xml:"ListMultipartUploadsResult"`
+	Bucket             string            `xml:"Bucket"`
+	NextKeyMarker      string            `xml:"NextKeyMarker"`
+	NextUploadIdMarker string            `xml:"NextUploadIdMarker"`
+	IsTruncated        bool              `xml:"IsTruncated"`
+	Uploads            []MultipartUpload `xml:"Upload"`
+}
+
+type SyncAction struct {
+	// Op is nosconst.SYNC_UPLOAD, nosconst.SYNC_DOWNLOAD or
+	// nosconst.SYNC_DELETE.
+	Op   string
+	Key  string
+	Path string
+	Size int64
+}
+
+type SyncResult struct {
+	// Actions lists what was done, or would be done in a dry run, ordered
+	// by key.
+	Actions []SyncAction
+
+	// Unchanged is the number of files that were already in sync.
+	Unchanged int
 }
@@ -0,0 +1,170 @@
+package nosclient
+
+import (
+	"context"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"net/http"
+)
+
+func (client *NosClient) PutBucketAcl(bucketName string, acl nosconst.Acl) error {
+	return client.PutBucketAclWithContext(context.Background(), bucketName, acl)
+}
+
+// PutBucketAclWithContext is like PutBucketAcl but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) PutBucketAclWithContext(ctx context.Context, bucketName string, acl nosconst.Acl) error {
+	err := utils.VerifyParams(bucketName)
+	if err != nil {
+		return err
+	}
+
+	return client.putAcl(ctx, bucketName, "", acl)
+}
+
+func (client *NosClient) GetBucketAcl(bucketName string) (*model.AclResult, error) {
+	return client.GetBucketAclWithContext(context.Background(), bucketName)
+}
+
+// GetBucketAclWithContext is like GetBucketAcl but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) GetBucketAclWithContext(ctx context.Context, bucketName string) (*model.AclResult, error) {
+	err := utils.VerifyParams(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.getAcl(ctx, bucketName, "")
+}
+
+func (client *NosClient) PutObjectAcl(putObjectAclRequest *model.PutObjectAclRequest) error {
+	return client.PutObjectAclWithContext(context.Background(), putObjectAclRequest)
+}
+
+// PutObjectAclWithContext is like PutObjectAcl but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) PutObjectAclWithContext(ctx context.Context, putObjectAclRequest *model.PutObjectAclRequest) error {
+	if putObjectAclRequest == nil {
+		return utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	err := utils.VerifyParamsWithObject(putObjectAclRequest.Bucket, putObjectAclRequest.Object)
+	if err != nil {
+		return err
+	}
+
+	return client.putAcl(ctx, putObjectAclRequest.Bucket, putObjectAclRequest.Object, putObjectAclRequest.Acl)
+}
+
+// GetObjectAcl returns the ACL of an object, which is the bucket's ACL if
+// none was set on the object.
+func (client *NosClient) GetObjectAcl(objectRequest *model.ObjectRequest) (*model.AclResult, error) {
+	return client.GetObjectAclWithContext(context.Background(), objectRequest)
+}
+
+// GetObjectAclWithContext is like GetObjectAcl but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) GetObjectAclWithContext(ctx context.Context, objectRequest *model.ObjectRequest) (*model.AclResult, error) {
+	if objectRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	err := utils.VerifyParamsWithObject(objectRequest.Bucket, objectRequest.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.getAcl(ctx, objectRequest.Bucket, objectRequest.Object)
+}
+
+func (client *NosClient) putAcl(ctx context.Context, bucket, object string, acl nosconst.Acl) error {
+	metadata, err := withAcl(nil, &acl, bucket, object)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		nosconst.ACL: "",
+	}
+
+	resp, err := client.doRequest(ctx, "PUT", bucket, object, metadata, nil, params, nosconst.XML_TYPE)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	} else {
+		err := utils.ProcessServerError(resp, bucket, object)
+		return err
+	}
+}
+
+func (client *NosClient) getAcl(ctx context.Context, bucket, object string) (*model.AclResult, error) {
+	params := map[string]string{
+		nosconst.ACL: "",
+	}
+
+	resp, err := client.doRequest(ctx, "GET", bucket, object, nil, nil, params, nosconst.XML_TYPE)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusOK {
+		result := &model.AclResult{}
+		err = utils.ParseXmlBody(resp.Body, result)
+		if err != nil {
+			return nil, err
+		}
+
+		// The header, when sent, names the canned ACL; otherwise a bucket
+		// or object anyone may read is public-read.
+		if acl, ok := nosconst.ParseAcl(resp.Header.Get(nosconst.X_NOS_ACL)); ok {
+			result.Acl = acl
+		} else {
+			result.Acl = nosconst.PRIVATE
+			for _, grant := range result.Grants {
+				if grant.Grantee.URI == nosconst.ACL_ALL_USERS &&
+					(grant.Permission == nosconst.ACL_READ || grant.Permission == nosconst.ACL_FULL_CONTROL) {
+					result.Acl = nosconst.PUBLICREAD
+				}
+			}
+		}
+
+		return result, nil
+	} else {
+		err := utils.ProcessServerError(resp, bucket, object)
+		return nil, err
+	}
+}
+
+// withAcl returns metadata with the x-nos-acl header for acl added, leaving
+// the caller's metadata untouched. A nil acl returns metadata unchanged.
+func withAcl(metadata *model.ObjectMetadata, acl *nosconst.Acl, bucket, object string) (*model.ObjectMetadata, error) {
+	if acl == nil {
+		return metadata, nil
+	}
+
+	cannedAcl := acl.String()
+	if cannedAcl == "" {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_ACL_INVALID, bucket, object, "")
+	}
+
+	result := &model.ObjectMetadata{
+		Metadata: map[string]string{},
+	}
+	if metadata != nil {
+		result.ContentLength = metadata.ContentLength
+		for key, value := range metadata.Metadata {
+			result.Metadata[key] = value
+		}
+	}
+	result.Metadata[nosconst.X_NOS_ACL] = cannedAcl
+	return result, nil
+}
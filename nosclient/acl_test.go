@@ -0,0 +1,143 @@
+package nosclient
+
+import (
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+type AclTestSuite struct {
+	server    *nostest.Server
+	nosClient *NosClient
+}
+
+var _ = Suite(&AclTestSuite{})
+
+func (s *AclTestSuite) SetUpTest(c *C) {
+	s.nosClient, s.server = newTestClient(c)
+}
+
+func (s *AclTestSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+// anonymousGet returns the status code of an unsigned GET of an object.
+func (s *AclTestSuite) anonymousGet(c *C, object string) int {
+	resp, err := http.Get(s.server.URL + "/" + TEST_BUCKET + "/" + object)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *AclTestSuite) TestBucketAcl(c *C) {
+	putTestObject(c, s.nosClient, TEST_BUCKET, "object", []byte("data"))
+
+	result, err := s.nosClient.GetBucketAcl(TEST_BUCKET)
+	c.Assert(err, IsNil)
+	c.Assert(result.Acl, Equals, nosconst.PRIVATE)
+	c.Assert(result.Grants, HasLen, 1)
+	c.Assert(result.Grants[0].Permission, Equals, nosconst.ACL_FULL_CONTROL)
+	c.Assert(s.anonymousGet(c, "object"), Equals, http.StatusForbidden)
+
+	err = s.nosClient.PutBucketAcl(TEST_BUCKET, nosconst.PUBLICREAD)
+	c.Assert(err, IsNil)
+
+	result, err = s.nosClient.GetBucketAcl(TEST_BUCKET)
+	c.Assert(err, IsNil)
+	c.Assert(result.Acl, Equals, nosconst.PUBLICREAD)
+	c.Assert(s.anonymousGet(c, "object"), Equals, http.StatusOK)
+
+	err = s.nosClient.PutBucketAcl(BUCKETNOTEXIST, nosconst.PUBLICREAD)
+	c.Assert(err, ErrorMatches, ".*NoSuchBucket.*")
+}
+
+func (s *AclTestSuite) TestObjectAcl(c *C) {
+	public := nosconst.PUBLICREAD
+	_, err := s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "public",
+		Body:   strings.NewReader("data"),
+		Acl:    &public,
+	})
+	c.Assert(err, IsNil)
+	putTestObject(c, s.nosClient, TEST_BUCKET, "inherited", []byte("data"))
+
+	c.Assert(s.anonymousGet(c, "public"), Equals, http.StatusOK)
+	c.Assert(s.anonymousGet(c, "inherited"), Equals, http.StatusForbidden)
+
+	result, err := s.nosClient.GetObjectAcl(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: "public"})
+	c.Assert(err, IsNil)
+	c.Assert(result.Acl, Equals, nosconst.PUBLICREAD)
+
+	err = s.nosClient.PutObjectAcl(&model.PutObjectAclRequest{
+		Bucket: TEST_BUCKET,
+		Object: "public",
+		Acl:    nosconst.PRIVATE,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.anonymousGet(c, "public"), Equals, http.StatusForbidden)
+
+	// An object's own ACL wins over the bucket's.
+	err = s.nosClient.PutBucketAcl(TEST_BUCKET, nosconst.PUBLICREAD)
+	c.Assert(err, IsNil)
+	c.Assert(s.anonymousGet(c, "public"), Equals, http.StatusForbidden)
+	c.Assert(s.anonymousGet(c, "inherited"), Equals, http.StatusOK)
+
+	err = s.nosClient.PutObjectAcl(&model.PutObjectAclRequest{
+		Bucket: TEST_BUCKET,
+		Object: "missing",
+		Acl:    nosconst.PRIVATE,
+	})
+	c.Assert(err, ErrorMatches, ".*NoSuchKey.*")
+}
+
+func (s *AclTestSuite) TestMultiUploadAcl(c *C) {
+	public := nosconst.PUBLICREAD
+	uploader := NewUploader(s.nosClient)
+	_, err := uploader.Upload(&model.UploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "multipart",
+		Body:   strings.NewReader("data"),
+		Acl:    &public,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.anonymousGet(c, "multipart"), Equals, http.StatusOK)
+}
+
+func (s *AclTestSuite) TestInvalidAcl(c *C) {
+	invalid := nosconst.Acl(42)
+	_, err := s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "object",
+		Body:   strings.NewReader("data"),
+		Acl:    &invalid,
+	})
+	c.Assert(err, ErrorMatches, "StatusCode = 445, .*InvalidAcl")
+}
+
+func (s *AclTestSuite) TestAclFromGrants(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<AccessControlPolicy><Owner><ID>owner</ID></Owner><AccessControlList>`+
+			`<Grant><Grantee><ID>owner</ID></Grantee><Permission>FULL_CONTROL</Permission></Grant>`+
+			`<Grant><Grantee><URI>`+nosconst.ACL_ALL_USERS+`</URI></Grantee><Permission>READ</Permission></Grant>`+
+			`</AccessControlList></AccessControlPolicy>`)
+	}))
+	defer server.Close()
+
+	conf := &config.Config{Endpoint: server.URL, AccessKey: "12345", SecretKey: "12345"}
+	conf.SetIsSubDomain(false)
+	client, err := New(conf)
+	c.Assert(err, IsNil)
+
+	result, err := client.GetBucketAcl(TEST_BUCKET)
+	c.Assert(err, IsNil)
+	c.Assert(result.Acl, Equals, nosconst.PUBLICREAD)
+	c.Assert(result.Owner.Id, Equals, "owner")
+	c.Assert(result.Grants[1].Grantee.URI, Equals, nosconst.ACL_ALL_USERS)
+}
@@ -0,0 +1,317 @@
+package nosclient
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const checkpointVersion = 1
+
+// uploadCheckpoint records the progress of a multipart upload of a local
+// file, so that an interrupted upload can be resumed by a later process.
+type uploadCheckpoint struct {
+	Version  int
+	Bucket   string
+	Object   string
+	UploadId string
+	PartSize int64
+
+	FilePath    string
+	FileSize    int64
+	FileModTime int64
+	FileMd5     string
+
+	// Codec is the name of the codec the file is compressed with, if any.
+	Codec string
+
+	Parts []checkpointPart
+}
+
+type checkpointPart struct {
+	PartNumber int
+	Etag       string
+}
+
+type fileFingerprint struct {
+	size    int64
+	modTime int64
+	md5     string
+}
+
+func getFileFingerprint(file *os.File) (*fileFingerprint, error) {
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, fi.Size())); err != nil {
+		return nil, err
+	}
+
+	return &fileFingerprint{
+		size:    fi.Size(),
+		modTime: fi.ModTime().UnixNano(),
+		md5:     hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// loadCheckpoint reads the checkpoint at path into checkpoint. It returns
+// false if the file does not exist or cannot be parsed.
+func loadCheckpoint(path string, checkpoint interface{}) bool {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(content, checkpoint) == nil
+}
+
+// saveCheckpoint writes the checkpoint through a temporary file, so a crash
+// never leaves a truncated checkpoint behind.
+func saveCheckpoint(path string, checkpoint interface{}) error {
+	content, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadUploadCheckpoint returns nil if there is no usable checkpoint at path.
+func loadUploadCheckpoint(path string) *uploadCheckpoint {
+	checkpoint := &uploadCheckpoint{}
+	if !loadCheckpoint(path, checkpoint) || checkpoint.Version != checkpointVersion {
+		return nil
+	}
+	return checkpoint
+}
+
+// matches reports whether the checkpoint was written for the same upload of
+// the same, unmodified file.
+func (checkpoint *uploadCheckpoint) matches(bucket, object, filePath string, partSize int64,
+	fingerprint *fileFingerprint, codec string) bool {
+
+	return checkpoint.Bucket == bucket && checkpoint.Object == object &&
+		checkpoint.FilePath == filePath && checkpoint.PartSize == partSize &&
+		checkpoint.FileSize == fingerprint.size && checkpoint.FileModTime == fingerprint.modTime &&
+		checkpoint.FileMd5 == fingerprint.md5 && checkpoint.Codec == codec && checkpoint.UploadId != ""
+}
+
+func (checkpoint *uploadCheckpoint) save(path string) error {
+	return saveCheckpoint(path, checkpoint)
+}
+
+// uploadWithCheckpoint uploads file, resuming the upload recorded in the
+// checkpoint file if it still matches the file. A failed upload is not
+// aborted, so that it can be resumed later.
+//
+// A compressed file is compressed again from the start when the upload is
+// resumed, and only the parts that come out the same as the uploaded ones
+// are skipped.
+func (uploader *Uploader) uploadWithCheckpoint(ctx context.Context, uploadRequest *model.UploadRequest,
+	file *os.File, partSize int64) (*model.CompleteMultiUploadResult, error) {
+
+	bucket := uploadRequest.Bucket
+	object := uploadRequest.Object
+	path := uploadRequest.CheckpointFile
+
+	fingerprint, err := getFileFingerprint(file)
+	if err != nil {
+		return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, object, err)
+	}
+
+	metadata := uploadRequest.Metadata
+	codec := uploader.client.compressionCodec(metadata)
+	codecName := ""
+	if codec != nil {
+		codecName = codec.Name()
+		metadata = compressedMetadata(metadata, codec, fingerprint.size, fingerprint.md5)
+	}
+
+	var done map[int]model.UploadPart
+	checkpoint := loadUploadCheckpoint(path)
+	if checkpoint != nil &&
+		checkpoint.matches(bucket, object, uploadRequest.FilePath, partSize, fingerprint, codecName) {
+		// The sizes of compressed parts are only known once they are
+		// compressed again.
+		size := fingerprint.size
+		if codec != nil {
+			size = -1
+		}
+		done, err = uploader.listDoneParts(ctx, checkpoint, size)
+		if noserror.IsNotFound(err) {
+			// The upload was completed or aborted since the checkpoint was saved.
+			checkpoint = nil
+		} else if err != nil {
+			return nil, err
+		}
+	} else {
+		if checkpoint != nil && checkpoint.Bucket == bucket && checkpoint.Object == object && checkpoint.UploadId != "" {
+			// The file has changed, so the parts already uploaded are useless.
+			uploader.abort(bucket, object, checkpoint.UploadId)
+		}
+		checkpoint = nil
+	}
+
+	if checkpoint == nil {
+		initResult, err := uploader.client.InitMultiUploadWithContext(ctx, &model.InitMultiUploadRequest{
+			Bucket:   bucket,
+			Object:   object,
+			Metadata: metadata,
+			Acl:      uploadRequest.Acl,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		checkpoint = &uploadCheckpoint{
+			Version:     checkpointVersion,
+			Bucket:      bucket,
+			Object:      object,
+			UploadId:    initResult.UploadId,
+			PartSize:    partSize,
+			FilePath:    uploadRequest.FilePath,
+			FileSize:    fingerprint.size,
+			FileModTime: fingerprint.modTime,
+			FileMd5:     fingerprint.md5,
+			Codec:       codecName,
+		}
+		done = map[int]model.UploadPart{}
+	}
+
+	checkpoint.Parts = checkpoint.Parts[:0]
+	for _, part := range done {
+		checkpoint.Parts = append(checkpoint.Parts, checkpointPart{PartNumber: part.PartNumber, Etag: part.Etag})
+	}
+	if err := checkpoint.save(path); err != nil {
+		return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, object, err)
+	}
+
+	var objectMd5 string
+	if uploader.client.verifyIntegrity {
+		objectMd5 = fingerprint.md5
+	}
+
+	produce := func(emit func(partContent) bool) error {
+		return readFileParts(file, fingerprint.size, partSize, done, emit)
+	}
+	if codec != nil {
+		produce = func(emit func(partContent) bool) error {
+			compressed := compressReader(codec, io.NewSectionReader(file, 0, fingerprint.size))
+			defer compressed.Close()
+
+			var reader io.Reader = compressed
+			var objectHash hash.Hash
+			if uploader.client.verifyIntegrity {
+				objectHash = md5.New()
+				reader = io.TeeReader(reader, objectHash)
+			}
+
+			same := make(map[int]model.UploadPart)
+			err := readParts(reader, partSize, 1, func(part partContent) bool {
+				if uploaded, ok := done[part.number]; ok &&
+					strings.EqualFold(utils.RemoveQuotes(uploaded.Etag), md5Hex(part.data)) {
+					same[part.number] = uploaded
+					return true
+				}
+				return emit(part)
+			})
+			done = same
+			objectMd5 = hashHex(objectHash)
+			return err
+		}
+	}
+
+	uploaded, err := uploader.uploadParts(ctx, bucket, object, checkpoint.UploadId, produce,
+		func(part model.UploadPart) {
+			checkpoint.Parts = append(checkpoint.Parts, checkpointPart{PartNumber: part.PartNumber, Etag: part.Etag})
+			if err := checkpoint.save(path); err != nil {
+				uploader.client.Log.Warn("save checkpoint", path, "failed:", err)
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	parts := uploaded
+	for _, part := range done {
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+
+	result, err := uploader.client.CompleteMultiUploadWithContext(ctx, &model.CompleteMultiUploadRequest{
+		Bucket:    bucket,
+		Object:    object,
+		UploadId:  checkpoint.UploadId,
+		Parts:     parts,
+		ObjectMd5: objectMd5,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(path); err != nil {
+		uploader.client.Log.Warn("remove checkpoint", path, "failed:", err)
+	}
+	return result, nil
+}
+
+// listDoneParts returns the parts of the checkpointed upload that are on the
+// server with the expected size, unless size is negative, and, if the
+// checkpoint has recorded one, the expected ETag.
+func (uploader *Uploader) listDoneParts(ctx context.Context, checkpoint *uploadCheckpoint,
+	size int64) (map[int]model.UploadPart, error) {
+
+	recorded := make(map[int]string)
+	for _, part := range checkpoint.Parts {
+		recorded[part.PartNumber] = part.Etag
+	}
+
+	done := make(map[int]model.UploadPart)
+	paginator := uploader.client.NewUploadPartsPaginator(ctx, &model.ListUploadPartsRequest{
+		Bucket:   checkpoint.Bucket,
+		Object:   checkpoint.Object,
+		UploadId: checkpoint.UploadId,
+	})
+	for paginator.Next() {
+		for _, part := range paginator.Page().Parts {
+			if size >= 0 && (part.PartNumber > partCount(size, checkpoint.PartSize) ||
+				int64(part.Size) != expectedPartSize(size, checkpoint.PartSize, part.PartNumber)) {
+				continue
+			}
+			if etag, ok := recorded[part.PartNumber]; ok && utils.RemoveQuotes(etag) != utils.RemoveQuotes(part.Etag) {
+				continue
+			}
+			done[part.PartNumber] = model.UploadPart{PartNumber: part.PartNumber, Etag: part.Etag}
+		}
+	}
+	if err := paginator.Err(); err != nil {
+		return nil, err
+	}
+	return done, nil
+}
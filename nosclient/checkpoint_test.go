@@ -0,0 +1,155 @@
+package nosclient
+
+import (
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func (s *UploaderTestSuite) writeFile(c *C, content []byte) string {
+	path := filepath.Join(c.MkDir(), "file")
+	c.Assert(ioutil.WriteFile(path, content, 0644), IsNil)
+	return path
+}
+
+// startUpload uploads the first parts of path and records them in a
+// checkpoint, as an interrupted upload would have done.
+func (s *UploaderTestSuite) startUpload(c *C, path, object, checkpointPath string, parts int) *uploadCheckpoint {
+	file, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer file.Close()
+
+	fingerprint, err := getFileFingerprint(file)
+	c.Assert(err, IsNil)
+
+	initResult, err := s.nosClient.InitMultiUpload(&model.InitMultiUploadRequest{Bucket: TEST_BUCKET, Object: object})
+	c.Assert(err, IsNil)
+
+	checkpoint := &uploadCheckpoint{
+		Version:     checkpointVersion,
+		Bucket:      TEST_BUCKET,
+		Object:      object,
+		UploadId:    initResult.UploadId,
+		PartSize:    nosconst.MIN_FILESIZE,
+		FilePath:    path,
+		FileSize:    fingerprint.size,
+		FileModTime: fingerprint.modTime,
+		FileMd5:     fingerprint.md5,
+	}
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	for number := 1; number <= parts; number++ {
+		result, err := s.nosClient.UploadPart(&model.UploadPartRequest{
+			Bucket:     TEST_BUCKET,
+			Object:     object,
+			UploadId:   initResult.UploadId,
+			PartNumber: number,
+			Content:    content[(number-1)*nosconst.MIN_FILESIZE : number*nosconst.MIN_FILESIZE],
+			PartSize:   nosconst.MIN_FILESIZE,
+		})
+		c.Assert(err, IsNil)
+
+		// The last part is on the server but missing from the checkpoint, as
+		// if the process died before saving it.
+		if number < parts {
+			checkpoint.Parts = append(checkpoint.Parts, checkpointPart{PartNumber: number, Etag: result.Etag})
+		}
+	}
+
+	c.Assert(checkpoint.save(checkpointPath), IsNil)
+	return checkpoint
+}
+
+func (s *UploaderTestSuite) TestCheckpointResume(c *C) {
+	content := randomContent(4*nosconst.MIN_FILESIZE + 100)
+	path := s.writeFile(c, content)
+	checkpointPath := filepath.Join(c.MkDir(), "checkpoint")
+	s.startUpload(c, path, "uploader/resumed", checkpointPath, 2)
+
+	uploader := NewUploader(s.nosClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+
+	result, err := uploader.Upload(&model.UploadRequest{
+		Bucket:         TEST_BUCKET,
+		Object:         "uploader/resumed",
+		FilePath:       path,
+		CheckpointFile: checkpointPath,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Etag, Matches, ".*-5")
+	c.Assert(s.getContent(c, "uploader/resumed"), DeepEquals, content)
+
+	// The interrupted upload was completed rather than replaced.
+	uploads, err := s.nosClient.ListMultiUploads(&model.ListMultiUploadsRequest{Bucket: TEST_BUCKET})
+	c.Assert(err, IsNil)
+	c.Assert(uploads.Uploads, HasLen, 0)
+
+	_, err = os.Stat(checkpointPath)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *UploaderTestSuite) TestCheckpointFileChanged(c *C) {
+	content := randomContent(3 * nosconst.MIN_FILESIZE)
+	path := s.writeFile(c, content)
+	checkpointPath := filepath.Join(c.MkDir(), "checkpoint")
+	s.startUpload(c, path, "uploader/changed", checkpointPath, 2)
+
+	content = randomContent(3*nosconst.MIN_FILESIZE + 1)
+	c.Assert(ioutil.WriteFile(path, content, 0644), IsNil)
+
+	uploader := NewUploader(s.nosClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+
+	_, err := uploader.Upload(&model.UploadRequest{
+		Bucket:         TEST_BUCKET,
+		Object:         "uploader/changed",
+		FilePath:       path,
+		CheckpointFile: checkpointPath,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.getContent(c, "uploader/changed"), DeepEquals, content)
+
+	uploads, err := s.nosClient.ListMultiUploads(&model.ListMultiUploadsRequest{Bucket: TEST_BUCKET})
+	c.Assert(err, IsNil)
+	c.Assert(uploads.Uploads, HasLen, 0)
+}
+
+func (s *UploaderTestSuite) TestCheckpointUploadGone(c *C) {
+	content := randomContent(2 * nosconst.MIN_FILESIZE)
+	path := s.writeFile(c, content)
+	checkpointPath := filepath.Join(c.MkDir(), "checkpoint")
+	checkpoint := s.startUpload(c, path, "uploader/gone", checkpointPath, 1)
+
+	err := s.nosClient.AbortMultiUpload(&model.AbortMultiUploadRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "uploader/gone",
+		UploadId: checkpoint.UploadId,
+	})
+	c.Assert(err, IsNil)
+
+	uploader := NewUploader(s.nosClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+
+	_, err = uploader.Upload(&model.UploadRequest{
+		Bucket:         TEST_BUCKET,
+		Object:         "uploader/gone",
+		FilePath:       path,
+		CheckpointFile: checkpointPath,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.getContent(c, "uploader/gone"), DeepEquals, content)
+}
+
+func (s *UploaderTestSuite) TestCheckpointRequiresFile(c *C) {
+	_, err := NewUploader(s.nosClient).Upload(&model.UploadRequest{
+		Bucket:         TEST_BUCKET,
+		Object:         "uploader/reader",
+		Body:           &failingReader{},
+		CheckpointFile: filepath.Join(c.MkDir(), "checkpoint"),
+	})
+	c.Assert(err, NotNil)
+}
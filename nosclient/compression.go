@@ -0,0 +1,257 @@
+package nosclient
+
+import (
+	"compress/gzip"
+	"crypto/md5"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Codec compresses and decompresses object content for the compression
+// mode selected by Config.Compression.
+type Codec interface {
+	// Name is recorded in the metadata of the objects the codec
+	// compresses, and selects the codec when they are read.
+	Name() string
+
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{}
+)
+
+func init() {
+	RegisterCodec(gzipCodec{})
+}
+
+// RegisterCodec makes codec available to Config.Compression and to
+// GetObject, replacing any codec with the same name. Only gzip is built
+// in, as the SDK depends on the standard library alone; other codecs, such
+// as one wrapping github.com/klauspost/compress/zstd, are registered by the
+// application under a name of its choosing.
+func RegisterCodec(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[codec.Name()] = codec
+}
+
+func lookupCodec(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[name]
+	return codec, ok
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return nosconst.COMPRESSION_GZIP
+}
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// compressionCodec returns the codec content written with metadata is
+// compressed with, or nil if it is written as it is: when compression is
+// off, when the caller already encoded the content, and for encrypted
+// content, which does not compress.
+func (client *NosClient) compressionCodec(metadata *model.ObjectMetadata) Codec {
+	if client.codec == nil || hasHeader(metadata, nosconst.CONTENT_ENCODING) ||
+		hasHeader(metadata, nosconst.X_NOS_META_COMPRESSION) ||
+		hasHeader(metadata, nosconst.X_NOS_META_ENCRYPTION_ALGORITHM) {
+		return nil
+	}
+	return client.codec
+}
+
+// compressedMetadata returns a copy of metadata for content compressed with
+// codec from uncompressedLength bytes (negative if unknown) with the MD5
+// uncompressedMd5 (empty if unknown). The caller's Content-Length and
+// Content-MD5 describe the uncompressed content, so they are dropped.
+func compressedMetadata(metadata *model.ObjectMetadata, codec Codec, uncompressedLength int64,
+	uncompressedMd5 string) *model.ObjectMetadata {
+
+	result := &model.ObjectMetadata{
+		Metadata: map[string]string{},
+	}
+	if metadata != nil {
+		for key, value := range metadata.Metadata {
+			if !strings.EqualFold(key, nosconst.CONTENT_LENGTH) && !strings.EqualFold(key, nosconst.CONTENT_MD5) {
+				result.Metadata[key] = value
+			}
+		}
+	}
+	result.Metadata[nosconst.X_NOS_META_COMPRESSION] = codec.Name()
+	if uncompressedLength >= 0 {
+		result.Metadata[nosconst.X_NOS_META_UNCOMPRESSED_LENGTH] = strconv.FormatInt(uncompressedLength, 10)
+	}
+	if uncompressedMd5 != "" {
+		result.Metadata[nosconst.X_NOS_META_UNCOMPRESSED_MD5] = uncompressedMd5
+	}
+	return result
+}
+
+// compressBody compresses body into a temporary file, so that memory use
+// does not grow with the content, and that the compressed content is sent
+// with its length and can be rewound for retries. It returns the file,
+// positioned at its start, which the caller removes with removeTempFile,
+// along with the length and the MD5 of body.
+func compressBody(codec Codec, body io.Reader) (file *os.File, n int64, sum string, err error) {
+	file, err = ioutil.TempFile("", "nos-compressed-")
+	if err != nil {
+		return nil, 0, "", err
+	}
+	defer func() {
+		if err != nil {
+			removeTempFile(file)
+		}
+	}()
+
+	writer, err := codec.NewWriter(file)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	bodyHash := md5.New()
+	n, err = io.Copy(writer, io.TeeReader(body, bodyHash))
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return nil, 0, "", err
+	}
+	return file, n, hashHex(bodyHash), nil
+}
+
+// removeTempFile closes and removes file.
+func removeTempFile(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
+
+// compressReader returns a reader of the content of reader compressed with
+// codec. Closing it stops the compression.
+func compressReader(codec Codec, reader io.Reader) io.ReadCloser {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		writer, err := codec.NewWriter(pipeWriter)
+		if err == nil {
+			_, err = io.Copy(writer, reader)
+			if closeErr := writer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		pipeWriter.CloseWithError(err)
+	}()
+	return pipeReader
+}
+
+// isCompressed reports whether metadata, read from an object, records a
+// codec. The size, ETag and ranges of such an object are those of its
+// compressed content.
+func isCompressed(metadata *model.ObjectMetadata) bool {
+	return metadata.Metadata[nosconst.X_NOS_META_COMPRESSION] != ""
+}
+
+// uncompressedLength returns the uncompressed length recorded in metadata,
+// or -1 if it was not recorded.
+func uncompressedLength(metadata *model.ObjectMetadata) int64 {
+	length, err := strconv.ParseInt(metadata.Metadata[nosconst.X_NOS_META_UNCOMPRESSED_LENGTH], 10, 64)
+	if err != nil || length < 0 {
+		return -1
+	}
+	return length
+}
+
+// decompressObject replaces the body of nosObject, a whole object, with its
+// decompressed content if its metadata records a codec. ContentLength
+// becomes the uncompressed length, or -1 if it was not recorded.
+func decompressObject(nosObject *model.NOSObject) error {
+	metadata := nosObject.ObjectMetadata
+	if !isCompressed(metadata) {
+		return nil
+	}
+	name := metadata.Metadata[nosconst.X_NOS_META_COMPRESSION]
+
+	bucket, object := nosObject.BucketName, nosObject.Key
+	codec, ok := lookupCodec(name)
+	if !ok {
+		return utils.ProcessClientError(noserror.ERROR_CODE_COMPRESSION_ERROR, bucket, object,
+			"unknown codec "+strconv.Quote(name))
+	}
+
+	body := &bodyReader{body: nosObject.Body}
+	reader, err := codec.NewReader(body)
+	if err != nil {
+		return utils.WrapClientError(noserror.ERROR_CODE_COMPRESSION_ERROR, bucket, object, err)
+	}
+	nosObject.Body = &decompressReader{
+		reader: reader,
+		body:   body,
+		bucket: bucket,
+		object: object,
+	}
+
+	metadata.ContentLength = uncompressedLength(metadata)
+	return nil
+}
+
+// bodyReader records the error of an object body, which the decompressor
+// passes on.
+type bodyReader struct {
+	body io.ReadCloser
+	err  error
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// decompressReader reads the decompressed content of an object body, and
+// closes both the decompressor and the body.
+type decompressReader struct {
+	reader io.ReadCloser
+	body   *bodyReader
+	bucket string
+	object string
+}
+
+func (r *decompressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	// Errors of the body itself, such as a checksum mismatch, are returned
+	// as they are.
+	if err != nil && err != io.EOF && err != r.body.err {
+		err = utils.WrapClientError(noserror.ERROR_CODE_COMPRESSION_ERROR, r.bucket, r.object, err)
+	}
+	return n, err
+}
+
+func (r *decompressReader) Close() error {
+	err := r.reader.Close()
+	if bodyErr := r.body.body.Close(); err == nil {
+		err = bodyErr
+	}
+	return err
+}
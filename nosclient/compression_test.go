@@ -0,0 +1,507 @@
+package nosclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+type CompressionTestSuite struct {
+	server    *nostest.Server
+	nosClient *NosClient
+
+	// gzipClient compresses what it uploads.
+	gzipClient *NosClient
+}
+
+var _ = Suite(&CompressionTestSuite{})
+
+func (s *CompressionTestSuite) SetUpTest(c *C) {
+	s.nosClient, s.server = newTestClient(c)
+	s.gzipClient = newServerClient(c, s.server, func(conf *config.Config) {
+		conf.Compression = nosconst.COMPRESSION_GZIP
+	})
+}
+
+func (s *CompressionTestSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *CompressionTestSuite) read(c *C, client *NosClient, object string) ([]byte, *model.ObjectMetadata, error) {
+	result, err := client.GetObject(&model.GetObjectRequest{Bucket: TEST_BUCKET, Object: object})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer result.Body.Close()
+	content, err := ioutil.ReadAll(result.Body)
+	return content, result.ObjectMetadata, err
+}
+
+func (s *CompressionTestSuite) stat(c *C, object string) *model.ObjectMetadata {
+	metadata, err := s.nosClient.GetObjectMetaData(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: object})
+	c.Assert(err, IsNil)
+	return metadata
+}
+
+// storedSize returns the size of the content stored for object, as listed.
+func (s *CompressionTestSuite) storedSize(c *C, object string) int64 {
+	result, err := s.nosClient.ListObjects(&model.ListObjectsRequest{Bucket: TEST_BUCKET, Prefix: object})
+	c.Assert(err, IsNil)
+	c.Assert(result.Contents, Not(HasLen), 0)
+	c.Assert(result.Contents[0].Key, Equals, object)
+	return result.Contents[0].Size
+}
+
+// compressibleContent returns size bytes that compress to about half of
+// their size, so that they still take several parts once compressed.
+func compressibleContent(size int) []byte {
+	return []byte(hex.EncodeToString(randomContent(size / 2)))[:size]
+}
+
+func (s *CompressionTestSuite) TestPutAndGet(c *C) {
+	content := bytes.Repeat([]byte(`{"level":"info","msg":"compressible"}`+"\n"), 1000)
+	_, err := s.gzipClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "compressed/log.json",
+		Body:   bytes.NewReader(content),
+		Metadata: &model.ObjectMetadata{
+			ContentLength: int64(len(content)),
+			Metadata: map[string]string{
+				nosconst.CONTENT_MD5:                        md5Hex(content),
+				nosconst.NOS_USER_METADATA_PREFIX + "Owner": "me",
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	size := s.storedSize(c, "compressed/log.json")
+	c.Assert(size < int64(len(content))/10, Equals, true, Commentf("%d bytes stored", size))
+	stored := s.stat(c, "compressed/log.json")
+	c.Assert(stored.ContentLength, Equals, int64(len(content)))
+	c.Assert(stored.Metadata[nosconst.X_NOS_META_COMPRESSION], Equals, nosconst.COMPRESSION_GZIP)
+	c.Assert(stored.Metadata[nosconst.X_NOS_META_UNCOMPRESSED_LENGTH], Equals, strconv.Itoa(len(content)))
+	c.Assert(stored.Metadata[nosconst.X_NOS_META_UNCOMPRESSED_MD5], Equals, md5Hex(content))
+	c.Assert(stored.Metadata[nosconst.NOS_USER_METADATA_PREFIX+"Owner"], Equals, "me")
+
+	// Any client decompresses whole objects.
+	for _, client := range []*NosClient{s.gzipClient, s.nosClient} {
+		read, metadata, err := s.read(c, client, "compressed/log.json")
+		c.Assert(err, IsNil)
+		c.Assert(read, DeepEquals, content)
+		c.Assert(metadata.ContentLength, Equals, int64(len(content)))
+	}
+
+	// Ranges of the compressed content are not ranges of the object.
+	_, err = s.gzipClient.GetObject(&model.GetObjectRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "compressed/log.json",
+		ObjRange: "bytes=0-1",
+	})
+	c.Assert(err, ErrorMatches, "StatusCode = 450, .*ranged reads of compressed objects are not supported.*")
+
+	// Content that is already encoded is stored as it is.
+	_, err = s.gzipClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "compressed/encoded",
+		Body:   bytes.NewReader([]byte("encoded")),
+		Metadata: &model.ObjectMetadata{
+			Metadata: map[string]string{nosconst.CONTENT_ENCODING: "br"},
+		},
+	})
+	c.Assert(err, IsNil)
+	read, _, err := s.read(c, s.nosClient, "compressed/encoded")
+	c.Assert(err, IsNil)
+	c.Assert(string(read), Equals, "encoded")
+	c.Assert(s.stat(c, "compressed/encoded").Metadata[nosconst.X_NOS_META_COMPRESSION], Equals, "")
+}
+
+func (s *CompressionTestSuite) TestIntegrity(c *C) {
+	client := newServerClient(c, s.server, func(conf *config.Config) {
+		conf.Compression = nosconst.COMPRESSION_GZIP
+		conf.VerifyIntegrity = true
+	})
+
+	content := bytes.Repeat([]byte("checked "), 1000)
+	putTestObject(c, client, TEST_BUCKET, "compressed/checked", content)
+
+	read, _, err := s.read(c, client, "compressed/checked")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+}
+
+func (s *CompressionTestSuite) TestPutStream(c *C) {
+	tempDir := c.MkDir()
+	defer os.Setenv("TMPDIR", os.Getenv("TMPDIR"))
+	os.Setenv("TMPDIR", tempDir)
+
+	// A stream is compressed into a temporary file, which is removed once it
+	// is sent, even if the request fails.
+	content := compressibleContent(3 * nosconst.MIN_FILESIZE)
+	_, err := s.gzipClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "compressed/stream",
+		Body:   pipeReader{bytes.NewReader(content)},
+	})
+	c.Assert(err, IsNil)
+	_, err = s.gzipClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: BUCKETNOTEXIST,
+		Object: "compressed/stream",
+		Body:   pipeReader{bytes.NewReader(content)},
+	})
+	c.Assert(err, ErrorMatches, ".*NoSuchBucket.*")
+	files, err := ioutil.ReadDir(tempDir)
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, 0)
+
+	read, _, err := s.read(c, s.nosClient, "compressed/stream")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+	c.Assert(s.storedSize(c, "compressed/stream") < int64(len(content)), Equals, true)
+}
+
+func (s *CompressionTestSuite) TestUpload(c *C) {
+	content := randomContent(2*nosconst.MIN_FILESIZE + 10)
+	uploader := NewUploader(s.gzipClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+	_, err := uploader.Upload(&model.UploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "compressed/upload",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+
+	stored := s.stat(c, "compressed/upload")
+	c.Assert(stored.Metadata[nosconst.X_NOS_META_COMPRESSION], Equals, nosconst.COMPRESSION_GZIP)
+
+	read, metadata, err := s.read(c, s.nosClient, "compressed/upload")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+	c.Assert(metadata.ContentLength, Equals, int64(-1))
+
+	// The uncompressed length and MD5 of a file are known in advance.
+	path := filepath.Join(c.MkDir(), "file")
+	c.Assert(ioutil.WriteFile(path, content, 0644), IsNil)
+	_, err = uploader.Upload(&model.UploadRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "compressed/file",
+		FilePath: path,
+	})
+	c.Assert(err, IsNil)
+	stored = s.stat(c, "compressed/file")
+	c.Assert(stored.ContentLength, Equals, int64(len(content)))
+	c.Assert(stored.Metadata[nosconst.X_NOS_META_UNCOMPRESSED_MD5], Equals, md5Hex(content))
+	read, _, err = s.read(c, s.nosClient, "compressed/file")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+
+	// Encrypted content is not compressed.
+	provider, err := NewLocalKeyProvider("key-1", bytes.Repeat([]byte{1}, 32))
+	c.Assert(err, IsNil)
+	encClient := NewEncryptionClient(s.gzipClient, provider)
+	_, err = encClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "compressed/encrypted",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.stat(c, "compressed/encrypted").Metadata[nosconst.X_NOS_META_COMPRESSION], Equals, "")
+}
+
+func (s *CompressionTestSuite) TestErrors(c *C) {
+	conf := s.server.Config()
+	conf.Compression = "lz4"
+	_, err := New(conf)
+	c.Assert(err, ErrorMatches, "StatusCode = 450, .*unknown codec \"lz4\".*")
+
+	put := func(object string, content []byte, codec string) {
+		_, err := s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+			Bucket: TEST_BUCKET,
+			Object: object,
+			Body:   bytes.NewReader(content),
+			Metadata: &model.ObjectMetadata{
+				Metadata: map[string]string{nosconst.X_NOS_META_COMPRESSION: codec},
+			},
+		})
+		c.Assert(err, IsNil)
+	}
+
+	put("compressed/lz4", []byte("lz4"), "lz4")
+	_, _, err = s.read(c, s.gzipClient, "compressed/lz4")
+	c.Assert(err, ErrorMatches, "StatusCode = 450, .*unknown codec \"lz4\".*")
+
+	put("compressed/corrupt", []byte("not gzip"), nosconst.COMPRESSION_GZIP)
+	_, _, err = s.read(c, s.gzipClient, "compressed/corrupt")
+	c.Assert(err, ErrorMatches, "StatusCode = 450, .*")
+
+	// A truncated stream fails when it is read.
+	compressed, err := ioutil.ReadAll(compressReader(gzipCodec{}, bytes.NewReader(randomContent(1000))))
+	c.Assert(err, IsNil)
+	put("compressed/truncated", compressed[:100], nosconst.COMPRESSION_GZIP)
+
+	result, err := s.nosClient.GetObject(&model.GetObjectRequest{Bucket: TEST_BUCKET, Object: "compressed/truncated"})
+	c.Assert(err, IsNil)
+	defer result.Body.Close()
+	_, err = ioutil.ReadAll(result.Body)
+	c.Assert(err, ErrorMatches, "StatusCode = 450, .*unexpected EOF.*")
+}
+
+func (s *CompressionTestSuite) TestCheckpointUpload(c *C) {
+	content := compressibleContent(8 * nosconst.MIN_FILESIZE)
+	path := filepath.Join(c.MkDir(), "file")
+	c.Assert(ioutil.WriteFile(path, content, 0644), IsNil)
+	checkpointPath := filepath.Join(c.MkDir(), "checkpoint")
+
+	// The first two compressed parts were uploaded before the upload was
+	// interrupted, and only the first one was recorded.
+	compressed, err := ioutil.ReadAll(compressReader(gzipCodec{}, bytes.NewReader(content)))
+	c.Assert(err, IsNil)
+	c.Assert(len(compressed) > 3*nosconst.MIN_FILESIZE, Equals, true, Commentf("%d bytes", len(compressed)))
+
+	initResult, err := s.nosClient.InitMultiUpload(&model.InitMultiUploadRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "compressed/resumed",
+		Metadata: compressedMetadata(nil, gzipCodec{}, int64(len(content)), md5Hex(content)),
+	})
+	c.Assert(err, IsNil)
+	file, err := os.Open(path)
+	c.Assert(err, IsNil)
+	fingerprint, err := getFileFingerprint(file)
+	file.Close()
+	c.Assert(err, IsNil)
+	checkpoint := &uploadCheckpoint{
+		Version:     checkpointVersion,
+		Bucket:      TEST_BUCKET,
+		Object:      "compressed/resumed",
+		UploadId:    initResult.UploadId,
+		PartSize:    nosconst.MIN_FILESIZE,
+		FilePath:    path,
+		FileSize:    fingerprint.size,
+		FileModTime: fingerprint.modTime,
+		FileMd5:     fingerprint.md5,
+		Codec:       nosconst.COMPRESSION_GZIP,
+	}
+	for number := 1; number <= 2; number++ {
+		result, err := s.nosClient.UploadPart(&model.UploadPartRequest{
+			Bucket:     TEST_BUCKET,
+			Object:     "compressed/resumed",
+			UploadId:   initResult.UploadId,
+			PartNumber: number,
+			Content:    compressed[(number-1)*nosconst.MIN_FILESIZE : number*nosconst.MIN_FILESIZE],
+			PartSize:   nosconst.MIN_FILESIZE,
+		})
+		c.Assert(err, IsNil)
+		if number == 1 {
+			checkpoint.Parts = append(checkpoint.Parts, checkpointPart{PartNumber: number, Etag: result.Etag})
+		}
+	}
+	c.Assert(checkpoint.save(checkpointPath), IsNil)
+
+	uploader := NewUploader(s.gzipClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+	_, err = uploader.Upload(&model.UploadRequest{
+		Bucket:         TEST_BUCKET,
+		Object:         "compressed/resumed",
+		FilePath:       path,
+		CheckpointFile: checkpointPath,
+	})
+	c.Assert(err, IsNil)
+
+	// The interrupted upload was completed rather than replaced.
+	uploads, err := s.nosClient.ListMultiUploads(&model.ListMultiUploadsRequest{Bucket: TEST_BUCKET})
+	c.Assert(err, IsNil)
+	c.Assert(uploads.Uploads, HasLen, 0)
+
+	c.Assert(s.storedSize(c, "compressed/resumed"), Equals, int64(len(compressed)))
+	stored := s.stat(c, "compressed/resumed")
+	c.Assert(stored.ContentLength, Equals, int64(len(content)))
+	read, _, err := s.read(c, s.nosClient, "compressed/resumed")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+}
+
+func (s *CompressionTestSuite) TestObjectWriter(c *C) {
+	write := func(object string, content []byte) {
+		w, err := s.gzipClient.NewObjectWriter(context.Background(), &model.ObjectWriterRequest{
+			Bucket:   TEST_BUCKET,
+			Object:   object,
+			PartSize: nosconst.MIN_FILESIZE,
+		})
+		c.Assert(err, IsNil)
+		for len(content) > 0 {
+			n := 1000
+			if n > len(content) {
+				n = len(content)
+			}
+			_, err := w.Write(content[:n])
+			c.Assert(err, IsNil)
+			content = content[n:]
+		}
+		c.Assert(w.Close(), IsNil)
+	}
+
+	// Content that is larger than a part once compressed is uploaded in
+	// compressed parts.
+	content := compressibleContent(4 * nosconst.MIN_FILESIZE)
+	write("compressed/parts", content)
+	stored := s.stat(c, "compressed/parts")
+	c.Assert(stored.Metadata[nosconst.X_NOS_META_COMPRESSION], Equals, nosconst.COMPRESSION_GZIP)
+	c.Assert(stored.Metadata[nosconst.ETAG], Matches, ".*-[0-9]+")
+	c.Assert(s.storedSize(c, "compressed/parts") < int64(len(content)), Equals, true)
+	read, _, err := s.read(c, s.nosClient, "compressed/parts")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+
+	// Content that fits in a part once compressed is sent with a single PUT.
+	content = bytes.Repeat([]byte("compressible "), 10000)
+	write("compressed/single", content)
+	stored = s.stat(c, "compressed/single")
+	c.Assert(stored.ContentLength, Equals, int64(len(content)))
+	c.Assert(stored.Metadata[nosconst.X_NOS_META_UNCOMPRESSED_MD5], Equals, md5Hex(content))
+	read, _, err = s.read(c, s.nosClient, "compressed/single")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+}
+
+func (s *CompressionTestSuite) TestDownload(c *C) {
+	content := compressibleContent(3 * nosconst.MIN_FILESIZE)
+	putTestObject(c, s.gzipClient, TEST_BUCKET, "compressed/download", content)
+
+	downloader := NewDownloader(s.nosClient)
+	downloader.PartSize = nosconst.MIN_FILESIZE
+	path := filepath.Join(c.MkDir(), "file")
+	c.Assert(ioutil.WriteFile(path, randomContent(10*nosconst.MIN_FILESIZE), 0644), IsNil)
+	metadata, err := downloader.Download(&model.DownloadRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "compressed/download",
+		FilePath: path,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(metadata.ContentLength, Equals, int64(len(content)))
+	read, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+
+	// The content is checked against the uncompressed MD5.
+	compressed, err := ioutil.ReadAll(compressReader(gzipCodec{}, bytes.NewReader(content)))
+	c.Assert(err, IsNil)
+	_, err = s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "compressed/mismatch",
+		Body:     bytes.NewReader(compressed),
+		Metadata: compressedMetadata(nil, gzipCodec{}, int64(len(content)), md5Hex([]byte("other"))),
+	})
+	c.Assert(err, IsNil)
+	_, err = downloader.Download(&model.DownloadRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "compressed/mismatch",
+		FilePath: path,
+	})
+	c.Assert(err, ErrorMatches, "StatusCode = 444, .*MD5 .* does not match .*")
+}
+
+func (s *CompressionTestSuite) TestObjectReader(c *C) {
+	content := compressibleContent(3*nosconst.MIN_FILESIZE + 10)
+	putTestObject(c, s.gzipClient, TEST_BUCKET, "compressed/reader", content)
+
+	reader, err := s.nosClient.NewObjectReader(context.Background(), &model.ObjectReaderRequest{
+		Bucket:    TEST_BUCKET,
+		Object:    "compressed/reader",
+		BlockSize: 1000,
+	})
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	c.Assert(reader.Size(), Equals, int64(len(content)))
+
+	read, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+
+	// Reading backwards starts over.
+	p := make([]byte, 1500)
+	_, err = reader.ReadAt(p, 2500)
+	c.Assert(err, IsNil)
+	c.Assert(p, DeepEquals, content[2500:4000])
+
+	_, err = reader.Seek(-10, io.SeekEnd)
+	c.Assert(err, IsNil)
+	read, err = ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content[len(content)-10:])
+
+	// The size of a streamed upload is unknown.
+	uploader := NewUploader(s.gzipClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+	_, err = uploader.Upload(&model.UploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "compressed/streamed",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+	_, err = s.nosClient.NewObjectReader(context.Background(), &model.ObjectReaderRequest{
+		Bucket: TEST_BUCKET,
+		Object: "compressed/streamed",
+	})
+	c.Assert(err, ErrorMatches, "StatusCode = 450, .*uncompressed length of the object was not recorded.*")
+}
+
+func (s *CompressionTestSuite) TestSync(c *C) {
+	dir := c.MkDir()
+	files := map[string][]byte{
+		"small.log": bytes.Repeat([]byte("small "), 1000),
+		"large.log": bytes.Repeat([]byte("large "), nosconst.DEFAULT_PARTSIZE/6+1),
+	}
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), content, 0644), IsNil)
+	}
+
+	request := &model.SyncRequest{
+		Bucket:   TEST_BUCKET,
+		Prefix:   "sync/",
+		LocalDir: dir,
+	}
+	syncer := NewSyncer(s.gzipClient)
+	result, err := syncer.Upload(request)
+	c.Assert(err, IsNil)
+	c.Assert(actions(result), Equals, "upload sync/large.log\nupload sync/small.log")
+	for name, content := range files {
+		c.Assert(s.storedSize(c, "sync/"+name) < int64(len(content))/10, Equals, true)
+	}
+
+	// The objects are compared with the files by their uncompressed content.
+	result, err = syncer.Upload(request)
+	c.Assert(err, IsNil)
+	c.Assert(result.Actions, HasLen, 0)
+	c.Assert(result.Unchanged, Equals, 2)
+
+	files["small.log"] = bytes.Repeat([]byte("SMALL "), 1000)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "small.log"), files["small.log"], 0644), IsNil)
+	result, err = syncer.Upload(request)
+	c.Assert(err, IsNil)
+	c.Assert(actions(result), Equals, "upload sync/small.log")
+
+	request.LocalDir = c.MkDir()
+	result, err = syncer.Download(request)
+	c.Assert(err, IsNil)
+	c.Assert(actions(result), Equals, "download sync/large.log\ndownload sync/small.log")
+	c.Assert(result.Actions[0].Size, Equals, int64(len(files["large.log"])))
+	for name, content := range files {
+		read, err := ioutil.ReadFile(filepath.Join(request.LocalDir, name))
+		c.Assert(err, IsNil)
+		c.Assert(read, DeepEquals, content)
+	}
+
+	result, err = syncer.Download(request)
+	c.Assert(err, IsNil)
+	c.Assert(result.Actions, HasLen, 0)
+	c.Assert(result.Unchanged, Equals, 2)
+}
@@ -0,0 +1,406 @@
+package nosclient
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Downloader downloads objects of any size, fetching several ranges of the
+// object at the same time. Compressed objects cannot be read by range, so
+// they are fetched and decompressed with a single GET, without a checkpoint.
+type Downloader struct {
+	// PartSize is the size of every range but the last.
+	PartSize int64
+
+	// Concurrency is the number of ranges fetched at the same time.
+	Concurrency int
+
+	client *NosClient
+}
+
+func NewDownloader(client *NosClient) *Downloader {
+	return &Downloader{
+		PartSize:    nosconst.DEFAULT_PARTSIZE,
+		Concurrency: nosconst.DEFAULT_CONCURRENCY,
+		client:      client,
+	}
+}
+
+// downloadCheckpoint records the ranges of an object already written to a
+// local file, so that an interrupted download can be resumed.
+type downloadCheckpoint struct {
+	Version      int
+	Bucket       string
+	Object       string
+	Etag         string
+	Size         int64
+	LastModified string
+	PartSize     int64
+	FilePath     string
+
+	Parts []int
+}
+
+// md5Etag matches the ETag of objects not uploaded in parts, which is the
+// MD5 of their content.
+var md5Etag = regexp.MustCompile("^[0-9a-fA-F]{32}$")
+
+func (downloader *Downloader) Download(downloadRequest *model.DownloadRequest) (*model.ObjectMetadata, error) {
+	return downloader.DownloadWithContext(context.Background(), downloadRequest)
+}
+
+// DownloadWithContext is like Download but carries ctx, which cancels the
+// download when it is done.
+//
+// The object is checked for changes while it is downloaded, and, unless it
+// was uploaded in parts, its content is checked against its ETag when the
+// destination can be read back.
+func (downloader *Downloader) DownloadWithContext(ctx context.Context, downloadRequest *model.DownloadRequest) (
+	*model.ObjectMetadata, error) {
+
+	if downloadRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	bucket := downloadRequest.Bucket
+	object := downloadRequest.Object
+
+	err := utils.VerifyParamsWithObject(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	if downloadRequest.FilePath == "" && downloadRequest.Writer == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, object, "")
+	}
+	if downloadRequest.CheckpointFile != "" && downloadRequest.FilePath == "" {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, object,
+			"CheckpointFile requires FilePath")
+	}
+
+	partSize := downloader.PartSize
+	if partSize <= 0 {
+		partSize = nosconst.DEFAULT_PARTSIZE
+	}
+
+	metadata, err := downloader.client.GetObjectMetaDataWithContext(ctx, &model.ObjectRequest{
+		Bucket: bucket,
+		Object: object,
+	})
+	if err != nil {
+		return nil, err
+	}
+	size := metadata.ContentLength
+	etag := metadata.Metadata[nosconst.ETAG]
+	if isCompressed(metadata) {
+		return downloader.downloadCompressed(ctx, downloadRequest, metadata)
+	}
+
+	writer := downloadRequest.Writer
+	done := make(map[int]bool)
+	var checkpoint *downloadCheckpoint
+	var onPart func(number int)
+	if downloadRequest.FilePath != "" {
+		if downloadRequest.CheckpointFile != "" {
+			checkpoint = &downloadCheckpoint{
+				Version:      checkpointVersion,
+				Bucket:       bucket,
+				Object:       object,
+				Etag:         etag,
+				Size:         size,
+				LastModified: metadata.Metadata[nosconst.LAST_MODIFIED],
+				PartSize:     partSize,
+				FilePath:     downloadRequest.FilePath,
+			}
+			for _, number := range downloader.loadCheckpoint(downloadRequest.CheckpointFile, checkpoint) {
+				done[number] = true
+			}
+
+			onPart = func(number int) {
+				checkpoint.Parts = append(checkpoint.Parts, number)
+				if err := saveCheckpoint(downloadRequest.CheckpointFile, checkpoint); err != nil {
+					downloader.client.Log.Warn("save checkpoint", downloadRequest.CheckpointFile, "failed:", err)
+				}
+			}
+		}
+
+		flags := os.O_CREATE | os.O_RDWR
+		if len(done) == 0 {
+			flags |= os.O_TRUNC
+		}
+		file, err := os.OpenFile(downloadRequest.FilePath, flags, 0644)
+		if err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, object, err)
+		}
+		defer file.Close()
+
+		if err := file.Truncate(size); err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, object, err)
+		}
+		writer = file
+	}
+
+	err = downloader.downloadParts(ctx, bucket, object, etag, size, partSize, writer, done, onPart)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyDownload(writer, bucket, object, etag, size)
+	if checkpoint != nil {
+		// A corrupted file cannot be resumed, so the checkpoint goes either way.
+		if err := os.Remove(downloadRequest.CheckpointFile); err != nil && !os.IsNotExist(err) {
+			downloader.client.Log.Warn("remove checkpoint", downloadRequest.CheckpointFile, "failed:", err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// downloadCompressed downloads the compressed object described by metadata
+// with a single GET, which decompresses it, and checks the content against
+// the uncompressed length and MD5, if they were recorded.
+func (downloader *Downloader) downloadCompressed(ctx context.Context, downloadRequest *model.DownloadRequest,
+	metadata *model.ObjectMetadata) (*model.ObjectMetadata, error) {
+
+	bucket := downloadRequest.Bucket
+	object := downloadRequest.Object
+
+	result, err := downloader.client.GetObjectWithContext(ctx, &model.GetObjectRequest{
+		Bucket: bucket,
+		Object: object,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer result.Body.Close()
+
+	if result.ObjectMetadata.Metadata[nosconst.ETAG] != metadata.Metadata[nosconst.ETAG] {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_CONTENT_MISMATCH, bucket, object,
+			"the object changed during the download")
+	}
+
+	writer := downloadRequest.Writer
+	if downloadRequest.FilePath != "" {
+		file, err := os.OpenFile(downloadRequest.FilePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, object, err)
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	size, err := io.Copy(&offsetWriter{writer: writer}, result.Body)
+	if err != nil {
+		return nil, readContentError(bucket, object, err)
+	}
+	if length := uncompressedLength(metadata); length >= 0 && size != length {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_CONTENT_MISMATCH, bucket, object,
+			fmt.Sprintf("got %d bytes, want %d", size, length))
+	}
+
+	err = verifyDownload(writer, bucket, object, metadata.Metadata[nosconst.X_NOS_META_UNCOMPRESSED_MD5], size)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata.ContentLength = size
+	return metadata, nil
+}
+
+// loadCheckpoint returns the completed parts recorded at path, if the
+// checkpoint there was written for the same object and destination as
+// checkpoint and the destination file is still in place.
+func (downloader *Downloader) loadCheckpoint(path string, checkpoint *downloadCheckpoint) []int {
+	saved := &downloadCheckpoint{}
+	if !loadCheckpoint(path, saved) {
+		return nil
+	}
+
+	if saved.Version != checkpoint.Version || saved.Bucket != checkpoint.Bucket ||
+		saved.Object != checkpoint.Object || saved.Etag != checkpoint.Etag || saved.Size != checkpoint.Size ||
+		saved.LastModified != checkpoint.LastModified || saved.PartSize != checkpoint.PartSize ||
+		saved.FilePath != checkpoint.FilePath {
+		return nil
+	}
+
+	fi, err := os.Stat(checkpoint.FilePath)
+	if err != nil || fi.Size() != checkpoint.Size {
+		return nil
+	}
+
+	checkpoint.Parts = saved.Parts
+	return saved.Parts
+}
+
+// downloadParts fetches the parts of the object not in done with a bounded
+// pool of workers. The first failure cancels the remaining work. If onPart
+// is not nil, it is called, one call at a time, for every part written.
+func (downloader *Downloader) downloadParts(ctx context.Context, bucket, object, etag string, size, partSize int64,
+	writer io.WriterAt, done map[int]bool, onPart func(int)) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := downloader.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	jobs := make(chan int)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				err := downloader.downloadPart(ctx, bucket, object, etag, size, partSize, number, writer)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					cancel()
+				} else if onPart != nil {
+					onPart(number)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	count := int((size + partSize - 1) / partSize)
+	for number := 1; number <= count && ctx.Err() == nil; number++ {
+		if done[number] {
+			continue
+		}
+		select {
+		case jobs <- number:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func (downloader *Downloader) downloadPart(ctx context.Context, bucket, object, etag string, size, partSize int64,
+	number int, writer io.WriterAt) error {
+
+	start := int64(number-1) * partSize
+	end := start + expectedPartSize(size, partSize, number) - 1
+
+	return downloader.client.getRange(ctx, bucket, object, "", etag, start, end, size,
+		&offsetWriter{writer: writer, offset: start})
+}
+
+// getRange copies the bytes from start to end, inclusive, of an object of
+// size bytes to writer, checking that the object still has the ETag etag.
+func (client *NosClient) getRange(ctx context.Context, bucket, object, versionId, etag string, start, end, size int64,
+	writer io.Writer) error {
+
+	result, err := client.GetObjectWithContext(ctx, &model.GetObjectRequest{
+		Bucket:    bucket,
+		Object:    object,
+		ObjRange:  fmt.Sprintf("bytes=%d-%d", start, end),
+		VersionId: versionId,
+	})
+	if err != nil {
+		return err
+	}
+	defer result.Body.Close()
+
+	if result.ObjectMetadata.Metadata[nosconst.ETAG] != etag {
+		return utils.ProcessClientError(noserror.ERROR_CODE_CONTENT_MISMATCH, bucket, object,
+			"the object changed during the download")
+	}
+
+	// A server ignoring the range sends the whole object with a 200.
+	contentRange := result.ObjectMetadata.Metadata[nosconst.CONTENT_RANGE]
+	if contentRange != fmt.Sprintf("bytes %d-%d/%d", start, end, size) &&
+		!(contentRange == "" && start == 0 && end == size-1) {
+		return utils.ProcessClientError(noserror.ERROR_CODE_CONTENT_MISMATCH, bucket, object,
+			"unexpected Content-Range "+contentRange)
+	}
+
+	_, err = io.CopyN(writer, result.Body, end-start+1)
+	if err != nil {
+		return utils.WrapClientError(noserror.ERROR_CODE_READCONTENT_ERROR, bucket, object, err)
+	}
+	return nil
+}
+
+// readContentError returns the error of reading an object body. Errors of
+// the decompression and of the integrity checks are returned as they are.
+func readContentError(bucket, object string, err error) error {
+	var clientError *noserror.ClientError
+	if errors.As(err, &clientError) {
+		return err
+	}
+	return utils.WrapClientError(noserror.ERROR_CODE_READCONTENT_ERROR, bucket, object, err)
+}
+
+// verifyDownload checks the downloaded content against etag, the object's
+// ETag or the MD5 of its uncompressed content, when etag is an MD5 and writer
+// can be read back.
+func verifyDownload(writer io.WriterAt, bucket, object, etag string, size int64) error {
+	reader, ok := writer.(io.ReaderAt)
+	if !ok || !md5Etag.MatchString(etag) {
+		return nil
+	}
+
+	hash := md5.New()
+	n, err := io.Copy(hash, io.NewSectionReader(reader, 0, size))
+	if err != nil {
+		return utils.WrapClientError(noserror.ERROR_CODE_READCONTENT_ERROR, bucket, object, err)
+	}
+	if n != size {
+		return utils.ProcessClientError(noserror.ERROR_CODE_CONTENT_MISMATCH, bucket, object,
+			fmt.Sprintf("got %d bytes, want %d", n, size))
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, etag) {
+		return utils.ProcessClientError(noserror.ERROR_CODE_CONTENT_MISMATCH, bucket, object,
+			"MD5 "+sum+" does not match "+etag)
+	}
+	return nil
+}
+
+// offsetWriter writes sequentially to an io.WriterAt from offset.
+type offsetWriter struct {
+	writer io.WriterAt
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
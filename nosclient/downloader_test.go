@@ -0,0 +1,173 @@
+package nosclient
+
+import (
+	"bytes"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type DownloaderTestSuite struct {
+	server    *nostest.Server
+	nosClient *NosClient
+}
+
+var _ = Suite(&DownloaderTestSuite{})
+
+func (s *DownloaderTestSuite) SetUpTest(c *C) {
+	s.nosClient, s.server = newTestClient(c)
+}
+
+func (s *DownloaderTestSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+// writerAt is an in-memory io.WriterAt that cannot be read back.
+type writerAt struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (w *writerAt) WriteAt(p []byte, offset int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if end := int(offset) + len(p); end > len(w.data) {
+		w.data = append(w.data, make([]byte, end-len(w.data))...)
+	}
+	return copy(w.data[offset:], p), nil
+}
+
+func (s *DownloaderTestSuite) TestDownloadFile(c *C) {
+	content := randomContent(5*nosconst.MIN_FILESIZE + 100)
+	putTestObject(c, s.nosClient, TEST_BUCKET, "downloader/file", content)
+
+	downloader := NewDownloader(s.nosClient)
+	downloader.PartSize = nosconst.MIN_FILESIZE
+
+	path := filepath.Join(c.MkDir(), "file")
+	metadata, err := downloader.Download(&model.DownloadRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "downloader/file",
+		FilePath: path,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(metadata.ContentLength, Equals, int64(len(content)))
+
+	downloaded, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(downloaded, DeepEquals, content)
+}
+
+func (s *DownloaderTestSuite) TestDownloadMultipartObject(c *C) {
+	content := randomContent(3*nosconst.MIN_FILESIZE + 1)
+	uploader := NewUploader(s.nosClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+	_, err := uploader.Upload(&model.UploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "downloader/multipart",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+
+	downloader := NewDownloader(s.nosClient)
+	downloader.PartSize = 10000
+	downloader.Concurrency = 3
+
+	writer := &writerAt{}
+	_, err = downloader.Download(&model.DownloadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "downloader/multipart",
+		Writer: writer,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(writer.data, DeepEquals, content)
+}
+
+func (s *DownloaderTestSuite) TestDownloadEmptyObject(c *C) {
+	putTestObject(c, s.nosClient, TEST_BUCKET, "downloader/empty", nil)
+
+	path := filepath.Join(c.MkDir(), "file")
+	c.Assert(ioutil.WriteFile(path, []byte("stale"), 0644), IsNil)
+
+	_, err := NewDownloader(s.nosClient).Download(&model.DownloadRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "downloader/empty",
+		FilePath: path,
+	})
+	c.Assert(err, IsNil)
+
+	downloaded, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(downloaded, HasLen, 0)
+}
+
+func (s *DownloaderTestSuite) TestDownloadMissingObject(c *C) {
+	_, err := NewDownloader(s.nosClient).Download(&model.DownloadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "downloader/missing",
+		Writer: &writerAt{},
+	})
+	c.Assert(err, NotNil)
+}
+
+func (s *DownloaderTestSuite) TestDownloadResume(c *C) {
+	content := randomContent(4 * nosconst.MIN_FILESIZE)
+	putTestObject(c, s.nosClient, TEST_BUCKET, "downloader/resumed", content)
+
+	downloader := NewDownloader(s.nosClient)
+	downloader.PartSize = nosconst.MIN_FILESIZE
+
+	metadata, err := s.nosClient.GetObjectMetaData(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: "downloader/resumed"})
+	c.Assert(err, IsNil)
+
+	// An interrupted download wrote parts 1 and 2, but part 1 was corrupted
+	// since; the resumed download must not fetch it again, and must notice.
+	path := filepath.Join(c.MkDir(), "file")
+	partial := make([]byte, len(content))
+	copy(partial[nosconst.MIN_FILESIZE:], content[nosconst.MIN_FILESIZE:2*nosconst.MIN_FILESIZE])
+	c.Assert(ioutil.WriteFile(path, partial, 0644), IsNil)
+
+	checkpointPath := filepath.Join(c.MkDir(), "checkpoint")
+	checkpoint := &downloadCheckpoint{
+		Version:      checkpointVersion,
+		Bucket:       TEST_BUCKET,
+		Object:       "downloader/resumed",
+		Etag:         metadata.Metadata[nosconst.ETAG],
+		Size:         int64(len(content)),
+		LastModified: metadata.Metadata[nosconst.LAST_MODIFIED],
+		PartSize:     nosconst.MIN_FILESIZE,
+		FilePath:     path,
+		Parts:        []int{1, 2},
+	}
+	c.Assert(saveCheckpoint(checkpointPath, checkpoint), IsNil)
+
+	request := &model.DownloadRequest{
+		Bucket:         TEST_BUCKET,
+		Object:         "downloader/resumed",
+		FilePath:       path,
+		CheckpointFile: checkpointPath,
+	}
+	_, err = downloader.Download(request)
+	c.Assert(err, ErrorMatches, ".*StatusCode = 444.*ContentMismatch.*")
+
+	downloaded, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(downloaded[2*nosconst.MIN_FILESIZE:], DeepEquals, content[2*nosconst.MIN_FILESIZE:])
+
+	// The checkpoint is gone, so the next download starts over.
+	_, err = os.Stat(checkpointPath)
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	_, err = downloader.Download(request)
+	c.Assert(err, IsNil)
+
+	downloaded, err = ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(downloaded, DeepEquals, content)
+}
@@ -0,0 +1,750 @@
+package nosclient
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// KeyProvider wraps the data keys that encrypt objects with master keys
+// kept outside NOS.
+type KeyProvider interface {
+	// WrapKey encrypts dataKey with the current master key. It returns the
+	// wrapped key and the id of the master key, which are stored with the
+	// object.
+	WrapKey(dataKey []byte) (wrappedKey []byte, keyId string, err error)
+
+	// UnwrapKey decrypts a key wrapped by WrapKey with the master key keyId.
+	UnwrapKey(wrappedKey []byte, keyId string) ([]byte, error)
+}
+
+// LocalKeyProvider wraps data keys with AES-GCM master keys held in memory.
+type LocalKeyProvider struct {
+	keyId string
+	keys  map[string]cipher.AEAD
+}
+
+// NewLocalKeyProvider returns a provider wrapping data keys with masterKey,
+// an AES key of 16, 24 or 32 bytes known as keyId.
+func NewLocalKeyProvider(keyId string, masterKey []byte) (*LocalKeyProvider, error) {
+	provider := &LocalKeyProvider{
+		keyId: keyId,
+		keys:  map[string]cipher.AEAD{},
+	}
+	if err := provider.AddKey(keyId, masterKey); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// AddKey adds a master key that unwraps the data keys of objects encrypted
+// before a key rotation. New data keys are still wrapped with the key given
+// to NewLocalKeyProvider.
+func (provider *LocalKeyProvider) AddKey(keyId string, masterKey []byte) error {
+	aead, err := newGcm(masterKey)
+	if err != nil {
+		return err
+	}
+	provider.keys[keyId] = aead
+	return nil
+}
+
+func (provider *LocalKeyProvider) WrapKey(dataKey []byte) ([]byte, string, error) {
+	aead := provider.keys[provider.keyId]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(provider.keyId)), provider.keyId, nil
+}
+
+func (provider *LocalKeyProvider) UnwrapKey(wrappedKey []byte, keyId string) ([]byte, error) {
+	aead, ok := provider.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown master key %q", keyId)
+	}
+	if len(wrappedKey) < aead.NonceSize() {
+		return nil, errors.New("wrapped key too short")
+	}
+	nonceSize := aead.NonceSize()
+	return aead.Open(nil, wrappedKey[:nonceSize], wrappedKey[nonceSize:], []byte(keyId))
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, utils.WrapClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, "", "", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, utils.WrapClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, "", "", err)
+	}
+	return aead, nil
+}
+
+// EncryptionClient encrypts objects before they leave the client and
+// decrypts them when they are read back.
+//
+// Every object is encrypted with its own AES-256 data key, wrapped by the
+// KeyProvider and stored, with the other encryption parameters, in the
+// object's user metadata. The content is split into chunks of ChunkSize
+// bytes, each sealed with AES-GCM under a nonce derived from its index, so
+// that a ranged read only fetches and decrypts the chunks it covers. The
+// last chunk is sealed as such, so a truncated object fails to decrypt.
+//
+// Objects without encryption metadata are read as they are.
+type EncryptionClient struct {
+	// ChunkSize is the size of the chunks encrypted separately, at most
+	// MAX_CHUNKSIZE. If zero, DEFAULT_CHUNKSIZE is used.
+	ChunkSize int64
+
+	// PartSize and Concurrency configure the multipart uploads of Upload,
+	// as for an Uploader.
+	PartSize    int64
+	Concurrency int
+
+	client   *NosClient
+	provider KeyProvider
+}
+
+func NewEncryptionClient(client *NosClient, provider KeyProvider) *EncryptionClient {
+	return &EncryptionClient{
+		ChunkSize:   nosconst.DEFAULT_CHUNKSIZE,
+		Concurrency: nosconst.DEFAULT_CONCURRENCY,
+		client:      client,
+		provider:    provider,
+	}
+}
+
+// objectCipher holds the encryption parameters of one object.
+type objectCipher struct {
+	aead      cipher.AEAD
+	nonce     []byte
+	chunkSize int64
+	resource  string
+}
+
+// newCipher generates the parameters of a new object, and returns them along
+// with the metadata that records them.
+func (client *EncryptionClient) newCipher(bucket, object string) (*objectCipher, map[string]string, error) {
+	chunkSize := client.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = nosconst.DEFAULT_CHUNKSIZE
+	} else if chunkSize > nosconst.MAX_CHUNKSIZE {
+		return nil, nil, utils.ProcessClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, bucket, object,
+			"ChunkSize is larger than MAX_CHUNKSIZE")
+	}
+
+	dataKey := make([]byte, 32)
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, utils.WrapClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, bucket, object, err)
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, utils.WrapClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, bucket, object, err)
+	}
+
+	wrappedKey, keyId, err := client.provider.WrapKey(dataKey)
+	if err != nil {
+		return nil, nil, utils.ProcessClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, bucket, object,
+			"wrap key: "+err.Error())
+	}
+	aead, err := newGcm(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := map[string]string{
+		nosconst.X_NOS_META_ENCRYPTION_ALGORITHM:  nosconst.ENCRYPTION_AES_GCM_CHUNKED,
+		nosconst.X_NOS_META_ENCRYPTION_KEY:        base64.StdEncoding.EncodeToString(wrappedKey),
+		nosconst.X_NOS_META_ENCRYPTION_KEY_ID:     keyId,
+		nosconst.X_NOS_META_ENCRYPTION_NONCE:      base64.StdEncoding.EncodeToString(nonce),
+		nosconst.X_NOS_META_ENCRYPTION_CHUNK_SIZE: strconv.FormatInt(chunkSize, 10),
+	}
+	return &objectCipher{aead: aead, nonce: nonce, chunkSize: chunkSize, resource: "/" + bucket + "/" + object},
+		headers, nil
+}
+
+// loadCipher returns the encryption parameters recorded in metadata, or nil
+// if the object is not encrypted.
+func (client *EncryptionClient) loadCipher(metadata *model.ObjectMetadata, bucket, object string) (
+	*objectCipher, error) {
+
+	header := func(key string) string {
+		for k, value := range metadata.Metadata {
+			if strings.EqualFold(k, key) {
+				return value
+			}
+		}
+		return ""
+	}
+
+	algorithm := header(nosconst.X_NOS_META_ENCRYPTION_ALGORITHM)
+	if algorithm == "" {
+		return nil, nil
+	}
+	if algorithm != nosconst.ENCRYPTION_AES_GCM_CHUNKED {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, bucket, object,
+			"unknown algorithm "+algorithm)
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(header(nosconst.X_NOS_META_ENCRYPTION_KEY))
+	if err != nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, bucket, object, "invalid key")
+	}
+	nonce, err := base64.StdEncoding.DecodeString(header(nosconst.X_NOS_META_ENCRYPTION_NONCE))
+	if err != nil || len(nonce) != 12 {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, bucket, object, "invalid nonce")
+	}
+	chunkSize, err := strconv.ParseInt(header(nosconst.X_NOS_META_ENCRYPTION_CHUNK_SIZE), 10, 64)
+	if err != nil || chunkSize <= 0 || chunkSize > nosconst.MAX_CHUNKSIZE {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, bucket, object,
+			"invalid chunk size")
+	}
+
+	dataKey, err := client.provider.UnwrapKey(wrappedKey, header(nosconst.X_NOS_META_ENCRYPTION_KEY_ID))
+	if err != nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, bucket, object,
+			"unwrap key: "+err.Error())
+	}
+	aead, err := newGcm(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &objectCipher{aead: aead, nonce: nonce, chunkSize: chunkSize, resource: "/" + bucket + "/" + object}, nil
+}
+
+// chunkNonce returns the nonce of the chunk at index: the object's nonce
+// with the index xored into its last 8 bytes.
+func (c *objectCipher) chunkNonce(index int64) []byte {
+	nonce := make([]byte, len(c.nonce))
+	copy(nonce, c.nonce)
+	tail := binary.BigEndian.Uint64(nonce[4:]) ^ uint64(index)
+	binary.BigEndian.PutUint64(nonce[4:], tail)
+	return nonce
+}
+
+// chunkAad marks the last chunk, so that an object cut at a chunk boundary
+// does not decrypt.
+func chunkAad(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// encryptedSize returns the size of size bytes once encrypted. Even empty
+// content has one chunk.
+func (c *objectCipher) encryptedSize(size int64) int64 {
+	chunks := (size + c.chunkSize - 1) / c.chunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return size + chunks*int64(c.aead.Overhead())
+}
+
+// decryptedSize returns the size of content encrypted into size bytes, and
+// the number of its chunks.
+func (c *objectCipher) decryptedSize(size int64) (int64, int64, error) {
+	overhead := int64(c.aead.Overhead())
+	chunks := (size + c.chunkSize + overhead - 1) / (c.chunkSize + overhead)
+	if chunks == 0 || size-chunks*overhead < 0 {
+		return 0, 0, noserror.NewClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, c.resource,
+			"invalid encrypted size "+strconv.FormatInt(size, 10))
+	}
+	return size - chunks*overhead, chunks, nil
+}
+
+// encryptReader encrypts source chunk by chunk.
+type encryptReader struct {
+	source  io.Reader
+	c       *objectCipher
+	index   int64
+	buffer  []byte
+	pending int
+	out     []byte
+	done    bool
+	err     error
+
+	// A seekable source is rewound to start when the reader is rewound.
+	seeker io.Seeker
+	start  int64
+	offset int64
+}
+
+func newEncryptReader(source io.Reader, c *objectCipher) (*encryptReader, error) {
+	r := &encryptReader{
+		source: source,
+		c:      c,
+		buffer: make([]byte, c.chunkSize+1),
+	}
+	if seeker, ok := source.(io.Seeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_READCONTENT_ERROR, "", "", err)
+		}
+		r.seeker = seeker
+		r.start = start
+	}
+	return r, nil
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.fill()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	r.offset += int64(n)
+	return n, nil
+}
+
+// fill encrypts the next chunk. One byte more than a chunk is read, to know
+// whether the chunk is the last one; it is kept for the next chunk.
+func (r *encryptReader) fill() {
+	n, err := io.ReadFull(r.source, r.buffer[r.pending:])
+	n += r.pending
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		r.err = utils.WrapClientError(noserror.ERROR_CODE_READCONTENT_ERROR, "", "", err)
+		return
+	}
+
+	last := int64(n) <= r.c.chunkSize
+	chunk := r.buffer[:n]
+	if !last {
+		chunk = r.buffer[:r.c.chunkSize]
+	}
+	r.out = r.c.aead.Seal(r.out[:0], r.c.chunkNonce(r.index), chunk, chunkAad(last))
+	r.index++
+
+	if last {
+		r.done = true
+	} else {
+		r.buffer[0] = r.buffer[r.c.chunkSize]
+		r.pending = 1
+	}
+}
+
+// Seek only tells the current offset, or rewinds a seekable source to where
+// it was when the reader was created, which is all a retried request needs.
+func (r *encryptReader) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekCurrent && offset == 0 {
+		return r.offset, nil
+	}
+	if whence == io.SeekStart && offset == 0 && r.seeker != nil {
+		if _, err := r.seeker.Seek(r.start, io.SeekStart); err != nil {
+			return 0, err
+		}
+		r.index, r.pending, r.out, r.done, r.err, r.offset = 0, 0, r.out[:0], false, nil, 0
+		return 0, nil
+	}
+	return 0, errors.New("an encrypted body can only be rewound to its start")
+}
+
+// decryptReader decrypts the chunks of body from the chunk at index, and
+// returns size bytes from skip bytes into the first one.
+type decryptReader struct {
+	body      io.ReadCloser
+	c         *objectCipher
+	index     int64
+	lastIndex int64
+	skip      int64
+	size      int64
+	buffer    []byte
+	out       []byte
+	started   bool
+	err       error
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		// Even empty content has a chunk to authenticate.
+		if r.size == 0 && r.started {
+			return 0, io.EOF
+		}
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.fill()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *decryptReader) fill() {
+	n, err := io.ReadFull(r.body, r.buffer)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		if r.index != r.lastIndex {
+			r.err = noserror.NewClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, r.c.resource, "truncated content")
+			return
+		}
+	} else if err != nil {
+		r.err = noserror.NewClientError(noserror.ERROR_CODE_READCONTENT_ERROR, r.c.resource, err.Error())
+		return
+	}
+
+	chunk, err := r.c.aead.Open(r.buffer[:0], r.c.chunkNonce(r.index), r.buffer[:n], chunkAad(r.index == r.lastIndex))
+	if err != nil {
+		r.err = noserror.NewClientError(noserror.ERROR_CODE_ENCRYPTION_ERROR, r.c.resource,
+			fmt.Sprintf("chunk %d: %v", r.index, err))
+		return
+	}
+	r.index++
+	r.started = true
+
+	if r.skip > int64(len(chunk)) {
+		r.skip = int64(len(chunk))
+	}
+	chunk = chunk[r.skip:]
+	r.skip = 0
+	if int64(len(chunk)) > r.size {
+		chunk = chunk[:r.size]
+	}
+	r.size -= int64(len(chunk))
+	r.out = chunk
+}
+
+func (r *decryptReader) Close() error {
+	return r.body.Close()
+}
+
+// encryptedMetadata returns a copy of metadata for the encrypted content:
+// it records the encryption parameters and drops Content-MD5, which only
+// applies to the plaintext.
+func encryptedMetadata(metadata *model.ObjectMetadata, headers map[string]string) *model.ObjectMetadata {
+	result := &model.ObjectMetadata{
+		Metadata: map[string]string{},
+	}
+	if metadata != nil {
+		for key, value := range metadata.Metadata {
+			if !strings.EqualFold(key, nosconst.CONTENT_MD5) {
+				result.Metadata[key] = value
+			}
+		}
+	}
+	for key, value := range headers {
+		result.Metadata[key] = value
+	}
+	return result
+}
+
+func (client *EncryptionClient) PutObjectByStream(putObjectRequest *model.PutObjectRequest) (*model.ObjectResult, error) {
+	return client.PutObjectByStreamWithContext(context.Background(), putObjectRequest)
+}
+
+// PutObjectByStreamWithContext is like PutObjectByStream but carries ctx, which cancels the
+// request when it is done.
+func (client *EncryptionClient) PutObjectByStreamWithContext(ctx context.Context,
+	putObjectRequest *model.PutObjectRequest) (*model.ObjectResult, error) {
+
+	if putObjectRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	bucket := putObjectRequest.Bucket
+	object := putObjectRequest.Object
+
+	err := utils.VerifyParamsWithObject(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	body := putObjectRequest.Body
+	if body == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, object, "")
+	}
+
+	start, err := body.Seek(0, io.SeekCurrent)
+	var end int64
+	if err == nil {
+		end, err = body.Seek(0, io.SeekEnd)
+	}
+	if err == nil {
+		_, err = body.Seek(start, io.SeekStart)
+	}
+	if err != nil {
+		return nil, utils.WrapClientError(noserror.ERROR_CODE_READCONTENT_ERROR, bucket, object, err)
+	}
+
+	c, headers, err := client.newCipher(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newEncryptReader(body, c)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := encryptedMetadata(putObjectRequest.Metadata, headers)
+	metadata.ContentLength = c.encryptedSize(end - start)
+	return client.client.PutObjectByStreamWithContext(ctx, &model.PutObjectRequest{
+		Bucket:   bucket,
+		Object:   object,
+		Body:     reader,
+		Metadata: metadata,
+		Acl:      putObjectRequest.Acl,
+	})
+}
+
+func (client *EncryptionClient) PutObjectByFile(putObjectRequest *model.PutObjectRequest) (*model.ObjectResult, error) {
+	return client.PutObjectByFileWithContext(context.Background(), putObjectRequest)
+}
+
+// PutObjectByFileWithContext is like PutObjectByFile but carries ctx, which cancels the
+// request when it is done.
+func (client *EncryptionClient) PutObjectByFileWithContext(ctx context.Context,
+	putObjectRequest *model.PutObjectRequest) (*model.ObjectResult, error) {
+
+	if putObjectRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	file, err := os.Open(putObjectRequest.FilePath)
+	if err != nil {
+		return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, "", "", err)
+	}
+	defer file.Close()
+
+	request := *putObjectRequest
+	request.Body = file
+	return client.PutObjectByStreamWithContext(ctx, &request)
+}
+
+func (client *EncryptionClient) Upload(uploadRequest *model.UploadRequest) (*model.CompleteMultiUploadResult, error) {
+	return client.UploadWithContext(context.Background(), uploadRequest)
+}
+
+// UploadWithContext is like Upload but carries ctx, which cancels the
+// upload when it is done. The content is encrypted as it is read, and
+// uploaded as a multipart upload; CheckpointFile is not supported, since
+// every upload is encrypted with a new key.
+func (client *EncryptionClient) UploadWithContext(ctx context.Context, uploadRequest *model.UploadRequest) (
+	*model.CompleteMultiUploadResult, error) {
+
+	if uploadRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	bucket := uploadRequest.Bucket
+	object := uploadRequest.Object
+
+	err := utils.VerifyParamsWithObject(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	if uploadRequest.CheckpointFile != "" {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, object,
+			"CheckpointFile is not supported with encryption")
+	}
+
+	reader := uploadRequest.Body
+	size := uploadRequest.Size
+	if uploadRequest.FilePath != "" {
+		file, err := os.Open(uploadRequest.FilePath)
+		if err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, object, err)
+		}
+		defer file.Close()
+
+		fi, err := file.Stat()
+		if err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, object, err)
+		}
+		reader = file
+		size = fi.Size()
+	}
+	if reader == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, object, "")
+	}
+
+	c, headers, err := client.newCipher(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := newEncryptReader(reader, c)
+	if err != nil {
+		return nil, err
+	}
+	if size > 0 {
+		size = c.encryptedSize(size)
+	}
+
+	uploader := NewUploader(client.client)
+	uploader.PartSize = client.PartSize
+	uploader.Concurrency = client.Concurrency
+	return uploader.UploadWithContext(ctx, &model.UploadRequest{
+		Bucket:   bucket,
+		Object:   object,
+		Body:     encrypted,
+		Size:     size,
+		Metadata: encryptedMetadata(uploadRequest.Metadata, headers),
+		Acl:      uploadRequest.Acl,
+	})
+}
+
+func (client *EncryptionClient) GetObject(getObjectRequest *model.GetObjectRequest) (*model.NOSObject, error) {
+	return client.GetObjectWithContext(context.Background(), getObjectRequest)
+}
+
+// GetObjectWithContext is like GetObject but carries ctx, which cancels the
+// request when it is done.
+//
+// The returned body is decrypted as it is read, and fails if the content
+// does not authenticate. ObjRange selects a range of the decrypted content,
+// as a single "bytes=first-last", "bytes=first-" or "bytes=-suffix" range.
+func (client *EncryptionClient) GetObjectWithContext(ctx context.Context, getObjectRequest *model.GetObjectRequest) (
+	*model.NOSObject, error) {
+
+	if getObjectRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	bucket := getObjectRequest.Bucket
+	object := getObjectRequest.Object
+
+	err := utils.VerifyParamsWithObject(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	if getObjectRequest.ObjRange == "" {
+		result, err := client.client.GetObjectWithContext(ctx, getObjectRequest)
+		if err != nil {
+			return nil, err
+		}
+		c, err := client.loadCipher(result.ObjectMetadata, bucket, object)
+		if err != nil || c == nil {
+			if err != nil {
+				result.Body.Close()
+			}
+			return result, err
+		}
+
+		size, chunks, err := c.decryptedSize(result.ObjectMetadata.ContentLength)
+		if err != nil {
+			result.Body.Close()
+			return nil, err
+		}
+		result.Body = c.newDecryptReader(result.Body, 0, chunks-1, 0, size)
+		result.ObjectMetadata.ContentLength = size
+		return result, nil
+	}
+
+	// The ciphertext range depends on the encryption parameters and the
+	// size of the object, so they are read first.
+	metadata, err := client.client.GetObjectMetaDataWithContext(ctx, &model.ObjectRequest{
+		Bucket:    bucket,
+		Object:    object,
+		VersionId: getObjectRequest.VersionId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c, err := client.loadCipher(metadata, bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return client.client.GetObjectWithContext(ctx, getObjectRequest)
+	}
+
+	encryptedSize := metadata.ContentLength
+	size, chunks, err := c.decryptedSize(encryptedSize)
+	if err != nil {
+		return nil, err
+	}
+	first, last, err := parseRange(getObjectRequest.ObjRange, size)
+	if err != nil {
+		return nil, utils.WrapClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, object, err)
+	}
+
+	chunkSize := c.chunkSize + int64(c.aead.Overhead())
+	firstChunk := first / c.chunkSize
+	lastChunk := last / c.chunkSize
+	end := (lastChunk + 1) * chunkSize
+	if end > encryptedSize {
+		end = encryptedSize
+	}
+
+	request := *getObjectRequest
+	request.ObjRange = fmt.Sprintf("bytes=%d-%d", firstChunk*chunkSize, end-1)
+	result, err := client.client.GetObjectWithContext(ctx, &request)
+	if err != nil {
+		return nil, err
+	}
+	if result.ObjectMetadata.Metadata[nosconst.ETAG] != metadata.Metadata[nosconst.ETAG] {
+		result.Body.Close()
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_CONTENT_MISMATCH, bucket, object,
+			"the object changed during the download")
+	}
+
+	result.Body = c.newDecryptReader(result.Body, firstChunk, chunks-1, first-firstChunk*c.chunkSize, last-first+1)
+	result.ObjectMetadata.ContentLength = last - first + 1
+	result.ObjectMetadata.Metadata[nosconst.CONTENT_RANGE] = fmt.Sprintf("bytes %d-%d/%d", first, last, size)
+	return result, nil
+}
+
+func (c *objectCipher) newDecryptReader(body io.ReadCloser, index, lastIndex, skip, size int64) *decryptReader {
+	return &decryptReader{
+		body:      body,
+		c:         c,
+		index:     index,
+		lastIndex: lastIndex,
+		skip:      skip,
+		size:      size,
+		buffer:    make([]byte, c.chunkSize+int64(c.aead.Overhead())),
+	}
+}
+
+// parseRange returns the first and last byte selected by a single HTTP byte
+// range in content of size bytes.
+func parseRange(objRange string, size int64) (int64, int64, error) {
+	spec := strings.TrimPrefix(objRange, "bytes=")
+	i := strings.Index(spec, "-")
+	if spec == objRange || i < 0 {
+		return 0, 0, errors.New("invalid range " + objRange)
+	}
+
+	var first, last int64
+	var err error
+	if i == 0 {
+		var suffix int64
+		suffix, err = strconv.ParseInt(spec[1:], 10, 64)
+		first, last = size-suffix, size-1
+		if first < 0 {
+			first = 0
+		}
+	} else {
+		first, err = strconv.ParseInt(spec[:i], 10, 64)
+		last = size - 1
+		if err == nil && spec[i+1:] != "" {
+			last, err = strconv.ParseInt(spec[i+1:], 10, 64)
+		}
+		if last > size-1 {
+			last = size - 1
+		}
+	}
+	if err != nil || first < 0 || first > last {
+		return 0, 0, errors.New("unsatisfiable range " + objRange)
+	}
+	return first, last, nil
+}
@@ -0,0 +1,240 @@
+package nosclient
+
+import (
+	"bytes"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type EncryptionTestSuite struct {
+	server    *nostest.Server
+	nosClient *NosClient
+	encClient *EncryptionClient
+}
+
+var _ = Suite(&EncryptionTestSuite{})
+
+func (s *EncryptionTestSuite) SetUpTest(c *C) {
+	s.nosClient, s.server = newTestClient(c)
+
+	provider, err := NewLocalKeyProvider("key-1", bytes.Repeat([]byte{1}, 32))
+	c.Assert(err, IsNil)
+	s.encClient = NewEncryptionClient(s.nosClient, provider)
+	s.encClient.ChunkSize = 1000
+}
+
+func (s *EncryptionTestSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *EncryptionTestSuite) read(c *C, client *EncryptionClient, object, objRange string) ([]byte, error) {
+	result, err := client.GetObject(&model.GetObjectRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   object,
+		ObjRange: objRange,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer result.Body.Close()
+	content, err := ioutil.ReadAll(result.Body)
+	if err == nil {
+		c.Assert(result.ObjectMetadata.ContentLength, Equals, int64(len(content)))
+	}
+	return content, err
+}
+
+func (s *EncryptionTestSuite) readRaw(c *C, object string) ([]byte, *model.ObjectMetadata) {
+	result, err := s.nosClient.GetObject(&model.GetObjectRequest{Bucket: TEST_BUCKET, Object: object})
+	c.Assert(err, IsNil)
+	defer result.Body.Close()
+	content, err := ioutil.ReadAll(result.Body)
+	c.Assert(err, IsNil)
+	return content, result.ObjectMetadata
+}
+
+func (s *EncryptionTestSuite) TestPutAndGet(c *C) {
+	for _, size := range []int{0, 10, 1000, 2500} {
+		content := randomContent(size)
+		_, err := s.encClient.PutObjectByStream(&model.PutObjectRequest{
+			Bucket: TEST_BUCKET,
+			Object: "encrypted/object",
+			Body:   bytes.NewReader(content),
+			Metadata: &model.ObjectMetadata{
+				Metadata: map[string]string{nosconst.NOS_USER_METADATA_PREFIX + "Owner": "me"},
+			},
+		})
+		c.Assert(err, IsNil)
+
+		read, err := s.read(c, s.encClient, "encrypted/object", "")
+		c.Assert(err, IsNil)
+		c.Assert(read, DeepEquals, content, Commentf("size %d", size))
+
+		raw, metadata := s.readRaw(c, "encrypted/object")
+		c.Assert(raw, Not(DeepEquals), content)
+		c.Assert(metadata.Metadata[nosconst.X_NOS_META_ENCRYPTION_ALGORITHM], Equals,
+			nosconst.ENCRYPTION_AES_GCM_CHUNKED)
+		c.Assert(metadata.Metadata[nosconst.X_NOS_META_ENCRYPTION_KEY_ID], Equals, "key-1")
+		c.Assert(metadata.Metadata[nosconst.NOS_USER_METADATA_PREFIX+"Owner"], Equals, "me")
+	}
+
+	path := filepath.Join(c.MkDir(), "file")
+	content := randomContent(3000)
+	c.Assert(ioutil.WriteFile(path, content, 0644), IsNil)
+	_, err := s.encClient.PutObjectByFile(&model.PutObjectRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "encrypted/file",
+		FilePath: path,
+	})
+	c.Assert(err, IsNil)
+	read, err := s.read(c, s.encClient, "encrypted/file", "")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+
+	// Objects that are not encrypted are read as they are.
+	putTestObject(c, s.nosClient, TEST_BUCKET, "plain", []byte("plain"))
+	read, err = s.read(c, s.encClient, "plain", "")
+	c.Assert(err, IsNil)
+	c.Assert(string(read), Equals, "plain")
+	read, err = s.read(c, s.encClient, "plain", "bytes=1-2")
+	c.Assert(err, IsNil)
+	c.Assert(string(read), Equals, "la")
+}
+
+func (s *EncryptionTestSuite) TestUploadAndRanges(c *C) {
+	content := randomContent(2*nosconst.MIN_FILESIZE + 123)
+	s.encClient.PartSize = nosconst.MIN_FILESIZE
+	result, err := s.encClient.Upload(&model.UploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "encrypted/upload",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Etag, Matches, ".*-3")
+
+	read, err := s.read(c, s.encClient, "encrypted/upload", "")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+
+	size := len(content)
+	for _, test := range []struct {
+		objRange    string
+		first, last int
+	}{
+		{"bytes=0-0", 0, 0},
+		{"bytes=999-1000", 999, 1000},
+		{"bytes=1500-4999", 1500, 4999},
+		{"bytes=30000-", 30000, size - 1},
+		{"bytes=-10", size - 10, size - 1},
+		{"bytes=100-99999999", 100, size - 1},
+	} {
+		read, err := s.read(c, s.encClient, "encrypted/upload", test.objRange)
+		c.Assert(err, IsNil, Commentf(test.objRange))
+		c.Assert(read, DeepEquals, content[test.first:test.last+1], Commentf(test.objRange))
+	}
+
+	_, err = s.read(c, s.encClient, "encrypted/upload", "bytes=99999999-")
+	c.Assert(err, ErrorMatches, ".*unsatisfiable range.*")
+
+	_, err = s.encClient.Upload(&model.UploadRequest{
+		Bucket:         TEST_BUCKET,
+		Object:         "encrypted/upload",
+		FilePath:       "file",
+		CheckpointFile: "checkpoint",
+	})
+	c.Assert(err, ErrorMatches, ".*CheckpointFile is not supported.*")
+}
+
+func (s *EncryptionTestSuite) TestTampering(c *C) {
+	content := randomContent(2500)
+	_, err := s.encClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "encrypted/object",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+	raw, metadata := s.readRaw(c, "encrypted/object")
+
+	encryption := map[string]string{}
+	for key, value := range metadata.Metadata {
+		if strings.HasPrefix(key, nosconst.NOS_USER_METADATA_PREFIX) {
+			encryption[key] = value
+		}
+	}
+	overwrite := func(data []byte) {
+		_, err := s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+			Bucket:   TEST_BUCKET,
+			Object:   "encrypted/object",
+			Body:     bytes.NewReader(data),
+			Metadata: &model.ObjectMetadata{Metadata: encryption},
+		})
+		c.Assert(err, IsNil)
+	}
+
+	modified := append([]byte(nil), raw...)
+	modified[1500] ^= 1
+	overwrite(modified)
+	_, err = s.read(c, s.encClient, "encrypted/object", "")
+	c.Assert(err, ErrorMatches, "StatusCode = 449, .*chunk 1.*")
+	read, err := s.read(c, s.encClient, "encrypted/object", "bytes=0-99")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content[:100])
+
+	// Dropping the last chunk leaves a last chunk that was not sealed as such.
+	overwrite(raw[:2*1016])
+	_, err = s.read(c, s.encClient, "encrypted/object", "")
+	c.Assert(err, ErrorMatches, "StatusCode = 449, .*chunk 1.*")
+
+	// A chunk size is not trusted to allocate more than MAX_CHUNKSIZE.
+	encryption[nosconst.X_NOS_META_ENCRYPTION_CHUNK_SIZE] = strconv.Itoa(nosconst.MAX_CHUNKSIZE + 1)
+	overwrite(raw)
+	_, err = s.read(c, s.encClient, "encrypted/object", "")
+	c.Assert(err, ErrorMatches, "StatusCode = 449, .*invalid chunk size.*")
+
+	s.encClient.ChunkSize = nosconst.MAX_CHUNKSIZE + 1
+	_, err = s.encClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "encrypted/object",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, ErrorMatches, "StatusCode = 449, .*ChunkSize is larger than MAX_CHUNKSIZE.*")
+}
+
+func (s *EncryptionTestSuite) TestKeyRotation(c *C) {
+	content := []byte("rotated")
+	_, err := s.encClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "encrypted/old",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+
+	provider, err := NewLocalKeyProvider("key-2", bytes.Repeat([]byte{2}, 32))
+	c.Assert(err, IsNil)
+	rotated := NewEncryptionClient(s.nosClient, provider)
+	_, err = s.read(c, rotated, "encrypted/old", "")
+	c.Assert(err, ErrorMatches, ".*unknown master key \"key-1\".*")
+
+	c.Assert(provider.AddKey("key-1", bytes.Repeat([]byte{1}, 32)), IsNil)
+	read, err := s.read(c, rotated, "encrypted/old", "")
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+
+	_, err = rotated.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "encrypted/new",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+	_, metadata := s.readRaw(c, "encrypted/new")
+	c.Assert(metadata.Metadata[nosconst.X_NOS_META_ENCRYPTION_KEY_ID], Equals, "key-2")
+
+	_, err = NewLocalKeyProvider("short", []byte("short"))
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,107 @@
+package nosclient
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"hash"
+	"io"
+	"strings"
+)
+
+// md5Hex returns the MD5 of data in hex, the form used by ETags and
+// Content-MD5.
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashHex returns the sum of hash in hex, or "" if hash is nil.
+func hashHex(hash hash.Hash) string {
+	if hash == nil {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// readerMd5 returns the MD5 of the rest of reader and rewinds it. It
+// returns false if reader cannot be rewound.
+func readerMd5(reader io.Reader) (string, bool) {
+	seeker, ok := reader.(io.ReadSeeker)
+	if !ok {
+		return "", false
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", false
+	}
+
+	hash := md5.New()
+	_, err = io.Copy(hash, seeker)
+	if _, seekErr := seeker.Seek(start, io.SeekStart); err != nil || seekErr != nil {
+		return "", false
+	}
+	return hashHex(hash), true
+}
+
+// hasHeader reports whether metadata sets the header key, in any case.
+func hasHeader(metadata *model.ObjectMetadata, key string) bool {
+	if metadata == nil {
+		return false
+	}
+	for k, value := range metadata.Metadata {
+		if strings.EqualFold(k, key) && value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// withHeader returns a copy of metadata that also sets the header key.
+func withHeader(metadata *model.ObjectMetadata, key, value string) *model.ObjectMetadata {
+	result := &model.ObjectMetadata{
+		Metadata: map[string]string{},
+	}
+	if metadata != nil {
+		result.ContentLength = metadata.ContentLength
+		for k, v := range metadata.Metadata {
+			result.Metadata[k] = v
+		}
+	}
+	result.Metadata[key] = value
+	return result
+}
+
+// checksumReader checks an object body against the object's ETag once the
+// body has been read to its end.
+type checksumReader struct {
+	body     io.ReadCloser
+	hash     hash.Hash
+	etag     string
+	resource string
+}
+
+func newChecksumReader(body io.ReadCloser, etag, bucket, object string) *checksumReader {
+	return &checksumReader{
+		body:     body,
+		hash:     md5.New(),
+		etag:     etag,
+		resource: "/" + bucket + "/" + object,
+	}
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if sum := hashHex(r.hash); !strings.EqualFold(sum, r.etag) {
+			return n, noserror.NewChecksumError(r.resource, r.etag, sum)
+		}
+	}
+	return n, err
+}
+
+func (r *checksumReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,227 @@
+package nosclient
+
+import (
+	"bytes"
+	"errors"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+type IntegrityTestSuite struct {
+	server    *nostest.Server
+	proxy     *httptest.Server
+	nosClient *NosClient
+
+	// The proxy records the headers of the requests, and corrupts request
+	// or response bodies and ETags when told to.
+	mu              sync.Mutex
+	headers         []http.Header
+	corruptRequest  bool
+	corruptResponse bool
+	corruptEtag     bool
+}
+
+var _ = Suite(&IntegrityTestSuite{})
+
+func (s *IntegrityTestSuite) SetUpTest(c *C) {
+	s.headers = nil
+	s.corruptRequest, s.corruptResponse, s.corruptEtag = false, false, false
+	s.proxy = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.nosClient, s.server = newTestClient(c, func(conf *config.Config) {
+		conf.Endpoint = s.proxy.URL
+		conf.VerifyIntegrity = true
+	})
+}
+
+func (s *IntegrityTestSuite) TearDownTest(c *C) {
+	s.proxy.Close()
+	s.server.Close()
+}
+
+func (s *IntegrityTestSuite) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.headers = append(s.headers, r.Header.Clone())
+	corruptRequest, corruptResponse, corruptEtag := s.corruptRequest, s.corruptResponse, s.corruptEtag
+	s.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if corruptRequest && r.Method == http.MethodPut && len(body) > 0 {
+		body[0] ^= 0xff
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	recorder := httptest.NewRecorder()
+	s.server.ServeHTTP(recorder, r)
+	content := recorder.Body.Bytes()
+	if corruptResponse && r.Method == http.MethodGet && len(content) > 0 {
+		content[0] ^= 0xff
+	}
+	for key, values := range recorder.Header() {
+		w.Header()[key] = values
+	}
+	if corruptEtag {
+		w.Header().Set(nosconst.ETAG, strings.Repeat("0", 32))
+	}
+	w.WriteHeader(recorder.Code)
+	w.Write(content)
+}
+
+func (s *IntegrityTestSuite) lastHeader(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.headers[len(s.headers)-1].Get(key)
+}
+
+// pipeReader is an io.ReadSeeker that cannot seek, like os.Stdin on a pipe.
+type pipeReader struct {
+	io.Reader
+}
+
+func (r pipeReader) Seek(offset int64, whence int) (int64, error) {
+	return 0, errors.New("illegal seek")
+}
+
+func (s *IntegrityTestSuite) TestPutObject(c *C) {
+	content := []byte("verified content")
+	putTestObject(c, s.nosClient, TEST_BUCKET, "integrity/object", content)
+	c.Assert(s.lastHeader(nosconst.CONTENT_MD5), Equals, md5Hex(content))
+
+	s.corruptRequest = true
+	_, err := s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "integrity/object",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, ErrorMatches, "(?s).*StatusCode = 400.*")
+	s.corruptRequest = false
+
+	// A body that cannot be rewound is checked against the returned ETag.
+	_, err = s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "integrity/piped",
+		Body:   pipeReader{bytes.NewReader(content)},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.lastHeader(nosconst.CONTENT_MD5), Equals, "")
+
+	s.corruptEtag = true
+	_, err = s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "integrity/piped",
+		Body:   pipeReader{bytes.NewReader(content)},
+	})
+	checksumError, ok := err.(*noserror.ChecksumError)
+	c.Assert(ok, Equals, true, Commentf("%v", err))
+	c.Assert(checksumError.Expected, Equals, md5Hex(content))
+	c.Assert(checksumError.Resource, Equals, "/"+TEST_BUCKET+"/integrity/piped")
+}
+
+func (s *IntegrityTestSuite) TestUpload(c *C) {
+	content := randomContent(2*nosconst.MIN_FILESIZE + 10)
+	uploader := NewUploader(s.nosClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+	uploader.Concurrency = 1
+	_, err := uploader.Upload(&model.UploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "integrity/upload",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+
+	// Init, three parts and complete.
+	c.Assert(s.headers, HasLen, 5)
+	for i, part := range [][]byte{content[:nosconst.MIN_FILESIZE],
+		content[nosconst.MIN_FILESIZE : 2*nosconst.MIN_FILESIZE], content[2*nosconst.MIN_FILESIZE:]} {
+		c.Assert(s.headers[1+i].Get(nosconst.CONTENT_MD5), Equals, md5Hex(part))
+	}
+	c.Assert(s.lastHeader(nosconst.X_NOS_OBJECT_MD5), Equals, md5Hex(content))
+
+	s.corruptRequest = true
+	_, err = uploader.Upload(&model.UploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "integrity/upload",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, ErrorMatches, "(?s).*StatusCode = 400.*")
+}
+
+func (s *IntegrityTestSuite) TestUploadPartSize(c *C) {
+	initResult, err := s.nosClient.InitMultiUpload(&model.InitMultiUploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "integrity/part",
+	})
+	c.Assert(err, IsNil)
+
+	// The part is the start of Content, and the whole of it if PartSize is
+	// larger.
+	content := []byte("ten bytes!")
+	for partNumber, partSize := range []int64{4, 11, -1} {
+		_, err := s.nosClient.UploadPart(&model.UploadPartRequest{
+			Bucket:     TEST_BUCKET,
+			Object:     "integrity/part",
+			UploadId:   initResult.UploadId,
+			PartNumber: partNumber + 1,
+			Content:    content,
+			PartSize:   partSize,
+		})
+		c.Assert(err, IsNil)
+	}
+	c.Assert(s.headers[1].Get(nosconst.CONTENT_MD5), Equals, md5Hex(content[:4]))
+	c.Assert(s.headers[2].Get(nosconst.CONTENT_MD5), Equals, md5Hex(content))
+	c.Assert(s.headers[3].Get(nosconst.CONTENT_MD5), Equals, md5Hex(nil))
+
+	result, err := s.nosClient.ListUploadParts(&model.ListUploadPartsRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "integrity/part",
+		UploadId: initResult.UploadId,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Parts, HasLen, 3)
+	c.Assert(result.Parts[0].Size, Equals, 4)
+	c.Assert(result.Parts[1].Size, Equals, len(content))
+	c.Assert(result.Parts[2].Size, Equals, 0)
+}
+
+func (s *IntegrityTestSuite) TestGetObject(c *C) {
+	content := []byte("downloaded content")
+	putTestObject(c, s.nosClient, TEST_BUCKET, "integrity/object", content)
+
+	read := func() ([]byte, error) {
+		result, err := s.nosClient.GetObject(&model.GetObjectRequest{Bucket: TEST_BUCKET, Object: "integrity/object"})
+		c.Assert(err, IsNil)
+		defer result.Body.Close()
+		return ioutil.ReadAll(result.Body)
+	}
+
+	downloaded, err := read()
+	c.Assert(err, IsNil)
+	c.Assert(downloaded, DeepEquals, content)
+
+	s.corruptResponse = true
+	_, err = read()
+	checksumError, ok := err.(*noserror.ChecksumError)
+	c.Assert(ok, Equals, true, Commentf("%v", err))
+	c.Assert(checksumError.Expected, Equals, md5Hex(content))
+	c.Assert(err, ErrorMatches, "StatusCode = 448, .*ChecksumMismatch.*")
+
+	// Ranges are not checked.
+	result, err := s.nosClient.GetObject(&model.GetObjectRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "integrity/object",
+		ObjRange: "bytes=0-3",
+	})
+	c.Assert(err, IsNil)
+	defer result.Body.Close()
+	_, err = ioutil.ReadAll(result.Body)
+	c.Assert(err, IsNil)
+}
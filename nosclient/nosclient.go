@@ -2,38 +2,57 @@ package nosclient
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
+	"crypto/tls"
 	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/auth"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/credentials"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/httpclient"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/logger"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"hash"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type NosClient struct {
-	endPoint  string
-	accessKey string
-	secretKey string
+	scheme      string
+	endPoint    string
+	credentials credentials.Provider
 
 	httpClient *http.Client
 	Log        logger.NosLog
 	isSubDomain bool
+
+	retry *config.RetryConfig
+
+	verifyIntegrity bool
+
+	// codec compresses uploaded content, if it is not nil.
+	codec Codec
 }
 
 func NewHttpClient(connectTimeout, requestTimeout, readWriteTimeout,
 	maxIdleConnection int) *http.Client {
+	return NewHttpClientWithTLS(connectTimeout, requestTimeout, readWriteTimeout, maxIdleConnection, nil)
+}
+
+// NewHttpClientWithTLS is like NewHttpClient but uses tlsConfig for https
+// connections. A nil tlsConfig uses the default configuration.
+func NewHttpClientWithTLS(connectTimeout, requestTimeout, readWriteTimeout,
+	maxIdleConnection int, tlsConfig *tls.Config) *http.Client {
 
 	tr := &httpclient.Transport{
 		ConnectTimeout:      time.Duration(connectTimeout) * time.Second,
@@ -41,6 +60,7 @@ func NewHttpClient(connectTimeout, requestTimeout, readWriteTimeout,
 		ReadWriteTimeout:    time.Duration(readWriteTimeout) * time.Second,
 		DisableKeepAlives:   false,
 		MaxIdleConnsPerHost: maxIdleConnection,
+		TLSClientConfig:     tlsConfig,
 	}
 
 	return &http.Client{Transport: tr}
@@ -56,15 +76,16 @@ func New(conf *config.Config) (*NosClient, error) {
 	}
 
 	client := &NosClient{
-		endPoint:  conf.Endpoint,
-		accessKey: conf.AccessKey,
-		secretKey: conf.SecretKey,
+		scheme:      conf.Scheme,
+		endPoint:    conf.Endpoint,
+		credentials: conf.Credentials,
 
-		httpClient: NewHttpClient(
+		httpClient: NewHttpClientWithTLS(
 			conf.NosServiceConnectTimeout,
 			conf.NosServiceReadWriteTimeout,
 			conf.NosServiceReadWriteTimeout,
-			conf.NosServiceMaxIdleConnection),
+			conf.NosServiceMaxIdleConnection,
+			conf.TLSClientConfig()),
 
 		Log: logger.NosLog{
 			LogLevel: conf.LogLevel,
@@ -72,23 +93,44 @@ func New(conf *config.Config) (*NosClient, error) {
 		},
 
 		isSubDomain: conf.GetIsSubDomain(),
+
+		retry: conf.Retry,
+
+		verifyIntegrity: conf.VerifyIntegrity,
+	}
+
+	// Without any credentials, requests are sent anonymously.
+	if client.credentials == nil && conf.AccessKey != "" && conf.SecretKey != "" {
+		client.credentials = credentials.NewStaticProvider(conf.AccessKey, conf.SecretKey)
+	}
+
+	if conf.Compression != "" {
+		codec, ok := lookupCodec(conf.Compression)
+		if !ok {
+			return nil, utils.ProcessClientError(noserror.ERROR_CODE_COMPRESSION_ERROR, "", "",
+				"unknown codec "+strconv.Quote(conf.Compression))
+		}
+		client.codec = codec
 	}
 
 	return client, nil
 }
 
-func (client *NosClient) getNosRequest(method, bucket, object string, metadata *model.ObjectMetadata,
-	body io.Reader, params map[string]string, bodyStyle string) (*http.Request, error) {
+// getNosUrl returns the request URL for bucket/object, the same URL without
+// its query string (used as the request's opaque URL), and the encoded object
+// name used for signing.
+func (client *NosClient) getNosUrl(bucket, object string, params map[string]string) (urlStr, opaque,
+	encodedObject string) {
 
-	var opaque string
-	var urlStr string
-	if client.isSubDomain {
-		urlStr = "http://" + bucket + "." + client.endPoint + "/"
+	if bucket == "" {
+		urlStr = client.scheme + "://" + client.endPoint + "/"
+	} else if client.isSubDomain {
+		urlStr = client.scheme + "://" + bucket + "." + client.endPoint + "/"
 	} else {
-		urlStr = "http://" + client.endPoint + "/" + bucket + "/"
+		urlStr = client.scheme + "://" + client.endPoint + "/" + bucket + "/"
 	}
 
-	encodedObject := utils.NosUrlEncode(object)
+	encodedObject = utils.NosUrlEncode(object)
 	urlStr += encodedObject
 	opaque = urlStr
 
@@ -101,7 +143,15 @@ func (client *NosClient) getNosRequest(method, bucket, object string, metadata *
 		urlStr += "?" + v.Encode()
 	}
 
-	request, err := http.NewRequest(method, urlStr, body)
+	return urlStr, opaque, encodedObject
+}
+
+func (client *NosClient) getNosRequest(ctx context.Context, method, bucket, object string, metadata *model.ObjectMetadata,
+	body io.Reader, params map[string]string, bodyStyle string) (*http.Request, error) {
+
+	urlStr, opaque, encodedObject := client.getNosUrl(bucket, object, params)
+
+	request, err := http.NewRequestWithContext(ctx, method, urlStr, body)
 	if err != nil {
 		return nil, err
 	}
@@ -122,9 +172,17 @@ func (client *NosClient) getNosRequest(method, bucket, object string, metadata *
 		}
 	}
 
-	if client.accessKey != "" && client.secretKey != "" {
-		request.Header.Set(nosconst.AUTHORIZATION,
-			auth.SignRequest(request, client.accessKey, client.secretKey, bucket, encodedObject))
+	if client.credentials != nil {
+		creds, err := client.credentials.Retrieve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if creds.Expired() {
+			return nil, utils.ProcessClientError(noserror.ERROR_CODE_CREDENTIALS_ERROR, bucket, object,
+				"credentials expired at "+creds.Expires.Format(time.RFC3339))
+		}
+		request.Header.Set(nosconst.AUTHORIZATION, auth.SignRequestWithToken(request,
+			creds.AccessKey, creds.SecretKey, creds.SecurityToken, bucket, encodedObject))
 	}
 
 	return request, nil
@@ -132,22 +190,19 @@ func (client *NosClient) getNosRequest(method, bucket, object string, metadata *
 
 func (client *NosClient) CreateBucket(bucketName string, location nosconst.Location,
 	acl nosconst.Acl) error {
-	var locationConstraint string
-	switch location {
-	case nosconst.HZ:
-		locationConstraint = "HZ"
-	default:
+	return client.CreateBucketWithContext(context.Background(), bucketName, location, acl)
+}
+
+// CreateBucketWithContext is like CreateBucket but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) CreateBucketWithContext(ctx context.Context, bucketName string, location nosconst.Location,
+	acl nosconst.Acl) error {
+	locationConstraint := location.String()
+	if locationConstraint == "" {
 		return errors.New("unsupported Location")
 	}
 
-	var aclString string
-
-	switch acl {
-	case nosconst.PUBLICREAD:
-		aclString = "public-read"
-	case nosconst.PRIVATE:
-		aclString = "private"
-	}
+	aclString := acl.String()
 
 	request := &model.CreateBucketRequest{
 		Location: locationConstraint,
@@ -164,10 +219,66 @@ func (client *NosClient) CreateBucket(bucketName string, location nosconst.Locat
 		},
 	}
 
-	req, err := client.getNosRequest("PUT", bucketName, "",
+	resp, err := client.doRequest(ctx, "PUT", bucketName, "",
 		metadata, bytes.NewReader(body), nil, nosconst.XML_TYPE)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	} else {
+		err := utils.ProcessServerError(resp, bucketName, "")
+		return err
+	}
+}
+
+// This operation lists all buckets owned by the account.
+func (client *NosClient) ListBuckets() (*model.ListBucketsResult, error) {
+	return client.ListBucketsWithContext(context.Background())
+}
+
+// ListBucketsWithContext is like ListBuckets but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) ListBucketsWithContext(ctx context.Context) (*model.ListBucketsResult, error) {
+	resp, err := client.doRequest(ctx, "GET", "", "", nil, nil, nil, nosconst.XML_TYPE)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusOK {
+		result := &model.ListBucketsResult{}
+		err = utils.ParseXmlBody(resp.Body, result)
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	} else {
+		err := utils.ProcessServerError(resp, "", "")
+		return nil, err
+	}
+}
+
+// HeadBucket returns nil if the bucket exists and is accessible, and the
+// server's error otherwise.
+func (client *NosClient) HeadBucket(bucketName string) error {
+	return client.HeadBucketWithContext(context.Background(), bucketName)
+}
+
+// HeadBucketWithContext is like HeadBucket but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) HeadBucketWithContext(ctx context.Context, bucketName string) error {
+	err := utils.VerifyParams(bucketName)
+	if err != nil {
+		return err
+	}
 
-	resp, err := client.httpClient.Do(req)
+	resp, err := client.doRequest(ctx, "HEAD", bucketName, "", nil, nil, nil, nosconst.XML_TYPE)
 	if err != nil {
 		return err
 	}
@@ -182,13 +293,107 @@ func (client *NosClient) CreateBucket(bucketName string, location nosconst.Locat
 	}
 }
 
+func (client *NosClient) DoesBucketExist(bucketName string) (bool, error) {
+	return client.DoesBucketExistWithContext(context.Background(), bucketName)
+}
+
+// DoesBucketExistWithContext is like DoesBucketExist but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) DoesBucketExistWithContext(ctx context.Context, bucketName string) (bool, error) {
+	err := client.HeadBucketWithContext(ctx, bucketName)
+	if err == nil {
+		return true, nil
+	}
+	if noserror.IsNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// This operation deletes a bucket, which must be empty.
+func (client *NosClient) DeleteBucket(bucketName string) error {
+	return client.DeleteBucketWithContext(context.Background(), bucketName)
+}
+
+// DeleteBucketWithContext is like DeleteBucket but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) DeleteBucketWithContext(ctx context.Context, bucketName string) error {
+	err := utils.VerifyParams(bucketName)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.doRequest(ctx, "DELETE", bucketName, "", nil, nil, nil, nosconst.XML_TYPE)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent {
+		return nil
+	} else {
+		err := utils.ProcessServerError(resp, bucketName, "")
+		return err
+	}
+}
+
+// GetBucketLocation returns the LocationConstraint of a bucket, which
+// nosconst.ParseLocation turns into a nosconst.Location.
+func (client *NosClient) GetBucketLocation(bucketName string) (*model.GetBucketLocationResult, error) {
+	return client.GetBucketLocationWithContext(context.Background(), bucketName)
+}
+
+// GetBucketLocationWithContext is like GetBucketLocation but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) GetBucketLocationWithContext(ctx context.Context, bucketName string) (
+	*model.GetBucketLocationResult, error) {
+
+	err := utils.VerifyParams(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		nosconst.LOCATION: "",
+	}
+
+	resp, err := client.doRequest(ctx, "GET", bucketName, "", nil, nil, params, nosconst.XML_TYPE)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusOK {
+		result := &model.GetBucketLocationResult{}
+		err = utils.ParseXmlBody(resp.Body, result)
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	} else {
+		err := utils.ProcessServerError(resp, bucketName, "")
+		return nil, err
+	}
+}
+
 func (client *NosClient) PutObjectByStream(putObjectRequest *model.PutObjectRequest) (*model.ObjectResult, error) {
+	return client.PutObjectByStreamWithContext(context.Background(), putObjectRequest)
+}
+
+// PutObjectByStreamWithContext is like PutObjectByStream but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) PutObjectByStreamWithContext(ctx context.Context, putObjectRequest *model.PutObjectRequest) (*model.ObjectResult, error) {
 	if putObjectRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
 	}
 
+	// The length of compressed content is checked once it is known.
+	codec := client.compressionCodec(putObjectRequest.Metadata)
 	var contentLength int64
-	if putObjectRequest.Metadata != nil {
+	if putObjectRequest.Metadata != nil && codec == nil {
 		contentLength = putObjectRequest.Metadata.ContentLength
 	}
 
@@ -197,13 +402,49 @@ func (client *NosClient) PutObjectByStream(putObjectRequest *model.PutObjectRequ
 		return nil, err
 	}
 
-	request, err := client.getNosRequest("PUT", putObjectRequest.Bucket, putObjectRequest.Object,
-		putObjectRequest.Metadata, putObjectRequest.Body, nil, nosconst.JSON_TYPE)
+	metadata, err := withAcl(putObjectRequest.Metadata, putObjectRequest.Acl,
+		putObjectRequest.Bucket, putObjectRequest.Object)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := client.httpClient.Do(request)
+	var body io.Reader = putObjectRequest.Body
+	if codec != nil && body != nil {
+		compressed, length, sum, err := compressBody(codec, body)
+		if err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_COMPRESSION_ERROR,
+				putObjectRequest.Bucket, putObjectRequest.Object, err)
+		}
+		defer removeTempFile(compressed)
+
+		stat, err := compressed.Stat()
+		if err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_COMPRESSION_ERROR,
+				putObjectRequest.Bucket, putObjectRequest.Object, err)
+		}
+		metadata = compressedMetadata(metadata, codec, length, sum)
+		metadata.ContentLength = stat.Size()
+		err = utils.VerifyParamsWithLength(putObjectRequest.Bucket, putObjectRequest.Object, metadata.ContentLength)
+		if err != nil {
+			return nil, err
+		}
+		body = compressed
+	}
+
+	var bodyHash hash.Hash
+	if client.verifyIntegrity && body != nil && !hasHeader(metadata, nosconst.CONTENT_MD5) {
+		if sum, ok := readerMd5(body); ok {
+			metadata = withHeader(metadata, nosconst.CONTENT_MD5, sum)
+		} else {
+			// The MD5 of a body that cannot be rewound, such as a pipe, is
+			// only known once it is sent, so it is checked against the ETag.
+			bodyHash = md5.New()
+			body = io.TeeReader(body, bodyHash)
+		}
+	}
+
+	resp, err := client.doRequest(ctx, "PUT", putObjectRequest.Bucket, putObjectRequest.Object,
+		metadata, body, nil, nosconst.JSON_TYPE)
 	if err != nil {
 		return nil, err
 	}
@@ -211,9 +452,13 @@ func (client *NosClient) PutObjectByStream(putObjectRequest *model.PutObjectRequ
 	client.Log.Debug("resp.StatusCode = ", resp.StatusCode)
 	if resp.StatusCode == http.StatusOK {
 		requestid, etag := utils.PopulateResponseHeader(resp)
+		if sum := hashHex(bodyHash); sum != "" && md5Etag.MatchString(etag) && !strings.EqualFold(sum, etag) {
+			return nil, noserror.NewChecksumError("/"+putObjectRequest.Bucket+"/"+putObjectRequest.Object, sum, etag)
+		}
 		objectResult := &model.ObjectResult{
 			Etag:      etag,
 			RequestId: requestid,
+			VersionId: resp.Header.Get(nosconst.NOS_VERSION_ID),
 		}
 
 		return objectResult, nil
@@ -224,13 +469,19 @@ func (client *NosClient) PutObjectByStream(putObjectRequest *model.PutObjectRequ
 }
 
 func (client *NosClient) PutObjectByFile(putObjectRequest *model.PutObjectRequest) (*model.ObjectResult, error) {
+	return client.PutObjectByFileWithContext(context.Background(), putObjectRequest)
+}
+
+// PutObjectByFileWithContext is like PutObjectByFile but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) PutObjectByFileWithContext(ctx context.Context, putObjectRequest *model.PutObjectRequest) (*model.ObjectResult, error) {
 	if putObjectRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
 	}
 
 	file, err := os.Open(putObjectRequest.FilePath)
 	if err != nil {
-		return nil, utils.ProcessClientError(noserror.ERROR_CODE_FILE_INVALID, "", "", err.Error())
+		return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, "", "", err)
 	}
 	defer file.Close()
 
@@ -243,16 +494,22 @@ func (client *NosClient) PutObjectByFile(putObjectRequest *model.PutObjectReques
 		if err == nil {
 			putObjectRequest.Metadata.ContentLength = fi.Size()
 		} else {
-			return nil, utils.ProcessClientError(noserror.ERROR_CODE_FILE_INVALID, "", "", err.Error())
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, "", "", err)
 		}
 	}
 
 	putObjectRequest.Body = file
 
-	return client.PutObjectByStream(putObjectRequest)
+	return client.PutObjectByStreamWithContext(ctx, putObjectRequest)
 }
 
 func (client *NosClient) CopyObject(copyObjectRequest *model.CopyObjectRequest) error {
+	return client.CopyObjectWithContext(context.Background(), copyObjectRequest)
+}
+
+// CopyObjectWithContext is like CopyObject but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) CopyObjectWithContext(ctx context.Context, copyObjectRequest *model.CopyObjectRequest) error {
 
 	if copyObjectRequest == nil {
 		return utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -280,12 +537,7 @@ func (client *NosClient) CopyObject(copyObjectRequest *model.CopyObjectRequest)
 		},
 	}
 
-	request, err := client.getNosRequest("PUT", destBucket, destObject, metadata, nil, nil, nosconst.JSON_TYPE)
-	if err != nil {
-		return err
-	}
-
-	resp, err := client.httpClient.Do(request)
+	resp, err := client.doRequest(ctx, "PUT", destBucket, destObject, metadata, nil, nil, nosconst.JSON_TYPE)
 	if err != nil {
 		return err
 	}
@@ -301,6 +553,12 @@ func (client *NosClient) CopyObject(copyObjectRequest *model.CopyObjectRequest)
 }
 
 func (client *NosClient) MoveObject(moveObjectRequest *model.MoveObjectRequest) error {
+	return client.MoveObjectWithContext(context.Background(), moveObjectRequest)
+}
+
+// MoveObjectWithContext is like MoveObject but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) MoveObjectWithContext(ctx context.Context, moveObjectRequest *model.MoveObjectRequest) error {
 
 	if moveObjectRequest == nil {
 		return utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -328,12 +586,7 @@ func (client *NosClient) MoveObject(moveObjectRequest *model.MoveObjectRequest)
 		},
 	}
 
-	request, err := client.getNosRequest("PUT", destBucket, destObject, metadata, nil, nil, nosconst.JSON_TYPE)
-	if err != nil {
-		return err
-	}
-
-	resp, err := client.httpClient.Do(request)
+	resp, err := client.doRequest(ctx, "PUT", destBucket, destObject, metadata, nil, nil, nosconst.JSON_TYPE)
 	if err != nil {
 		return err
 	}
@@ -349,6 +602,12 @@ func (client *NosClient) MoveObject(moveObjectRequest *model.MoveObjectRequest)
 }
 
 func (client *NosClient) DeleteObject(deleteObjectRequest *model.ObjectRequest) error {
+	return client.DeleteObjectWithContext(context.Background(), deleteObjectRequest)
+}
+
+// DeleteObjectWithContext is like DeleteObject but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) DeleteObjectWithContext(ctx context.Context, deleteObjectRequest *model.ObjectRequest) error {
 
 	if deleteObjectRequest == nil {
 		return utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -359,13 +618,8 @@ func (client *NosClient) DeleteObject(deleteObjectRequest *model.ObjectRequest)
 		return err
 	}
 
-	request, err := client.getNosRequest("DELETE", deleteObjectRequest.Bucket, deleteObjectRequest.Object,
-		nil, nil, nil, nosconst.JSON_TYPE)
-	if err != nil {
-		return err
-	}
-
-	resp, err := client.httpClient.Do(request)
+	resp, err := client.doRequest(ctx, "DELETE", deleteObjectRequest.Bucket, deleteObjectRequest.Object,
+		nil, nil, versionParams(deleteObjectRequest.VersionId), nosconst.JSON_TYPE)
 	if err != nil {
 		return err
 	}
@@ -382,6 +636,13 @@ func (client *NosClient) DeleteObject(deleteObjectRequest *model.ObjectRequest)
 
 func (client *NosClient) DeleteMultiObjects(deleteRequest *model.DeleteMultiObjectsRequest) (*model.DeleteObjectsResult,
 	error) {
+	return client.DeleteMultiObjectsWithContext(context.Background(), deleteRequest)
+}
+
+// DeleteMultiObjectsWithContext is like DeleteMultiObjects but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) DeleteMultiObjectsWithContext(ctx context.Context, deleteRequest *model.DeleteMultiObjectsRequest) (*model.DeleteObjectsResult,
+	error) {
 
 	if deleteRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -421,16 +682,11 @@ func (client *NosClient) DeleteMultiObjects(deleteRequest *model.DeleteMultiObje
 	params := map[string]string{
 		"delete": "",
 	}
-	request, err := client.getNosRequest("POST", deleteRequest.Bucket, "", metadata,
+	resp, err := client.doRequest(ctx, "POST", deleteRequest.Bucket, "", metadata,
 		bytes.NewReader(body), params, nosconst.XML_TYPE)
 	if err != nil {
 		return nil, err
 	}
-
-	resp, err := client.httpClient.Do(request)
-	if err != nil {
-		return nil, err
-	}
 	defer resp.Body.Close()
 	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
 
@@ -448,7 +704,66 @@ func (client *NosClient) DeleteMultiObjects(deleteRequest *model.DeleteMultiObje
 	}
 }
 
+// DeleteObjects deletes any number of objects of a bucket, MAX_FILENUMBER at
+// a time. It returns the keys deleted, which are all of keys unless it also
+// returns an error.
+func (client *NosClient) DeleteObjects(bucket string, keys []string) ([]string, error) {
+	return client.DeleteObjectsWithContext(context.Background(), bucket, keys)
+}
+
+// DeleteObjectsWithContext is like DeleteObjects but carries ctx, which cancels the
+// requests when it is done.
+func (client *NosClient) DeleteObjectsWithContext(ctx context.Context, bucket string, keys []string) ([]string,
+	error) {
+
+	var deleted []string
+	for start := 0; start < len(keys); start += nosconst.MAX_FILENUMBER {
+		end := start + nosconst.MAX_FILENUMBER
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+
+		deleteObjects := &model.DeleteMultiObjects{Quiet: true}
+		for _, key := range batch {
+			deleteObjects.Append(model.DeleteObject{Key: key})
+		}
+		result, err := client.DeleteMultiObjectsWithContext(ctx, &model.DeleteMultiObjectsRequest{
+			Bucket:        bucket,
+			DelectObjects: deleteObjects,
+		})
+		if err != nil {
+			return deleted, err
+		}
+
+		failed := make(map[string]bool)
+		for _, deleteError := range result.Error {
+			failed[deleteError.Key] = true
+		}
+		for _, key := range batch {
+			if !failed[key] {
+				deleted = append(deleted, key)
+			}
+		}
+		if len(result.Error) > 0 {
+			deleteError := result.Error[0]
+			return deleted, utils.ProcessClientError(noserror.ERROR_CODE_DELETEMULTIOBJECTS_ERROR, bucket,
+				deleteError.Key, deleteError.Code+": "+deleteError.Message)
+		}
+	}
+	return deleted, nil
+}
+
+// GetObject reads an object. If IfModifiedSince is set and the object has not
+// been modified since, the error matches noserror.ErrNotModified. Compressed
+// objects are decompressed, and cannot be read by range.
 func (client *NosClient) GetObject(getObjectRequest *model.GetObjectRequest) (*model.NOSObject, error) {
+	return client.GetObjectWithContext(context.Background(), getObjectRequest)
+}
+
+// GetObjectWithContext is like GetObject but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) GetObjectWithContext(ctx context.Context, getObjectRequest *model.GetObjectRequest) (*model.NOSObject, error) {
 
 	if getObjectRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -466,13 +781,8 @@ func (client *NosClient) GetObject(getObjectRequest *model.GetObjectRequest) (*m
 		},
 	}
 
-	request, err := client.getNosRequest("GET", getObjectRequest.Bucket, getObjectRequest.Object, metadata,
-		nil, nil, nosconst.JSON_TYPE)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := client.httpClient.Do(request)
+	resp, err := client.doRequest(ctx, "GET", getObjectRequest.Bucket, getObjectRequest.Object, metadata,
+		nil, versionParams(getObjectRequest.VersionId), nosconst.JSON_TYPE)
 	if err != nil {
 		return nil, err
 	}
@@ -486,9 +796,28 @@ func (client *NosClient) GetObject(getObjectRequest *model.GetObjectRequest) (*m
 			ObjectMetadata: utils.PopulateAllHeader(resp),
 			Body:           resp.Body,
 		}
+
+		// Only a whole object, not uploaded in parts, has its MD5 as ETag.
+		etag := nosObject.ObjectMetadata.Metadata[nosconst.ETAG]
+		if client.verifyIntegrity && resp.StatusCode == http.StatusOK && md5Etag.MatchString(etag) {
+			nosObject.Body = newChecksumReader(resp.Body, etag, getObjectRequest.Bucket, getObjectRequest.Object)
+		}
+		if resp.StatusCode == http.StatusOK {
+			if err := decompressObject(nosObject); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+		} else if isCompressed(nosObject.ObjectMetadata) {
+			// The range would be one of the compressed content.
+			resp.Body.Close()
+			return nil, utils.ProcessClientError(noserror.ERROR_CODE_COMPRESSION_ERROR, getObjectRequest.Bucket,
+				getObjectRequest.Object, "ranged reads of compressed objects are not supported")
+		}
 		return nosObject, nil
 	} else if resp.StatusCode == http.StatusNotModified {
-		return nil, nil
+		resp.Body.Close()
+		return nil, noserror.NewNotModifiedError(getObjectRequest.Bucket+"/"+getObjectRequest.Object,
+			resp.Header.Get(nosconst.X_NOS_REQUEST_ID))
 	} else {
 		err := utils.ProcessServerError(resp, getObjectRequest.Bucket, getObjectRequest.Object)
 		resp.Body.Close()
@@ -497,6 +826,12 @@ func (client *NosClient) GetObject(getObjectRequest *model.GetObjectRequest) (*m
 }
 
 func (client *NosClient) DoesObjectExist(objectRequest *model.ObjectRequest) (bool, error) {
+	return client.DoesObjectExistWithContext(context.Background(), objectRequest)
+}
+
+// DoesObjectExistWithContext is like DoesObjectExist but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) DoesObjectExistWithContext(ctx context.Context, objectRequest *model.ObjectRequest) (bool, error) {
 
 	if objectRequest == nil {
 		return false, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -507,13 +842,8 @@ func (client *NosClient) DoesObjectExist(objectRequest *model.ObjectRequest) (bo
 		return false, err
 	}
 
-	request, err := client.getNosRequest("HEAD", objectRequest.Bucket, objectRequest.Object, nil, nil,
-		nil, nosconst.JSON_TYPE)
-	if err != nil {
-		return false, err
-	}
-
-	resp, err := client.httpClient.Do(request)
+	resp, err := client.doRequest(ctx, "HEAD", objectRequest.Bucket, objectRequest.Object, nil, nil,
+		versionParams(objectRequest.VersionId), nosconst.JSON_TYPE)
 	if err != nil {
 		return false, err
 	}
@@ -530,7 +860,16 @@ func (client *NosClient) DoesObjectExist(objectRequest *model.ObjectRequest) (bo
 	}
 }
 
+// GetObjectMetaData reads the metadata of an object. The ContentLength of a
+// compressed object is its uncompressed length, as GetObject reads it, or -1
+// if that was not recorded.
 func (client *NosClient) GetObjectMetaData(objectRequest *model.ObjectRequest) (*model.ObjectMetadata, error) {
+	return client.GetObjectMetaDataWithContext(context.Background(), objectRequest)
+}
+
+// GetObjectMetaDataWithContext is like GetObjectMetaData but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) GetObjectMetaDataWithContext(ctx context.Context, objectRequest *model.ObjectRequest) (*model.ObjectMetadata, error) {
 
 	if objectRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -541,13 +880,8 @@ func (client *NosClient) GetObjectMetaData(objectRequest *model.ObjectRequest) (
 		return nil, err
 	}
 
-	request, err := client.getNosRequest("HEAD", objectRequest.Bucket, objectRequest.Object, nil,
-		nil, nil, nosconst.JSON_TYPE)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := client.httpClient.Do(request)
+	resp, err := client.doRequest(ctx, "HEAD", objectRequest.Bucket, objectRequest.Object, nil,
+		nil, versionParams(objectRequest.VersionId), nosconst.JSON_TYPE)
 	if err != nil {
 		return nil, err
 	}
@@ -555,7 +889,11 @@ func (client *NosClient) GetObjectMetaData(objectRequest *model.ObjectRequest) (
 	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
 
 	if resp.StatusCode == http.StatusOK {
-		return utils.PopulateAllHeader(resp), nil
+		metadata := utils.PopulateAllHeader(resp)
+		if isCompressed(metadata) {
+			metadata.ContentLength = uncompressedLength(metadata)
+		}
+		return metadata, nil
 	} else {
 		err := utils.ProcessServerError(resp, objectRequest.Bucket, objectRequest.Object)
 		return nil, err
@@ -563,6 +901,12 @@ func (client *NosClient) GetObjectMetaData(objectRequest *model.ObjectRequest) (
 }
 
 func (client *NosClient) ListObjects(listObjectsRequest *model.ListObjectsRequest) (*model.ListObjectsResult, error) {
+	return client.ListObjectsWithContext(context.Background(), listObjectsRequest)
+}
+
+// ListObjectsWithContext is like ListObjects but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) ListObjectsWithContext(ctx context.Context, listObjectsRequest *model.ListObjectsRequest) (*model.ListObjectsResult, error) {
 
 	if listObjectsRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -589,12 +933,7 @@ func (client *NosClient) ListObjects(listObjectsRequest *model.ListObjectsReques
 		nosconst.LIST_MAXKEYS:   strconv.Itoa(maxKeys),
 	}
 
-	request, err := client.getNosRequest("GET", bucket, "", nil, nil, params, nosconst.XML_TYPE)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := client.httpClient.Do(request)
+	resp, err := client.doRequest(ctx, "GET", bucket, "", nil, nil, params, nosconst.XML_TYPE)
 	if err != nil {
 		return nil, err
 	}
@@ -616,6 +955,12 @@ func (client *NosClient) ListObjects(listObjectsRequest *model.ListObjectsReques
 
 // multipart upload api
 func (client *NosClient) InitMultiUpload(initMultiUploadRequest *model.InitMultiUploadRequest) (*model.InitMultiUploadResult, error) {
+	return client.InitMultiUploadWithContext(context.Background(), initMultiUploadRequest)
+}
+
+// InitMultiUploadWithContext is like InitMultiUpload but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) InitMultiUploadWithContext(ctx context.Context, initMultiUploadRequest *model.InitMultiUploadRequest) (*model.InitMultiUploadResult, error) {
 
 	if initMultiUploadRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -623,23 +968,22 @@ func (client *NosClient) InitMultiUpload(initMultiUploadRequest *model.InitMulti
 
 	bucket := initMultiUploadRequest.Bucket
 	object := initMultiUploadRequest.Object
-	metadata := initMultiUploadRequest.Metadata
 
 	err := utils.VerifyParamsWithObject(bucket, object)
 	if err != nil {
 		return nil, err
 	}
 
-	params := map[string]string{
-		"uploads": "",
-	}
-
-	request, err := client.getNosRequest("POST", bucket, object, metadata, nil, params, nosconst.XML_TYPE)
+	metadata, err := withAcl(initMultiUploadRequest.Metadata, initMultiUploadRequest.Acl, bucket, object)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := client.httpClient.Do(request)
+	params := map[string]string{
+		"uploads": "",
+	}
+
+	resp, err := client.doRequest(ctx, "POST", bucket, object, metadata, nil, params, nosconst.XML_TYPE)
 	if err != nil {
 		return nil, err
 	}
@@ -660,6 +1004,12 @@ func (client *NosClient) InitMultiUpload(initMultiUploadRequest *model.InitMulti
 }
 
 func (client *NosClient) UploadPart(uploadPartRequest *model.UploadPartRequest) (*model.ObjectResult, error) {
+	return client.UploadPartWithContext(context.Background(), uploadPartRequest)
+}
+
+// UploadPartWithContext is like UploadPart but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) UploadPartWithContext(ctx context.Context, uploadPartRequest *model.UploadPartRequest) (*model.ObjectResult, error) {
 
 	if uploadPartRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -677,6 +1027,16 @@ func (client *NosClient) UploadPart(uploadPartRequest *model.UploadPartRequest)
 	if err != nil {
 		return nil, err
 	}
+	// At most PartSize bytes of Content are sent, and none if it is negative.
+	if partSize < 0 {
+		partSize = 0
+	} else if partSize > int64(len(content)) {
+		partSize = int64(len(content))
+	}
+	if contentMd5 == "" && client.verifyIntegrity {
+		contentMd5 = md5Hex(content[:partSize])
+	}
+
 	metadata := &model.ObjectMetadata{}
 	metadata.Metadata = make(map[string]string)
 	if contentMd5 != "" {
@@ -687,20 +1047,12 @@ func (client *NosClient) UploadPart(uploadPartRequest *model.UploadPartRequest)
 		nosconst.UPLOADID:   uploadId,
 		nosconst.PARTNUMBER: strconv.FormatInt(int64(partNumber), 10),
 	}
-	limitReader := &io.LimitedReader{
-		R: bytes.NewReader(content),
-		N: partSize,
-	}
-	request, err := client.getNosRequest("PUT", bucket, object, metadata, limitReader,
+	partReader := io.NewSectionReader(bytes.NewReader(content), 0, partSize)
+	resp, err := client.doRequest(ctx, "PUT", bucket, object, metadata, partReader,
 		params, nosconst.JSON_TYPE)
 	if err != nil {
 		return nil, err
 	}
-
-	resp, err := client.httpClient.Do(request)
-	if err != nil {
-		return nil, err
-	}
 	defer resp.Body.Close()
 	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
 
@@ -719,6 +1071,13 @@ func (client *NosClient) UploadPart(uploadPartRequest *model.UploadPartRequest)
 
 func (client *NosClient) CompleteMultiUpload(completeMultiUploadRequest *model.CompleteMultiUploadRequest) (
 	*model.CompleteMultiUploadResult, error) {
+	return client.CompleteMultiUploadWithContext(context.Background(), completeMultiUploadRequest)
+}
+
+// CompleteMultiUploadWithContext is like CompleteMultiUpload but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) CompleteMultiUploadWithContext(ctx context.Context, completeMultiUploadRequest *model.CompleteMultiUploadRequest) (
+	*model.CompleteMultiUploadResult, error) {
 
 	if completeMultiUploadRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -755,16 +1114,11 @@ func (client *NosClient) CompleteMultiUpload(completeMultiUploadRequest *model.C
 		return nil, err
 	}
 
-	request, err := client.getNosRequest("POST", bucket, object, metadata, bytes.NewReader(body),
+	resp, err := client.doRequest(ctx, "POST", bucket, object, metadata, bytes.NewReader(body),
 		params, nosconst.XML_TYPE)
 	if err != nil {
 		return nil, err
 	}
-
-	resp, err := client.httpClient.Do(request)
-	if err != nil {
-		return nil, err
-	}
 	defer resp.Body.Close()
 	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
 
@@ -783,6 +1137,12 @@ func (client *NosClient) CompleteMultiUpload(completeMultiUploadRequest *model.C
 }
 
 func (client *NosClient) AbortMultiUpload(abortMultiUploadRequest *model.AbortMultiUploadRequest) error {
+	return client.AbortMultiUploadWithContext(context.Background(), abortMultiUploadRequest)
+}
+
+// AbortMultiUploadWithContext is like AbortMultiUpload but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) AbortMultiUploadWithContext(ctx context.Context, abortMultiUploadRequest *model.AbortMultiUploadRequest) error {
 
 	if abortMultiUploadRequest == nil {
 		return utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -801,12 +1161,7 @@ func (client *NosClient) AbortMultiUpload(abortMultiUploadRequest *model.AbortMu
 		nosconst.UPLOADID: uploadId,
 	}
 
-	request, err := client.getNosRequest("DELETE", bucket, object, nil, nil, params, nosconst.JSON_TYPE)
-	if err != nil {
-		return err
-	}
-
-	resp, err := client.httpClient.Do(request)
+	resp, err := client.doRequest(ctx, "DELETE", bucket, object, nil, nil, params, nosconst.JSON_TYPE)
 	if err != nil {
 		return err
 	}
@@ -822,6 +1177,12 @@ func (client *NosClient) AbortMultiUpload(abortMultiUploadRequest *model.AbortMu
 }
 
 func (client *NosClient) ListUploadParts(listUploadPartsRequest *model.ListUploadPartsRequest) (*model.ListPartsResult, error) {
+	return client.ListUploadPartsWithContext(context.Background(), listUploadPartsRequest)
+}
+
+// ListUploadPartsWithContext is like ListUploadParts but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) ListUploadPartsWithContext(ctx context.Context, listUploadPartsRequest *model.ListUploadPartsRequest) (*model.ListPartsResult, error) {
 
 	if listUploadPartsRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -844,12 +1205,7 @@ func (client *NosClient) ListUploadParts(listUploadPartsRequest *model.ListUploa
 		nosconst.PART_NUMBER_MARKER: strconv.Itoa(partNumberMarker),
 	}
 
-	request, err := client.getNosRequest("GET", bucket, object, nil, nil, params, nosconst.XML_TYPE)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := client.httpClient.Do(request)
+	resp, err := client.doRequest(ctx, "GET", bucket, object, nil, nil, params, nosconst.XML_TYPE)
 	if err != nil {
 		return nil, err
 	}
@@ -873,6 +1229,13 @@ func (client *NosClient) ListUploadParts(listUploadPartsRequest *model.ListUploa
 // This operation lists in-progress multipart uploads.
 func (client *NosClient) ListMultiUploads(listMultiUploadsRequest *model.ListMultiUploadsRequest) (
 	*model.ListMultiUploadsResult, error) {
+	return client.ListMultiUploadsWithContext(context.Background(), listMultiUploadsRequest)
+}
+
+// ListMultiUploadsWithContext is like ListMultiUploads but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) ListMultiUploadsWithContext(ctx context.Context, listMultiUploadsRequest *model.ListMultiUploadsRequest) (
+	*model.ListMultiUploadsResult, error) {
 
 	if listMultiUploadsRequest == nil {
 		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
@@ -889,17 +1252,13 @@ func (client *NosClient) ListMultiUploads(listMultiUploadsRequest *model.ListMul
 	}
 
 	params := map[string]string{
-		nosconst.UPLOADS:          "",
-		nosconst.LIST_KEY_MARKER:  listMultiUploadsRequest.KeyMarker,
-		nosconst.LIST_MAX_UPLOADS: strconv.Itoa(listMultiUploadsRequest.MaxUploads),
-	}
-
-	request, err := client.getNosRequest("GET", bucket, "", nil, nil, params, nosconst.XML_TYPE)
-	if err != nil {
-		return nil, err
+		nosconst.UPLOADS:              "",
+		nosconst.LIST_KEY_MARKER:      listMultiUploadsRequest.KeyMarker,
+		nosconst.LIST_UPLOADID_MARKER: listMultiUploadsRequest.UploadIdMarker,
+		nosconst.LIST_MAX_UPLOADS:     strconv.Itoa(listMultiUploadsRequest.MaxUploads),
 	}
 
-	resp, err := client.httpClient.Do(request)
+	resp, err := client.doRequest(ctx, "GET", bucket, "", nil, nil, params, nosconst.XML_TYPE)
 	if err != nil {
 		return nil, err
 	}
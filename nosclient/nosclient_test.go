@@ -1,13 +1,27 @@
 package nosclient
 
 import (
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/credentials"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/logger"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
 	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -16,26 +30,27 @@ func Test(t *testing.T) { TestingT(t) }
 
 type NosClientTestSuite struct {
 	nosClient *NosClient
+	server    *nostest.Server
 }
 
 func (s *NosClientTestSuite) SetUpSuite(c *C) {
+	s.server = nostest.NewServer()
+	s.server.CreateBucket(TEST_BUCKET)
 
-	conf := &config.Config{
-		Endpoint:  "nos-eastchina1.126.net",
-		AccessKey: "your accesskey",
-		SecretKey: "your secretkey",
-
-		NosServiceConnectTimeout:    3,
-		NosServiceReadWriteTimeout:  60,
-		NosServiceMaxIdleConnection: 100,
-
-		LogLevel: logger.LogLevel(logger.DEBUG),
-		Logger:   logger.NewDefaultLogger(),
-	}
+	conf := s.server.Config()
+	conf.NosServiceConnectTimeout = 3
+	conf.NosServiceReadWriteTimeout = 60
+	conf.NosServiceMaxIdleConnection = 100
+	conf.LogLevel = logger.LogLevel(logger.DEBUG)
+	conf.Logger = logger.NewDefaultLogger()
 
 	s.nosClient, _ = New(conf)
 }
 
+func (s *NosClientTestSuite) TearDownSuite(c *C) {
+	s.server.Close()
+}
+
 var _ = Suite(&NosClientTestSuite{})
 
 const (
@@ -44,17 +59,86 @@ const (
 	BIGFILEOBJECT  = "bigfile.mp4"
 	OBJECTMD5      = "2b04df3ecc1d94afddff082d139c6f15"
 	ERROBJECTMD5   = "029ed5acc233ba3403e1a87ca54d1839"
-	SRCMD5         = "ba92b109c212245a8b8d4f1f5e46f0b6"
 	SPECIALOBJECT  = "特殊   字符`-=[]\\;',./ ~!@#$%^&*()_+{}|:\"<>?"
 	SPECIALBUCKET  = "ab._a"
 	BUCKETNOTEXIST = "1a2b3c4dexist"
+
+	// SRCFILESIZE is the size of the file written by writeSrcFile, which is
+	// uploaded in seven parts of 1024000 bytes at most.
+	SRCFILESIZE = 6390720
 )
 
+// writeSrcFile writes SRCFILESIZE bytes of random content to a temporary
+// file, and returns its path and the MD5 of its content.
+func writeSrcFile(c *C) (path, contentMd5 string) {
+	file, err := ioutil.TempFile(c.MkDir(), "testserver")
+	c.Assert(err, IsNil)
+	defer file.Close()
+
+	content := randomContent(SRCFILESIZE)
+	_, err = file.Write(content)
+	c.Assert(err, IsNil)
+	sum := md5.Sum(content)
+	return file.Name(), hex.EncodeToString(sum[:])
+}
+
 func (s *NosClientTestSuite) TestCreateBucket(c *C) {
 	err := s.nosClient.CreateBucket("sjltestbucket-public", nosconst.HZ, nosconst.PUBLICREAD)
 	c.Assert(err, IsNil)
 }
 
+func (s *NosClientTestSuite) TestBucketManagement(c *C) {
+	const bucket = "sjltestbucket-bj"
+	err := s.nosClient.CreateBucket(bucket, nosconst.BJ, nosconst.PRIVATE)
+	c.Assert(err, IsNil)
+
+	exist, err := s.nosClient.DoesBucketExist(bucket)
+	c.Assert(err, IsNil)
+	c.Assert(exist, Equals, true)
+
+	exist, err = s.nosClient.DoesBucketExist(BUCKETNOTEXIST)
+	c.Assert(err, IsNil)
+	c.Assert(exist, Equals, false)
+
+	location, err := s.nosClient.GetBucketLocation(bucket)
+	c.Assert(err, IsNil)
+	c.Assert(location.Location, Equals, "BJ")
+	parsed, ok := nosconst.ParseLocation(location.Location)
+	c.Assert(ok, Equals, true)
+	c.Assert(parsed, Equals, nosconst.BJ)
+
+	result, err := s.nosClient.ListBuckets()
+	c.Assert(err, IsNil)
+	names := []string{}
+	for _, b := range result.Buckets {
+		names = append(names, b.Name)
+		c.Assert(b.CreationDate, Not(Equals), "")
+	}
+	c.Assert(strings.Join(names, ","), Matches, "(.*,)?"+bucket+"(,.*)?")
+	c.Assert(strings.Join(names, ","), Matches, "(.*,)?"+TEST_BUCKET+"(,.*)?")
+
+	_, err = s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: bucket,
+		Object: "object",
+		Body:   strings.NewReader("data"),
+	})
+	c.Assert(err, IsNil)
+
+	err = s.nosClient.DeleteBucket(bucket)
+	c.Assert(err, ErrorMatches, ".*BucketNotEmpty.*")
+
+	err = s.nosClient.DeleteObject(&model.ObjectRequest{Bucket: bucket, Object: "object"})
+	c.Assert(err, IsNil)
+	err = s.nosClient.DeleteBucket(bucket)
+	c.Assert(err, IsNil)
+
+	err = s.nosClient.HeadBucket(bucket)
+	c.Assert(err, ErrorMatches, "StatusCode = 404.*")
+
+	_, err = s.nosClient.GetBucketLocation(bucket)
+	c.Assert(err, ErrorMatches, ".*NoSuchBucket.*")
+}
+
 func (s *NosClientTestSuite) TestPutObjectByStream(c *C) {
 
 	//test put object ok
@@ -275,13 +359,13 @@ func (s *NosClientTestSuite) TestDoesObjectExist(c *C) {
 func (s *NosClientTestSuite) TestCopyObject(c *C) {
 
 	//upload src file
-	path := "../test/" + "testserver"
+	path, srcMd5 := writeSrcFile(c)
 	contentType := "text/plain"
 
 	metadata := &model.ObjectMetadata{
 		Metadata: map[string]string{
 			nosconst.CONTENT_TYPE:     contentType,
-			nosconst.ORIG_CONTENT_MD5: SRCMD5,
+			nosconst.ORIG_CONTENT_MD5: srcMd5,
 		},
 	}
 
@@ -294,7 +378,7 @@ func (s *NosClientTestSuite) TestCopyObject(c *C) {
 
 	result, err := s.nosClient.PutObjectByFile(putObjectRequest)
 	c.Assert(err, IsNil)
-	c.Assert(result.Etag, Equals, SRCMD5)
+	c.Assert(result.Etag, Equals, srcMd5)
 
 	//copy file
 	copyRequest := &model.CopyObjectRequest{
@@ -337,13 +421,13 @@ func (s *NosClientTestSuite) TestCopyObject(c *C) {
 func (s *NosClientTestSuite) TestMoveObject(c *C) {
 
 	//upload src file
-	path := "../test/" + "testserver"
+	path, srcMd5 := writeSrcFile(c)
 	contentType := "text/plain"
 
 	metadata := &model.ObjectMetadata{
 		Metadata: map[string]string{
 			nosconst.CONTENT_TYPE:     contentType,
-			nosconst.ORIG_CONTENT_MD5: SRCMD5,
+			nosconst.ORIG_CONTENT_MD5: srcMd5,
 		},
 	}
 
@@ -356,7 +440,7 @@ func (s *NosClientTestSuite) TestMoveObject(c *C) {
 
 	result, err := s.nosClient.PutObjectByFile(putObjectRequest)
 	c.Assert(err, IsNil)
-	c.Assert(result.Etag, Equals, SRCMD5)
+	c.Assert(result.Etag, Equals, srcMd5)
 
 	//move file
 	moveRequest := &model.MoveObjectRequest{
@@ -566,6 +650,32 @@ func (s *NosClientTestSuite) TestDeleteMultilObjects(c *C) {
 	c.Assert(err.Error(), Equals, "StatusCode = 434, Resource = , Message = Request is nil")
 }
 
+func (s *NosClientTestSuite) TestDeleteObjects(c *C) {
+	var keys []string
+	for i := 0; i <= nosconst.MAX_FILENUMBER; i++ {
+		key := fmt.Sprintf("delete/%04d", i)
+		_, err := s.nosClient.PutObjectByStream(&model.PutObjectRequest{
+			Bucket: TEST_BUCKET,
+			Object: key,
+			Body:   strings.NewReader(key),
+		})
+		c.Assert(err, IsNil)
+		keys = append(keys, key)
+	}
+
+	deleted, err := s.nosClient.DeleteObjects(TEST_BUCKET, keys)
+	c.Assert(err, IsNil)
+	c.Assert(deleted, DeepEquals, keys)
+
+	result, err := s.nosClient.ListObjects(&model.ListObjectsRequest{Bucket: TEST_BUCKET, Prefix: "delete/"})
+	c.Assert(err, IsNil)
+	c.Assert(result.Contents, HasLen, 0)
+
+	deleted, err = s.nosClient.DeleteObjects(BUCKETNOTEXIST, keys)
+	c.Assert(err, NotNil)
+	c.Assert(deleted, HasLen, 0)
+}
+
 func (s *NosClientTestSuite) TestGetObjects(c *C) {
 
 	//upload file
@@ -635,7 +745,7 @@ func (s *NosClientTestSuite) TestGetObjects(c *C) {
 		IfModifiedSince: tm.Format(nosconst.RFC1123_GMT),
 	}
 	objectResult, err = s.nosClient.GetObject(objectRequest)
-	c.Assert(err, IsNil)
+	c.Assert(noserror.IsNotModified(err), Equals, true, Commentf("%v", err))
 	c.Assert(objectResult, IsNil)
 
 	objectRequest.Bucket = SPECIALBUCKET
@@ -750,7 +860,7 @@ func (s *NosClientTestSuite) TestListObjects(c *C) {
 func (s *NosClientTestSuite) TestBigFileUpload(c *C) {
 
 	var uploadId string
-	path := "../test/" + "testserver"
+	path, _ := writeSrcFile(c)
 	initRequest := &model.InitMultiUploadRequest{
 		Bucket: TEST_BUCKET,
 		Object: BIGFILEOBJECT,
@@ -774,6 +884,7 @@ func (s *NosClientTestSuite) TestBigFileUpload(c *C) {
 	//upload part
 	file, err := os.Open(path)
 	c.Assert(err, IsNil)
+	defer file.Close()
 	stat, err := file.Stat()
 	count := stat.Size()/1024000 + 1
 	uploadPartRequest := &model.UploadPartRequest{
@@ -834,7 +945,7 @@ func (s *NosClientTestSuite) TestBigFileUpload(c *C) {
 		Object: BIGFILEOBJECT,
 	}
 	data, err := s.nosClient.GetObjectMetaData(objectRequest)
-	c.Assert(data.ContentLength, Equals, int64(6390720))
+	c.Assert(data.ContentLength, Equals, int64(SRCFILESIZE))
 
 	_, err = s.nosClient.InitMultiUpload(nil)
 	c.Assert(err.Error(), Equals, "StatusCode = 434, Resource = , Message = Request is nil")
@@ -847,7 +958,7 @@ func (s *NosClientTestSuite) TestBigFileUpload(c *C) {
 func (s *NosClientTestSuite) TestListPartsAndAbort(c *C) {
 
 	var uploadId string
-	path := "../test/" + "testserver"
+	path, _ := writeSrcFile(c)
 	initRequest := &model.InitMultiUploadRequest{
 		Bucket: TEST_BUCKET,
 		Object: BIGFILEOBJECT,
@@ -863,6 +974,7 @@ func (s *NosClientTestSuite) TestListPartsAndAbort(c *C) {
 	//upload part
 	file, err := os.Open(path)
 	c.Assert(err, IsNil)
+	defer file.Close()
 	stat, err := file.Stat()
 	count := stat.Size()/1024000 + 1
 	uploadPartRequest := &model.UploadPartRequest{
@@ -1037,3 +1149,198 @@ func (s *NosClientTestSuite) TestListMultiUploads(c *C) {
 	_, err = s.nosClient.ListMultiUploads(nil)
 	c.Assert(err.Error(), Equals, "StatusCode = 434, Resource = , Message = Request is nil")
 }
+
+// rotatingProvider returns whatever credentials it currently holds.
+type rotatingProvider struct {
+	mu    sync.Mutex
+	creds credentials.Credentials
+	err   error
+}
+
+func (provider *rotatingProvider) set(creds credentials.Credentials, err error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	provider.creds, provider.err = creds, err
+}
+
+func (provider *rotatingProvider) Retrieve(ctx context.Context) (credentials.Credentials, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	return provider.creds, provider.err
+}
+
+func (s *NosClientTestSuite) TestCredentialsProvider(c *C) {
+	provider := &rotatingProvider{}
+	conf := s.server.Config()
+	conf.AccessKey, conf.SecretKey = "", ""
+	conf.Credentials = provider
+	client, err := New(conf)
+	c.Assert(err, IsNil)
+
+	request := &model.ObjectRequest{Bucket: TEST_BUCKET, Object: "credentials/missing"}
+	valid := credentials.Credentials{AccessKey: s.server.AccessKey, SecretKey: s.server.SecretKey}
+
+	// The provider is consulted for every request, so rotated keys are
+	// used without rebuilding the client.
+	provider.set(valid, nil)
+	exists, err := client.DoesObjectExist(request)
+	c.Assert(err, IsNil)
+	c.Assert(exists, Equals, false)
+
+	provider.set(credentials.Credentials{AccessKey: s.server.AccessKey, SecretKey: "rotated"}, nil)
+	_, err = client.DoesObjectExist(request)
+	c.Assert(err, ErrorMatches, "(?s).*StatusCode = 403.*")
+
+	provider.set(credentials.Credentials{}, errors.New("no credentials"))
+	_, err = client.DoesObjectExist(request)
+	c.Assert(err, ErrorMatches, ".*no credentials.*")
+	_, err = client.PresignGetObject(&model.PresignRequest{
+		Bucket:  TEST_BUCKET,
+		Object:  "credentials/missing",
+		Expires: time.Minute,
+	})
+	c.Assert(err, ErrorMatches, ".*no credentials.*")
+
+	provider.set(valid, nil)
+	_, err = client.DoesObjectExist(request)
+	c.Assert(err, IsNil)
+}
+
+func (s *NosClientTestSuite) TestSecurityToken(c *C) {
+	server := nostest.NewServerWithOptions(&nostest.Options{SecurityToken: "token"})
+	defer server.Close()
+	server.CreateBucket(TEST_BUCKET)
+
+	client, err := New(server.Config())
+	c.Assert(err, IsNil)
+	_, err = client.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: TEST_BUCKET,
+		Object: "temporary",
+		Body:   strings.NewReader("temporary"),
+	})
+	c.Assert(err, IsNil)
+
+	urlStr, err := client.PresignGetObject(&model.PresignRequest{
+		Bucket:  TEST_BUCKET,
+		Object:  "temporary",
+		Expires: time.Minute,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(urlStr, Matches, ".*NOSSecurityToken=token.*")
+	resp, err := http.Get(urlStr)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	// The token is signed, so it cannot be replaced.
+	resp, err = http.Get(strings.Replace(urlStr, "NOSSecurityToken=token", "NOSSecurityToken=other", 1))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusForbidden)
+
+	conf := server.Config()
+	conf.Credentials = nil
+	client, err = New(conf)
+	c.Assert(err, IsNil)
+	_, err = client.DoesObjectExist(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: "temporary"})
+	c.Assert(err, ErrorMatches, "(?s).*StatusCode = 403.*")
+
+	// Expired credentials are not sent.
+	provider := &rotatingProvider{}
+	provider.set(credentials.Credentials{
+		AccessKey:     server.AccessKey,
+		SecretKey:     server.SecretKey,
+		SecurityToken: "token",
+		Expires:       time.Now().Add(-time.Second),
+	}, nil)
+	conf.Credentials = provider
+	client, err = New(conf)
+	c.Assert(err, IsNil)
+	_, err = client.DoesObjectExist(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: "temporary"})
+	c.Assert(err, ErrorMatches, "StatusCode = 451, .*credentials expired at .*")
+}
+
+type HttpsTestSuite struct{}
+
+var _ = Suite(&HttpsTestSuite{})
+
+func (s *HttpsTestSuite) TestHttpsEndpoint(c *C) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.TLS, NotNil)
+		w.Header().Set("ETag", "\"etag\"")
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	conf := &config.Config{
+		Endpoint:  server.URL,
+		AccessKey: "12345",
+		SecretKey: "12345",
+		TLS: &config.TLSConfig{
+			RootCAs:    roots,
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+	conf.SetIsSubDomain(false)
+
+	client, err := New(conf)
+	c.Assert(err, IsNil)
+
+	result, err := client.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: "bucket",
+		Object: "object",
+		Body:   strings.NewReader("content"),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Etag, Equals, "etag")
+
+	// without the test CA the server certificate must be rejected
+	conf.TLS = nil
+	conf.Retry = &config.RetryConfig{MaxAttempts: 1}
+	client, err = New(conf)
+	c.Assert(err, IsNil)
+
+	_, err = client.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: "bucket",
+		Object: "object",
+		Body:   strings.NewReader("content"),
+	})
+	c.Assert(err, NotNil)
+}
+
+func (s *NosClientTestSuite) TestErrorClassification(c *C) {
+	_, err := s.nosClient.GetObject(&model.GetObjectRequest{Bucket: TEST_BUCKET, Object: "missing"})
+	c.Assert(noserror.IsNotFound(err), Equals, true)
+	c.Assert(noserror.IsAccessDenied(err), Equals, false)
+	c.Assert(noserror.IsRetryable(err), Equals, false)
+	c.Assert(errors.Is(err, &noserror.ServerError{NosErr: &noserror.NosError{Code: "NoSuchKey"}}), Equals, true)
+	var serverError *noserror.ServerError
+	c.Assert(errors.As(err, &serverError), Equals, true)
+	c.Assert(serverError.StatusCode, Equals, http.StatusNotFound)
+
+	conf := s.server.Config()
+	conf.SecretKey = "wrong"
+	client, err := New(conf)
+	c.Assert(err, IsNil)
+	_, err = client.GetObject(&model.GetObjectRequest{Bucket: TEST_BUCKET, Object: "missing"})
+	c.Assert(noserror.IsAccessDenied(err), Equals, true)
+	c.Assert(noserror.IsNotFound(err), Equals, false)
+
+	c.Assert(noserror.IsPreconditionFailed(&noserror.ServerError{
+		StatusCode: http.StatusPreconditionFailed,
+		NosErr:     noserror.NewNosError("PreconditionFailed", "", "", ""),
+	}), Equals, true)
+
+	// Client errors keep their cause.
+	_, err = NewUploader(s.nosClient).Upload(&model.UploadRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "missing",
+		FilePath: "not-exist.file",
+	})
+	c.Assert(errors.Is(err, os.ErrNotExist), Equals, true)
+	c.Assert(errors.Is(err, &noserror.ClientError{StatusCode: noserror.ERROR_CODE_FILE_INVALID}), Equals, true)
+	c.Assert(errors.Is(err, &noserror.ClientError{StatusCode: noserror.ERROR_CODE_REQUEST_ERROR}), Equals, false)
+	c.Assert(noserror.IsNotFound(err), Equals, false)
+}
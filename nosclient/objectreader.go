@@ -0,0 +1,374 @@
+package nosclient
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// ObjectReader reads an object through ranged GETs, a block at a time. It
+// implements io.ReadSeeker and io.ReaderAt, so that archive/zip and similar
+// readers can work on an object without downloading all of it.
+//
+// Fetched blocks are kept in a small cache, and sequential reads fetch the
+// next blocks in the background. Every block is checked against the ETag the
+// object had when the reader was opened, so that a reader never mixes the
+// content of two versions of the object.
+//
+// A compressed object cannot be read by range, so its content is
+// decompressed from a single GET instead: reading backwards starts over from
+// the beginning of the object, and blocks are not read ahead.
+//
+// ReadAt may be called concurrently; Read and Seek may not.
+type ObjectReader struct {
+	client    *NosClient
+	bucket    string
+	object    string
+	versionId string
+	etag      string
+	size      int64
+	metadata  *model.ObjectMetadata
+
+	blockSize int64
+	readAhead int
+	capacity  int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// The cached blocks, by index, and their use order, most recent first.
+	mu     sync.Mutex
+	blocks map[int64]*list.Element
+	lru    *list.List
+	closed bool
+
+	offset int64
+
+	// The decompressed content of a compressed object, read up to
+	// streamOffset.
+	compressed   bool
+	streamMu     sync.Mutex
+	stream       io.ReadCloser
+	streamOffset int64
+}
+
+// readerBlock is a block of the object, fetched in the background. ready is
+// closed once data or err is set.
+type readerBlock struct {
+	index int64
+	data  []byte
+	err   error
+	ready chan struct{}
+}
+
+// NewObjectReader opens the object described by objectReaderRequest for
+// reading. ctx cancels the reader's requests when it is done. The reader
+// must be closed to stop its background requests.
+func (client *NosClient) NewObjectReader(ctx context.Context, objectReaderRequest *model.ObjectReaderRequest) (
+	*ObjectReader, error) {
+
+	if objectReaderRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	bucket := objectReaderRequest.Bucket
+	object := objectReaderRequest.Object
+
+	err := utils.VerifyParamsWithObject(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	blockSize := objectReaderRequest.BlockSize
+	if blockSize == 0 {
+		blockSize = nosconst.DEFAULT_BLOCKSIZE
+	}
+	if blockSize < 0 {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, object,
+			"negative BlockSize")
+	}
+	readAhead := objectReaderRequest.ReadAhead
+	if readAhead == 0 {
+		readAhead = nosconst.DEFAULT_READAHEAD
+	}
+	if readAhead < 0 {
+		readAhead = 0
+	}
+	capacity := objectReaderRequest.CacheBlocks
+	if capacity <= 0 {
+		capacity = nosconst.DEFAULT_CACHEBLOCKS
+	}
+	if capacity < readAhead+1 {
+		capacity = readAhead + 1
+	}
+
+	metadata, err := client.GetObjectMetaDataWithContext(ctx, &model.ObjectRequest{
+		Bucket:    bucket,
+		Object:    object,
+		VersionId: objectReaderRequest.VersionId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	compressed := isCompressed(metadata)
+	if compressed {
+		if metadata.ContentLength < 0 {
+			return nil, utils.ProcessClientError(noserror.ERROR_CODE_COMPRESSION_ERROR, bucket, object,
+				"the uncompressed length of the object was not recorded")
+		}
+		readAhead = 0
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &ObjectReader{
+		client:     client,
+		bucket:     bucket,
+		object:     object,
+		versionId:  objectReaderRequest.VersionId,
+		etag:       metadata.Metadata[nosconst.ETAG],
+		size:       metadata.ContentLength,
+		metadata:   metadata,
+		blockSize:  blockSize,
+		readAhead:  readAhead,
+		capacity:   capacity,
+		ctx:        ctx,
+		cancel:     cancel,
+		blocks:     make(map[int64]*list.Element),
+		lru:        list.New(),
+		compressed: compressed,
+	}, nil
+}
+
+// Size returns the size of the object.
+func (r *ObjectReader) Size() int64 {
+	return r.size
+}
+
+// Metadata returns the metadata of the object when the reader was opened.
+func (r *ObjectReader) Metadata() *model.ObjectMetadata {
+	return r.metadata
+}
+
+// Read reads from the current offset, at most to the end of its block, and
+// starts fetching the blocks that follow.
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		if r.isClosed() {
+			return 0, os.ErrClosed
+		}
+		return 0, io.EOF
+	}
+
+	// The block read comes first in the cache's use order, so that reading
+	// ahead never evicts it.
+	index := r.offset / r.blockSize
+	if _, err := r.block(index); err != nil {
+		return 0, err
+	}
+	for i := int64(1); i <= int64(r.readAhead) && (index+i)*r.blockSize < r.size; i++ {
+		if _, err := r.block(index + i); err != nil {
+			return 0, err
+		}
+	}
+
+	if rest := (index+1)*r.blockSize - r.offset; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	n, err := r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt reads len(p) bytes from offset off, unless the object ends first.
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, r.bucket, r.object,
+			"negative offset")
+	}
+
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= r.size {
+			return n, io.EOF
+		}
+
+		index := pos / r.blockSize
+		b, err := r.block(index)
+		if err != nil {
+			return n, err
+		}
+		<-b.ready
+		if b.err != nil {
+			return n, b.err
+		}
+		n += copy(p[n:], b.data[pos-index*r.blockSize:])
+	}
+	return n, nil
+}
+
+// Seek sets the offset of the next Read. Seeking past the end of the object
+// is allowed; reading there returns io.EOF.
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, r.bucket, r.object,
+			"invalid whence")
+	}
+	if offset < 0 {
+		return 0, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, r.bucket, r.object,
+			"negative offset")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// Close stops the background requests and drops the cache. Later reads
+// return os.ErrClosed.
+func (r *ObjectReader) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.blocks = nil
+	r.lru.Init()
+	r.mu.Unlock()
+
+	r.cancel()
+	r.wg.Wait()
+
+	r.streamMu.Lock()
+	if r.stream != nil {
+		r.stream.Close()
+		r.stream = nil
+	}
+	r.streamMu.Unlock()
+	return nil
+}
+
+func (r *ObjectReader) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// block returns the block at index from the cache, starting to fetch it if
+// it is not there.
+func (r *ObjectReader) block(index int64) (*readerBlock, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, os.ErrClosed
+	}
+	if e, ok := r.blocks[index]; ok {
+		r.lru.MoveToFront(e)
+		return e.Value.(*readerBlock), nil
+	}
+
+	b := &readerBlock{index: index, ready: make(chan struct{})}
+	r.blocks[index] = r.lru.PushFront(b)
+	for r.lru.Len() > r.capacity {
+		r.remove(r.lru.Back())
+	}
+
+	r.wg.Add(1)
+	go r.fetch(b)
+	return b, nil
+}
+
+// remove drops e from the cache. The caller must hold mu.
+func (r *ObjectReader) remove(e *list.Element) {
+	r.lru.Remove(e)
+	delete(r.blocks, e.Value.(*readerBlock).index)
+}
+
+// fetch fetches b. A failed block is dropped from the cache, so that a later
+// read tries again.
+func (r *ObjectReader) fetch(b *readerBlock) {
+	defer r.wg.Done()
+
+	start := b.index * r.blockSize
+	end := start + r.blockSize
+	if end > r.size {
+		end = r.size
+	}
+
+	buffer := bytes.NewBuffer(make([]byte, 0, end-start))
+	if r.compressed {
+		b.err = r.readDecompressed(start, end, buffer)
+	} else {
+		b.err = r.client.getRange(r.ctx, r.bucket, r.object, r.versionId, r.etag, start, end-1, r.size, buffer)
+	}
+	b.data = buffer.Bytes()
+
+	if b.err != nil {
+		r.mu.Lock()
+		if e, ok := r.blocks[b.index]; ok && e.Value == b {
+			r.remove(e)
+		}
+		r.mu.Unlock()
+	}
+	close(b.ready)
+}
+
+// readDecompressed copies the decompressed content of the object from start
+// to end, exclusive, to writer. It goes on reading the current GET if it has
+// not gone past start, and starts a new one otherwise.
+func (r *ObjectReader) readDecompressed(start, end int64, writer io.Writer) error {
+	r.streamMu.Lock()
+	defer r.streamMu.Unlock()
+
+	if r.stream != nil && r.streamOffset > start {
+		r.stream.Close()
+		r.stream = nil
+	}
+	if r.stream == nil {
+		result, err := r.client.GetObjectWithContext(r.ctx, &model.GetObjectRequest{
+			Bucket:    r.bucket,
+			Object:    r.object,
+			VersionId: r.versionId,
+		})
+		if err != nil {
+			return err
+		}
+		if result.ObjectMetadata.Metadata[nosconst.ETAG] != r.etag {
+			result.Body.Close()
+			return utils.ProcessClientError(noserror.ERROR_CODE_CONTENT_MISMATCH, r.bucket, r.object,
+				"the object changed during the download")
+		}
+		r.stream, r.streamOffset = result.Body, 0
+	}
+
+	_, err := io.CopyN(ioutil.Discard, r.stream, start-r.streamOffset)
+	if err == nil {
+		_, err = io.CopyN(writer, r.stream, end-start)
+	}
+	if err != nil {
+		r.stream.Close()
+		r.stream = nil
+		return readContentError(r.bucket, r.object, err)
+	}
+	r.streamOffset = end
+	return nil
+}
@@ -0,0 +1,174 @@
+package nosclient
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+)
+
+type ObjectReaderTestSuite struct {
+	server    *nostest.Server
+	proxy     *httptest.Server
+	gets      int64
+	nosClient *NosClient
+}
+
+var _ = Suite(&ObjectReaderTestSuite{})
+
+// SetUpTest puts a proxy counting the GET requests in front of the server.
+func (s *ObjectReaderTestSuite) SetUpTest(c *C) {
+	s.gets = 0
+	s.proxy = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt64(&s.gets, 1)
+		}
+		s.server.ServeHTTP(w, r)
+	}))
+	s.nosClient, s.server = newTestClient(c, func(conf *config.Config) {
+		conf.Endpoint = s.proxy.URL
+	})
+}
+
+func (s *ObjectReaderTestSuite) TearDownTest(c *C) {
+	s.proxy.Close()
+	s.server.Close()
+}
+
+func (s *ObjectReaderTestSuite) newObjectReader(c *C, object string) *ObjectReader {
+	reader, err := s.nosClient.NewObjectReader(context.Background(), &model.ObjectReaderRequest{
+		Bucket:      TEST_BUCKET,
+		Object:      object,
+		BlockSize:   nosconst.MIN_FILESIZE,
+		ReadAhead:   1,
+		CacheBlocks: 2,
+	})
+	c.Assert(err, IsNil)
+	return reader
+}
+
+func (s *ObjectReaderTestSuite) TestReadAndSeek(c *C) {
+	content := randomContent(5*nosconst.MIN_FILESIZE + 100)
+	putTestObject(c, s.nosClient, TEST_BUCKET, "reader/object", content)
+
+	reader := s.newObjectReader(c, "reader/object")
+	defer reader.Close()
+	c.Assert(reader.Size(), Equals, int64(len(content)))
+
+	read, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content)
+	c.Assert(atomic.LoadInt64(&s.gets), Equals, int64(6))
+
+	// The last blocks are still cached.
+	offset, err := reader.Seek(-nosconst.MIN_FILESIZE, io.SeekEnd)
+	c.Assert(err, IsNil)
+	c.Assert(offset, Equals, int64(4*nosconst.MIN_FILESIZE+100))
+	read, err = ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, content[offset:])
+	c.Assert(atomic.LoadInt64(&s.gets), Equals, int64(6))
+
+	p := make([]byte, 2*nosconst.MIN_FILESIZE)
+	n, err := reader.ReadAt(p, 100)
+	c.Assert(err, IsNil)
+	c.Assert(p[:n], DeepEquals, content[100:100+len(p)])
+
+	n, err = reader.ReadAt(p, int64(len(content)-10))
+	c.Assert(err, Equals, io.EOF)
+	c.Assert(p[:n], DeepEquals, content[len(content)-10:])
+
+	_, err = reader.Seek(-1, io.SeekStart)
+	c.Assert(err, NotNil)
+	_, err = reader.ReadAt(p, -1)
+	c.Assert(err, NotNil)
+
+	c.Assert(reader.Close(), IsNil)
+	_, err = reader.ReadAt(p, 0)
+	c.Assert(err, Equals, os.ErrClosed)
+}
+
+func (s *ObjectReaderTestSuite) TestZip(c *C) {
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	large := randomContent(10 * nosconst.MIN_FILESIZE)
+	for name, content := range map[string][]byte{"large.bin": large, "small.txt": []byte("small")} {
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		c.Assert(err, IsNil)
+		_, err = w.Write(content)
+		c.Assert(err, IsNil)
+	}
+	c.Assert(writer.Close(), IsNil)
+	putTestObject(c, s.nosClient, TEST_BUCKET, "reader/archive.zip", archive.Bytes())
+
+	reader := s.newObjectReader(c, "reader/archive.zip")
+	defer reader.Close()
+	zipReader, err := zip.NewReader(reader, reader.Size())
+	c.Assert(err, IsNil)
+	c.Assert(zipReader.File, HasLen, 2)
+
+	for _, file := range zipReader.File {
+		if file.Name != "small.txt" {
+			continue
+		}
+		r, err := file.Open()
+		c.Assert(err, IsNil)
+		content, err := ioutil.ReadAll(r)
+		c.Assert(err, IsNil)
+		c.Assert(string(content), Equals, "small")
+	}
+
+	// Only the blocks around the small file and the central directory are
+	// fetched, not the large file.
+	gets := atomic.LoadInt64(&s.gets)
+	c.Assert(gets <= 4, Equals, true, Commentf("%d GETs", gets))
+}
+
+func (s *ObjectReaderTestSuite) TestObjectChanged(c *C) {
+	putTestObject(c, s.nosClient, TEST_BUCKET, "reader/changing", bytes.Repeat([]byte("a"), 2*nosconst.MIN_FILESIZE))
+
+	reader, err := s.nosClient.NewObjectReader(context.Background(), &model.ObjectReaderRequest{
+		Bucket:    TEST_BUCKET,
+		Object:    "reader/changing",
+		BlockSize: nosconst.MIN_FILESIZE,
+		ReadAhead: -1,
+	})
+	c.Assert(err, IsNil)
+	defer reader.Close()
+
+	p := make([]byte, 10)
+	_, err = reader.ReadAt(p, 0)
+	c.Assert(err, IsNil)
+
+	putTestObject(c, s.nosClient, TEST_BUCKET, "reader/changing", bytes.Repeat([]byte("b"), 2*nosconst.MIN_FILESIZE))
+	_, err = reader.ReadAt(p, nosconst.MIN_FILESIZE)
+	c.Assert(err, ErrorMatches, "(?s).*changed.*")
+}
+
+func (s *ObjectReaderTestSuite) TestErrors(c *C) {
+	_, err := s.nosClient.NewObjectReader(context.Background(), nil)
+	c.Assert(err, NotNil)
+
+	_, err = s.nosClient.NewObjectReader(context.Background(), &model.ObjectReaderRequest{
+		Bucket: TEST_BUCKET,
+		Object: "reader/missing",
+	})
+	c.Assert(err, ErrorMatches, "(?s).*404.*")
+
+	putTestObject(c, s.nosClient, TEST_BUCKET, "reader/empty", nil)
+	reader := s.newObjectReader(c, "reader/empty")
+	defer reader.Close()
+	n, err := reader.Read(make([]byte, 10))
+	c.Assert(n, Equals, 0)
+	c.Assert(err, Equals, io.EOF)
+}
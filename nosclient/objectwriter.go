@@ -0,0 +1,320 @@
+package nosclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"hash"
+	"io"
+)
+
+// ObjectWriter writes an object as it is produced. The written content is
+// buffered into parts, which are uploaded in the background as a multipart
+// upload; Close commits the upload and CloseWithError abandons it. Content
+// that fits in a single part is sent with a single PUT on Close instead.
+//
+// When the client compresses uploads, the content is compressed as it is
+// written, and the parts are parts of the compressed content.
+//
+// An ObjectWriter is not safe for concurrent use.
+type ObjectWriter struct {
+	client   *NosClient
+	uploader *Uploader
+	request  model.ObjectWriterRequest
+	partSize int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	buffer []byte
+	number int
+
+	// hash is the MD5 of the parts handed to the background upload, when
+	// the client verifies integrity.
+	hash hash.Hash
+
+	// The compressor, if the content is compressed, writes the compressed
+	// content to the buffer. The length and the MD5 of the content written
+	// to it are recorded if the content ends up in a single PUT.
+	codec         Codec
+	compressor    io.WriteCloser
+	contentLength int64
+	contentHash   hash.Hash
+
+	// The multipart upload, started once the content outgrows one part.
+	// The background upload reads the parts from parts, and closes done
+	// once it has finished with uploaded and uploadErr set.
+	uploadId  string
+	parts     chan partContent
+	done      chan struct{}
+	uploaded  []model.UploadPart
+	uploadErr error
+
+	err    error
+	closed bool
+	result *model.ObjectResult
+}
+
+// NewObjectWriter returns a writer for the object described by
+// objectWriterRequest. ctx cancels the upload when it is done.
+func (client *NosClient) NewObjectWriter(ctx context.Context, objectWriterRequest *model.ObjectWriterRequest) (
+	*ObjectWriter, error) {
+
+	if objectWriterRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	err := utils.VerifyParamsWithObject(objectWriterRequest.Bucket, objectWriterRequest.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	uploader := NewUploader(client)
+	uploader.PartSize = objectWriterRequest.PartSize
+	if objectWriterRequest.Concurrency > 0 {
+		uploader.Concurrency = objectWriterRequest.Concurrency
+	}
+	partSize, err := uploader.partSize(0)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &ObjectWriter{
+		client:   client,
+		uploader: uploader,
+		request:  *objectWriterRequest,
+		partSize: partSize,
+		ctx:      ctx,
+		cancel:   cancel,
+		buffer:   make([]byte, 0, partSize),
+	}
+	if client.verifyIntegrity {
+		w.hash = md5.New()
+	}
+	if codec := client.compressionCodec(objectWriterRequest.Metadata); codec != nil {
+		w.compressor, err = codec.NewWriter(&objectWriterBuffer{w: w})
+		if err != nil {
+			cancel()
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_COMPRESSION_ERROR, objectWriterRequest.Bucket,
+				objectWriterRequest.Object, err)
+		}
+		w.codec = codec
+		w.contentHash = md5.New()
+	}
+	return w, nil
+}
+
+// Write buffers p, uploading every part it fills. It returns the error of
+// a failed part upload, after which the writer can only be closed.
+func (w *ObjectWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	if w.compressor == nil {
+		return w.write(p)
+	}
+	n, err := w.compressor.Write(p)
+	w.contentLength += int64(n)
+	w.contentHash.Write(p[:n])
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// objectWriterBuffer receives the compressed content of an ObjectWriter.
+type objectWriterBuffer struct {
+	w *ObjectWriter
+}
+
+func (b *objectWriterBuffer) Write(p []byte) (int, error) {
+	return b.w.write(p)
+}
+
+// write buffers p, uploading every part it fills.
+func (w *ObjectWriter) write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// A full part is only sent once more content follows, so that
+		// content of exactly one part is still sent with a single PUT.
+		if int64(len(w.buffer)) == w.partSize {
+			if err := w.flush(); err != nil {
+				w.err = err
+				return written, err
+			}
+		}
+
+		n := copy(w.buffer[len(w.buffer):cap(w.buffer)], p)
+		w.buffer = w.buffer[:len(w.buffer)+n]
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// flush hands the buffered part to the background upload, starting the
+// upload first if needed.
+func (w *ObjectWriter) flush() error {
+	bucket := w.request.Bucket
+	object := w.request.Object
+
+	if w.uploadId == "" {
+		metadata := w.request.Metadata
+		if w.codec != nil {
+			metadata = compressedMetadata(metadata, w.codec, -1, "")
+		}
+		initResult, err := w.client.InitMultiUploadWithContext(w.ctx, &model.InitMultiUploadRequest{
+			Bucket:   bucket,
+			Object:   object,
+			Metadata: metadata,
+			Acl:      w.request.Acl,
+		})
+		if err != nil {
+			return err
+		}
+
+		w.uploadId = initResult.UploadId
+		w.parts = make(chan partContent)
+		w.done = make(chan struct{})
+		go func() {
+			defer close(w.done)
+			w.uploaded, w.uploadErr = w.uploader.uploadParts(w.ctx, bucket, object, w.uploadId,
+				func(emit func(partContent) bool) error {
+					for part := range w.parts {
+						if !emit(part) {
+							return nil
+						}
+					}
+					return nil
+				}, nil)
+		}()
+	}
+
+	w.number++
+	if w.number > nosconst.MAX_PARTNUMBER {
+		return utils.ProcessClientError(noserror.ERROR_CODE_PARTNUMBER_ERROR, bucket, object, "")
+	}
+
+	select {
+	case w.parts <- partContent{number: w.number, data: w.buffer}:
+	case <-w.done:
+		return w.uploadErr
+	}
+	if w.hash != nil {
+		w.hash.Write(w.buffer)
+	}
+	w.buffer = make([]byte, 0, w.partSize)
+	return nil
+}
+
+// Close uploads the rest of the content and commits the object. If a
+// write failed, or the commit fails, the upload is aborted and the error is
+// returned.
+func (w *ObjectWriter) Close() error {
+	if w.closed {
+		return io.ErrClosedPipe
+	}
+	w.closed = true
+	defer w.cancel()
+
+	// Closing the compressor writes the end of the compressed content.
+	if w.compressor != nil && w.err == nil {
+		if err := w.compressor.Close(); err != nil && w.err == nil {
+			w.err = utils.WrapClientError(noserror.ERROR_CODE_COMPRESSION_ERROR, w.request.Bucket,
+				w.request.Object, err)
+		}
+	}
+	if w.err != nil {
+		w.abort()
+		return w.err
+	}
+
+	bucket := w.request.Bucket
+	object := w.request.Object
+
+	if w.uploadId == "" {
+		metadata := &model.ObjectMetadata{ContentLength: int64(len(w.buffer))}
+		if w.codec != nil {
+			metadata = compressedMetadata(w.request.Metadata, w.codec, w.contentLength, hashHex(w.contentHash))
+			metadata.ContentLength = int64(len(w.buffer))
+		} else if w.request.Metadata != nil {
+			metadata.Metadata = w.request.Metadata.Metadata
+		}
+		result, err := w.client.PutObjectByStreamWithContext(w.ctx, &model.PutObjectRequest{
+			Bucket:   bucket,
+			Object:   object,
+			Body:     bytes.NewReader(w.buffer),
+			Metadata: metadata,
+			Acl:      w.request.Acl,
+		})
+		if err != nil {
+			return err
+		}
+		w.result = result
+		return nil
+	}
+
+	if err := w.flush(); err != nil {
+		w.abort()
+		return err
+	}
+	close(w.parts)
+	<-w.done
+	if w.uploadErr != nil {
+		w.uploader.abort(bucket, object, w.uploadId)
+		return w.uploadErr
+	}
+
+	result, err := w.uploader.complete(w.ctx, bucket, object, w.uploadId, w.uploaded, hashHex(w.hash))
+	if err != nil {
+		return err
+	}
+	w.result = &model.ObjectResult{Etag: result.Etag}
+	return nil
+}
+
+// CloseWithError abandons the object: the background upload is stopped
+// and aborted, and nothing is written. Later calls to Write return
+// io.ErrClosedPipe.
+func (w *ObjectWriter) CloseWithError(err error) error {
+	if w.closed {
+		return io.ErrClosedPipe
+	}
+	w.closed = true
+	w.cancel()
+
+	w.client.Log.Debug("object writer for", w.request.Object, "closed with error:", err)
+	w.abort()
+	return nil
+}
+
+// abort stops the background upload, if there is one, and aborts it. The
+// caller must have set closed.
+func (w *ObjectWriter) abort() {
+	if w.uploadId == "" {
+		return
+	}
+	w.cancel()
+	close(w.parts)
+	<-w.done
+	w.uploader.abort(w.request.Bucket, w.request.Object, w.uploadId)
+}
+
+// Result returns the result of the upload once Close has succeeded, and nil
+// before.
+func (w *ObjectWriter) Result() *model.ObjectResult {
+	return w.result
+}
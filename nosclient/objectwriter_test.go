@@ -0,0 +1,100 @@
+package nosclient
+
+import (
+	"context"
+	"errors"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	. "gopkg.in/check.v1"
+	"io"
+)
+
+func (s *UploaderTestSuite) newObjectWriter(c *C, ctx context.Context, object string) *ObjectWriter {
+	writer, err := s.nosClient.NewObjectWriter(ctx, &model.ObjectWriterRequest{
+		Bucket:      TEST_BUCKET,
+		Object:      object,
+		PartSize:    nosconst.MIN_FILESIZE,
+		Concurrency: 2,
+		Metadata: &model.ObjectMetadata{
+			Metadata: map[string]string{nosconst.CONTENT_TYPE: "text/csv"},
+		},
+	})
+	c.Assert(err, IsNil)
+	return writer
+}
+
+// writeChunks writes content in small chunks, as an encoder would.
+func writeChunks(writer io.Writer, content []byte) error {
+	for len(content) > 0 {
+		n := 1000
+		if n > len(content) {
+			n = len(content)
+		}
+		if _, err := writer.Write(content[:n]); err != nil {
+			return err
+		}
+		content = content[n:]
+	}
+	return nil
+}
+
+func (s *UploaderTestSuite) listUploads(c *C) []model.MultipartUpload {
+	uploads, err := s.nosClient.ListMultiUploads(&model.ListMultiUploadsRequest{Bucket: TEST_BUCKET})
+	c.Assert(err, IsNil)
+	return uploads.Uploads
+}
+
+func (s *UploaderTestSuite) TestObjectWriter(c *C) {
+	for _, test := range []struct {
+		object string
+		size   int
+		etag   string
+	}{
+		{"writer/empty", 0, "[0-9a-f]{32}"},
+		{"writer/part", nosconst.MIN_FILESIZE, "[0-9a-f]{32}"},
+		{"writer/large", 3*nosconst.MIN_FILESIZE + 10, "[0-9a-f]{32}-4"},
+	} {
+		content := randomContent(test.size)
+		writer := s.newObjectWriter(c, context.Background(), test.object)
+		c.Assert(writeChunks(writer, content), IsNil)
+		c.Assert(writer.Result(), IsNil)
+		c.Assert(writer.Close(), IsNil)
+
+		c.Assert(writer.Result().Etag, Matches, test.etag, Commentf(test.object))
+		c.Assert(s.getContent(c, test.object), DeepEquals, content, Commentf(test.object))
+		metadata, err := s.nosClient.GetObjectMetaData(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: test.object})
+		c.Assert(err, IsNil)
+		c.Assert(metadata.Metadata[nosconst.CONTENT_TYPE], Equals, "text/csv")
+
+		_, err = writer.Write([]byte("late"))
+		c.Assert(err, Equals, io.ErrClosedPipe)
+		c.Assert(writer.Close(), Equals, io.ErrClosedPipe)
+	}
+}
+
+func (s *UploaderTestSuite) TestObjectWriterCloseWithError(c *C) {
+	writer := s.newObjectWriter(c, context.Background(), "writer/abandoned")
+	c.Assert(writeChunks(writer, randomContent(5*nosconst.MIN_FILESIZE/2)), IsNil)
+	c.Assert(s.listUploads(c), HasLen, 1)
+
+	c.Assert(writer.CloseWithError(errors.New("encoder failed")), IsNil)
+	c.Assert(s.listUploads(c), HasLen, 0)
+	exist, err := s.nosClient.DoesObjectExist(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: "writer/abandoned"})
+	c.Assert(err, IsNil)
+	c.Assert(exist, Equals, false)
+
+	_, err = writer.Write([]byte("late"))
+	c.Assert(err, Equals, io.ErrClosedPipe)
+}
+
+func (s *UploaderTestSuite) TestObjectWriterCancel(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	writer := s.newObjectWriter(c, ctx, "writer/cancelled")
+	c.Assert(writeChunks(writer, randomContent(5*nosconst.MIN_FILESIZE/2)), IsNil)
+
+	cancel()
+	err := writeChunks(writer, randomContent(4*nosconst.MIN_FILESIZE))
+	c.Assert(err, NotNil)
+	c.Assert(writer.Close(), Equals, err)
+	c.Assert(s.listUploads(c), HasLen, 0)
+}
@@ -0,0 +1,233 @@
+package nosclient
+
+import (
+	"context"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+)
+
+// paginator holds the state shared by the list paginators.
+type paginator struct {
+	ctx  context.Context
+	err  error
+	done bool
+}
+
+// next fetches a page with fetch, which reports whether another page
+// follows it. It stops once ctx is done or a request fails. A page after
+// which the listing cannot go on is still returned, but stops the paginator
+// with the error that fetch records with stop.
+func (p *paginator) next(fetch func() (bool, error)) bool {
+	if p.done {
+		return false
+	}
+	if err := p.ctx.Err(); err != nil {
+		p.err = err
+		p.done = true
+		return false
+	}
+
+	more, err := fetch()
+	if err != nil {
+		p.err = err
+		p.done = true
+		return false
+	}
+	p.done = !more
+	return true
+}
+
+// Err returns the error that stopped the paginator, if any. It is nil when
+// every page was listed.
+func (p *paginator) Err() error {
+	return p.err
+}
+
+// stop records that the listing is truncated but gives no marker to go on
+// from, as problem tells, so that the caller does not take the pages listed
+// so far for all of them, and reports that no page follows.
+func (p *paginator) stop(bucket, problem string) bool {
+	p.err = utils.ProcessClientError(noserror.ERROR_CODE_PARSEXML_ERROR, bucket, "",
+		"the listing is truncated but "+problem)
+	return false
+}
+
+// ObjectsPaginator lists the objects of a bucket page by page, following the
+// markers of truncated results:
+//
+//	paginator := client.NewObjectsPaginator(ctx, &model.ListObjectsRequest{Bucket: bucket})
+//	for paginator.Next() {
+//		for _, contents := range paginator.Page().Contents {
+//			...
+//		}
+//	}
+//	if err := paginator.Err(); err != nil {
+//		...
+//	}
+type ObjectsPaginator struct {
+	paginator
+	client  *NosClient
+	request *model.ListObjectsRequest
+	page    *model.ListObjectsResult
+}
+
+// NewObjectsPaginator returns a paginator listing the objects selected by
+// listObjectsRequest, starting at its Marker. MaxKeys is the page size hint.
+// The paginator works on a copy of the request.
+func (client *NosClient) NewObjectsPaginator(ctx context.Context,
+	listObjectsRequest *model.ListObjectsRequest) *ObjectsPaginator {
+
+	paginator := &ObjectsPaginator{
+		paginator: paginator{ctx: ctx},
+		client:    client,
+	}
+	if listObjectsRequest != nil {
+		request := *listObjectsRequest
+		paginator.request = &request
+	}
+	return paginator
+}
+
+// Next fetches the next page and reports whether there is one.
+func (p *ObjectsPaginator) Next() bool {
+	return p.next(func() (bool, error) {
+		page, err := p.client.ListObjectsWithContext(p.ctx, p.request)
+		if err != nil {
+			return false, err
+		}
+		p.page = page
+
+		// NextMarker may be left out, in which case the listing goes on
+		// after the last key or common prefix of the page.
+		marker := page.NextMarker
+		if marker == "" {
+			if n := len(page.Contents); n > 0 {
+				marker = page.Contents[n-1].Key
+			}
+			if n := len(page.CommonPrefixes); n > 0 && page.CommonPrefixes[n-1].Prefix > marker {
+				marker = page.CommonPrefixes[n-1].Prefix
+			}
+		}
+		if !page.IsTruncated {
+			return false, nil
+		}
+		if marker == "" {
+			return p.stop(p.request.Bucket, "has no NextMarker"), nil
+		}
+		p.request.Marker = marker
+		return true, nil
+	})
+}
+
+// Page returns the page fetched by the last call to Next.
+func (p *ObjectsPaginator) Page() *model.ListObjectsResult {
+	return p.page
+}
+
+// MultiUploadsPaginator lists the in-progress multipart uploads of a bucket
+// page by page, following both the key and upload id markers of truncated
+// results. It is used like ObjectsPaginator.
+type MultiUploadsPaginator struct {
+	paginator
+	client  *NosClient
+	request *model.ListMultiUploadsRequest
+	page    *model.ListMultiUploadsResult
+}
+
+// NewMultiUploadsPaginator returns a paginator listing the uploads selected
+// by listMultiUploadsRequest, starting at its KeyMarker and UploadIdMarker.
+// MaxUploads is the page size hint. The paginator works on a copy of the
+// request.
+func (client *NosClient) NewMultiUploadsPaginator(ctx context.Context,
+	listMultiUploadsRequest *model.ListMultiUploadsRequest) *MultiUploadsPaginator {
+
+	paginator := &MultiUploadsPaginator{
+		paginator: paginator{ctx: ctx},
+		client:    client,
+	}
+	if listMultiUploadsRequest != nil {
+		request := *listMultiUploadsRequest
+		paginator.request = &request
+	}
+	return paginator
+}
+
+// Next fetches the next page and reports whether there is one.
+func (p *MultiUploadsPaginator) Next() bool {
+	return p.next(func() (bool, error) {
+		page, err := p.client.ListMultiUploadsWithContext(p.ctx, p.request)
+		if err != nil {
+			return false, err
+		}
+		p.page = page
+
+		if !page.IsTruncated {
+			return false, nil
+		}
+		if page.NextKeyMarker == "" {
+			return p.stop(p.request.Bucket, "has no NextKeyMarker"), nil
+		}
+		p.request.KeyMarker = page.NextKeyMarker
+		p.request.UploadIdMarker = page.NextUploadIdMarker
+		return true, nil
+	})
+}
+
+// Page returns the page fetched by the last call to Next.
+func (p *MultiUploadsPaginator) Page() *model.ListMultiUploadsResult {
+	return p.page
+}
+
+// UploadPartsPaginator lists the parts of a multipart upload page by page,
+// following the part number markers of truncated results. It is used like
+// ObjectsPaginator.
+type UploadPartsPaginator struct {
+	paginator
+	client  *NosClient
+	request *model.ListUploadPartsRequest
+	page    *model.ListPartsResult
+}
+
+// NewUploadPartsPaginator returns a paginator listing the parts of the
+// upload named by listUploadPartsRequest, after its PartNumberMarker.
+// MaxParts is the page size hint. The paginator works on a copy of the
+// request.
+func (client *NosClient) NewUploadPartsPaginator(ctx context.Context,
+	listUploadPartsRequest *model.ListUploadPartsRequest) *UploadPartsPaginator {
+
+	paginator := &UploadPartsPaginator{
+		paginator: paginator{ctx: ctx},
+		client:    client,
+	}
+	if listUploadPartsRequest != nil {
+		request := *listUploadPartsRequest
+		paginator.request = &request
+	}
+	return paginator
+}
+
+// Next fetches the next page and reports whether there is one.
+func (p *UploadPartsPaginator) Next() bool {
+	return p.next(func() (bool, error) {
+		page, err := p.client.ListUploadPartsWithContext(p.ctx, p.request)
+		if err != nil {
+			return false, err
+		}
+		p.page = page
+
+		if !page.IsTruncated {
+			return false, nil
+		}
+		if page.NextPartNumberMarker <= p.request.PartNumberMarker {
+			return p.stop(p.request.Bucket, "its NextPartNumberMarker does not advance"), nil
+		}
+		p.request.PartNumberMarker = page.NextPartNumberMarker
+		return true, nil
+	})
+}
+
+// Page returns the page fetched by the last call to Next.
+func (p *UploadPartsPaginator) Page() *model.ListPartsResult {
+	return p.page
+}
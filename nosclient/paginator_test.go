@@ -0,0 +1,218 @@
+package nosclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+)
+
+type PaginatorTestSuite struct {
+	server    *nostest.Server
+	nosClient *NosClient
+}
+
+var _ = Suite(&PaginatorTestSuite{})
+
+func (s *PaginatorTestSuite) SetUpTest(c *C) {
+	s.nosClient, s.server = newTestClient(c)
+}
+
+func (s *PaginatorTestSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *PaginatorTestSuite) initUpload(c *C, object string) string {
+	result, err := s.nosClient.InitMultiUpload(&model.InitMultiUploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: object,
+	})
+	c.Assert(err, IsNil)
+	return result.UploadId
+}
+
+func (s *PaginatorTestSuite) TestObjectsPaginator(c *C) {
+	for _, object := range []string{"a", "b", "c", "d", "dir/e", "dir/f", "g"} {
+		putTestObject(c, s.nosClient, TEST_BUCKET, object, []byte("data"))
+	}
+
+	request := &model.ListObjectsRequest{Bucket: TEST_BUCKET, MaxKeys: 2}
+	paginator := s.nosClient.NewObjectsPaginator(context.Background(), request)
+	var listed []string
+	pages := 0
+	for paginator.Next() {
+		pages++
+		c.Assert(len(paginator.Page().Contents) <= 2, Equals, true)
+		for _, contents := range paginator.Page().Contents {
+			listed = append(listed, contents.Key)
+		}
+	}
+	c.Assert(paginator.Err(), IsNil)
+	c.Assert(pages, Equals, 4)
+	c.Assert(strings.Join(listed, ","), Equals, "a,b,c,d,dir/e,dir/f,g")
+
+	// The caller's request is left as it was.
+	c.Assert(request.Marker, Equals, "")
+
+	request = &model.ListObjectsRequest{Bucket: TEST_BUCKET, Delimiter: "/", Marker: "b", MaxKeys: 2}
+	paginator = s.nosClient.NewObjectsPaginator(context.Background(), request)
+	listed = nil
+	for paginator.Next() {
+		for _, contents := range paginator.Page().Contents {
+			listed = append(listed, contents.Key)
+		}
+		for _, prefix := range paginator.Page().CommonPrefixes {
+			listed = append(listed, prefix.Prefix)
+		}
+	}
+	c.Assert(paginator.Err(), IsNil)
+	sort.Strings(listed)
+	c.Assert(strings.Join(listed, ","), Equals, "c,d,dir/,g")
+}
+
+func (s *PaginatorTestSuite) TestMultiUploadsPaginator(c *C) {
+	// Several uploads of one key force pages to end within the key, which
+	// only the upload id marker can continue from.
+	expected := map[string]bool{}
+	for _, object := range []string{"a", "b", "b", "b", "c"} {
+		expected[object+"/"+s.initUpload(c, object)] = true
+	}
+
+	paginator := s.nosClient.NewMultiUploadsPaginator(context.Background(), &model.ListMultiUploadsRequest{
+		Bucket:     TEST_BUCKET,
+		MaxUploads: 2,
+	})
+	listed := map[string]bool{}
+	pages := 0
+	for paginator.Next() {
+		pages++
+		for _, upload := range paginator.Page().Uploads {
+			key := upload.Key + "/" + upload.UploadId
+			c.Assert(listed[key], Equals, false)
+			listed[key] = true
+		}
+	}
+	c.Assert(paginator.Err(), IsNil)
+	c.Assert(pages, Equals, 3)
+	c.Assert(listed, DeepEquals, expected)
+}
+
+func (s *PaginatorTestSuite) TestUploadPartsPaginator(c *C) {
+	uploadId := s.initUpload(c, "object")
+	for number := 1; number <= 5; number++ {
+		_, err := s.nosClient.UploadPart(&model.UploadPartRequest{
+			Bucket:     TEST_BUCKET,
+			Object:     "object",
+			UploadId:   uploadId,
+			PartNumber: number,
+			Content:    bytes.Repeat([]byte{byte(number)}, number),
+			PartSize:   int64(number),
+		})
+		c.Assert(err, IsNil)
+	}
+
+	paginator := s.nosClient.NewUploadPartsPaginator(context.Background(), &model.ListUploadPartsRequest{
+		Bucket:           TEST_BUCKET,
+		Object:           "object",
+		UploadId:         uploadId,
+		MaxParts:         2,
+		PartNumberMarker: 1,
+	})
+	var listed []string
+	for paginator.Next() {
+		for _, part := range paginator.Page().Parts {
+			listed = append(listed, fmt.Sprintf("%d:%d", part.PartNumber, part.Size))
+		}
+	}
+	c.Assert(paginator.Err(), IsNil)
+	c.Assert(strings.Join(listed, ","), Equals, "2:2,3:3,4:4,5:5")
+}
+
+func (s *PaginatorTestSuite) TestPaginatorErrors(c *C) {
+	paginator := s.nosClient.NewObjectsPaginator(context.Background(), &model.ListObjectsRequest{
+		Bucket: BUCKETNOTEXIST,
+	})
+	c.Assert(paginator.Next(), Equals, false)
+	c.Assert(paginator.Err(), ErrorMatches, ".*NoSuchBucket.*")
+	c.Assert(paginator.Next(), Equals, false)
+
+	paginator = s.nosClient.NewObjectsPaginator(context.Background(), nil)
+	c.Assert(paginator.Next(), Equals, false)
+	c.Assert(paginator.Err(), ErrorMatches, "StatusCode = 434, .*")
+
+	// Cancelling the context stops the paginator between pages.
+	for _, object := range []string{"a", "b", "c"} {
+		putTestObject(c, s.nosClient, TEST_BUCKET, object, []byte("data"))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	paginator = s.nosClient.NewObjectsPaginator(ctx, &model.ListObjectsRequest{Bucket: TEST_BUCKET, MaxKeys: 1})
+	c.Assert(paginator.Next(), Equals, true)
+	c.Assert(paginator.Page().Contents[0].Key, Equals, "a")
+	cancel()
+	c.Assert(paginator.Next(), Equals, false)
+	c.Assert(paginator.Err(), Equals, context.Canceled)
+}
+
+// truncatedServer serves listings that are truncated but leave out their
+// next markers: objects "a" and "b", then "c" after the marker "b", and a
+// page of uploads.
+func truncatedServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		_, uploads := query["uploads"]
+		w.Header().Set("Content-Type", "application/xml")
+		switch {
+		case query.Get("marker") == "missing":
+			fmt.Fprint(w, `<ListBucketResult><IsTruncated>true</IsTruncated></ListBucketResult>`)
+		case query.Get("marker") == "b":
+			fmt.Fprint(w, `<ListBucketResult><IsTruncated>false</IsTruncated>`+
+				`<Contents><Key>c</Key></Contents></ListBucketResult>`)
+		case uploads:
+			fmt.Fprint(w, `<ListMultipartUploadsResult><IsTruncated>true</IsTruncated>`+
+				`<Upload><Key>a</Key><UploadId>1</UploadId></Upload></ListMultipartUploadsResult>`)
+		default:
+			fmt.Fprint(w, `<ListBucketResult><IsTruncated>true</IsTruncated>`+
+				`<Contents><Key>a</Key></Contents><Contents><Key>b</Key></Contents></ListBucketResult>`)
+		}
+	}))
+}
+
+func (s *PaginatorTestSuite) TestTruncatedWithoutMarker(c *C) {
+	server := truncatedServer()
+	defer server.Close()
+	client := newRetryTestClient(c, server.URL, 1)
+
+	// The listing of objects goes on after the last key.
+	paginator := client.NewObjectsPaginator(context.Background(), &model.ListObjectsRequest{Bucket: "bucket"})
+	var listed []string
+	for paginator.Next() {
+		for _, contents := range paginator.Page().Contents {
+			listed = append(listed, contents.Key)
+		}
+	}
+	c.Assert(paginator.Err(), IsNil)
+	c.Assert(strings.Join(listed, ","), Equals, "a,b,c")
+
+	// A truncated page with nothing to go on from stops the paginator with
+	// an error, rather than passing for the end of the listing.
+	paginator = client.NewObjectsPaginator(context.Background(), &model.ListObjectsRequest{
+		Bucket: "bucket",
+		Marker: "missing",
+	})
+	c.Assert(paginator.Next(), Equals, true)
+	c.Assert(paginator.Next(), Equals, false)
+	c.Assert(paginator.Err(), ErrorMatches, "StatusCode = 437, .*truncated but has no NextMarker")
+
+	uploads := client.NewMultiUploadsPaginator(context.Background(), &model.ListMultiUploadsRequest{Bucket: "bucket"})
+	c.Assert(uploads.Next(), Equals, true)
+	c.Assert(uploads.Page().Uploads, HasLen, 1)
+	c.Assert(uploads.Next(), Equals, false)
+	c.Assert(uploads.Err(), ErrorMatches, "StatusCode = 437, .*truncated but has no NextKeyMarker")
+}
@@ -0,0 +1,140 @@
+package nosclient
+
+import (
+	"context"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/auth"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/logger"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PresignGetObject returns a URL that downloads the object without further
+// credentials until the request's Expires duration has elapsed. URLs signed
+// with temporary credentials expire with the credentials at the latest.
+func (client *NosClient) PresignGetObject(presignRequest *model.PresignRequest) (string, error) {
+	return client.PresignGetObjectWithContext(context.Background(), presignRequest)
+}
+
+// PresignGetObjectWithContext is like PresignGetObject but carries ctx, which
+// cancels the retrieval of the credentials when it is done.
+func (client *NosClient) PresignGetObjectWithContext(ctx context.Context, presignRequest *model.PresignRequest) (
+	string, error) {
+	return client.presign(ctx, "GET", presignRequest)
+}
+
+// PresignPutObject returns a URL that uploads the object with a plain PUT.
+func (client *NosClient) PresignPutObject(presignRequest *model.PresignRequest) (string, error) {
+	return client.PresignPutObjectWithContext(context.Background(), presignRequest)
+}
+
+// PresignPutObjectWithContext is like PresignPutObject but carries ctx, which
+// cancels the retrieval of the credentials when it is done.
+func (client *NosClient) PresignPutObjectWithContext(ctx context.Context, presignRequest *model.PresignRequest) (
+	string, error) {
+	return client.presign(ctx, "PUT", presignRequest)
+}
+
+// PresignHeadObject returns a URL that reads the object's metadata.
+func (client *NosClient) PresignHeadObject(presignRequest *model.PresignRequest) (string, error) {
+	return client.PresignHeadObjectWithContext(context.Background(), presignRequest)
+}
+
+// PresignHeadObjectWithContext is like PresignHeadObject but carries ctx,
+// which cancels the retrieval of the credentials when it is done.
+func (client *NosClient) PresignHeadObjectWithContext(ctx context.Context, presignRequest *model.PresignRequest) (
+	string, error) {
+	return client.presign(ctx, "HEAD", presignRequest)
+}
+
+func (client *NosClient) presign(ctx context.Context, method string, presignRequest *model.PresignRequest) (
+	string, error) {
+
+	if presignRequest == nil {
+		return "", utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	bucket := presignRequest.Bucket
+	object := presignRequest.Object
+
+	err := utils.VerifyParamsWithObject(bucket, object)
+	if err != nil {
+		return "", err
+	}
+
+	if presignRequest.Expires <= 0 {
+		return "", utils.ProcessClientError(noserror.ERROR_CODE_EXPIRES_INVALID, bucket, object, "")
+	}
+
+	params := map[string]string{}
+	overrides := map[string]string{
+		nosconst.RESPONSE_CONTENT_TYPE:        presignRequest.ResponseContentType,
+		nosconst.RESPONSE_CONTENT_DISPOSITION: presignRequest.ResponseContentDisposition,
+		nosconst.RESPONSE_CONTENT_ENCODING:    presignRequest.ResponseContentEncoding,
+		nosconst.RESPONSE_CONTENT_LANGUAGE:    presignRequest.ResponseContentLanguage,
+		nosconst.RESPONSE_CACHE_CONTROL:       presignRequest.ResponseCacheControl,
+		nosconst.RESPONSE_EXPIRES:             presignRequest.ResponseExpires,
+	}
+	for key, value := range overrides {
+		if value != "" {
+			params[key] = value
+		}
+	}
+
+	urlStr, opaque, encodedObject := client.getNosUrl(bucket, object, params)
+	if client.credentials == nil {
+		return urlStr, nil
+	}
+	creds, err := client.credentials.Retrieve(ctx)
+	if err != nil {
+		return "", err
+	}
+	if creds.Expired() {
+		return "", utils.ProcessClientError(noserror.ERROR_CODE_CREDENTIALS_ERROR, bucket, object,
+			"credentials expired at "+creds.Expires.Format(time.RFC3339))
+	}
+
+	request, err := http.NewRequest(method, urlStr, nil)
+	if err != nil {
+		return "", err
+	}
+	if presignRequest.ContentType != "" {
+		request.Header.Set(nosconst.CONTENT_TYPE, presignRequest.ContentType)
+	}
+	if presignRequest.ContentMd5 != "" {
+		request.Header.Set(nosconst.CONTENT_MD5, presignRequest.ContentMd5)
+	}
+
+	// The security token is signed as the header it is sent as otherwise.
+	if creds.SecurityToken != "" {
+		request.Header.Set(nosconst.X_NOS_SECURITY_TOKEN, creds.SecurityToken)
+	}
+
+	// The URL cannot outlive the credentials it is signed with.
+	expiry := time.Now().Add(presignRequest.Expires)
+	if !creds.Expires.IsZero() && creds.Expires.Before(expiry) {
+		expiry = creds.Expires
+	}
+	expires := expiry.Unix()
+	signature := auth.PresignRequest(request, creds.SecretKey, bucket, encodedObject, expires)
+
+	v := url.Values{}
+	for key, val := range params {
+		v.Add(key, val)
+	}
+	v.Add(nosconst.PRESIGN_ACCESS_KEY_ID, creds.AccessKey)
+	v.Add(nosconst.PRESIGN_EXPIRES, strconv.FormatInt(expires, 10))
+	v.Add(nosconst.PRESIGN_SIGNATURE, signature)
+	if creds.SecurityToken != "" {
+		v.Add(nosconst.PRESIGN_SECURITY_TOKEN, creds.SecurityToken)
+	}
+
+	client.Log.DebugWith(logger.LogDebugWithSigning, "presign", method, opaque, "expires", expires)
+
+	return opaque + "?" + v.Encode(), nil
+}
@@ -0,0 +1,155 @@
+package nosclient
+
+import (
+	"context"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/auth"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/credentials"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PresignTestSuite struct{}
+
+var _ = Suite(&PresignTestSuite{})
+
+// presignHandler checks the query string signature the same way the server does.
+func presignHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.SplitN(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/", 2)
+		query := r.URL.Query()
+		expires, _ := strconv.ParseInt(query.Get("Expires"), 10, 64)
+
+		signature := auth.PresignRequest(r, "secret", path[0], path[1], expires)
+		if query.Get("NOSAccessKeyId") != "access" || query.Get("Signature") != signature {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if contentType := query.Get("response-content-type"); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+	}
+}
+
+func (s *PresignTestSuite) TestPresignGetObject(c *C) {
+	server := httptest.NewServer(presignHandler())
+	defer server.Close()
+
+	conf := &config.Config{
+		Endpoint:  server.URL,
+		AccessKey: "access",
+		SecretKey: "secret",
+	}
+	conf.SetIsSubDomain(false)
+	client, err := New(conf)
+	c.Assert(err, IsNil)
+
+	urlStr, err := client.PresignGetObject(&model.PresignRequest{
+		Bucket:              "bucket",
+		Object:              "dir/特殊 object",
+		Expires:             time.Minute,
+		ResponseContentType: "application/octet-stream",
+	})
+	c.Assert(err, IsNil)
+
+	u, err := url.Parse(urlStr)
+	c.Assert(err, IsNil)
+	c.Assert(u.EscapedPath(), Equals, "/bucket/dir%2F%E7%89%B9%E6%AE%8A%20object")
+	expires, err := strconv.ParseInt(u.Query().Get("Expires"), 10, 64)
+	c.Assert(err, IsNil)
+	c.Assert(expires > time.Now().Unix(), Equals, true)
+
+	resp, err := http.Get(urlStr)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("Content-Type"), Equals, "application/octet-stream")
+
+	// tampering with a signed override must invalidate the URL
+	resp, err = http.Get(strings.Replace(urlStr, "octet-stream", "json", 1))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusForbidden)
+}
+
+func (s *PresignTestSuite) TestPresignPutObject(c *C) {
+	server := httptest.NewServer(presignHandler())
+	defer server.Close()
+
+	conf := &config.Config{
+		Endpoint:  server.URL,
+		AccessKey: "access",
+		SecretKey: "secret",
+	}
+	conf.SetIsSubDomain(false)
+	client, err := New(conf)
+	c.Assert(err, IsNil)
+
+	urlStr, err := client.PresignPutObject(&model.PresignRequest{
+		Bucket:      "bucket",
+		Object:      "object",
+		Expires:     time.Minute,
+		ContentType: "text/plain",
+	})
+	c.Assert(err, IsNil)
+
+	request, _ := http.NewRequest("PUT", urlStr, strings.NewReader("content"))
+	request.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(request)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	request, _ = http.NewRequest("PUT", urlStr, strings.NewReader("content"))
+	request.Header.Set("Content-Type", "text/html")
+	resp, err = http.DefaultClient.Do(request)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusForbidden)
+}
+
+func (s *PresignTestSuite) TestPresignError(c *C) {
+	conf := &config.Config{
+		Endpoint:  "nos-eastchina1.126.net",
+		AccessKey: "access",
+		SecretKey: "secret",
+	}
+	client, err := New(conf)
+	c.Assert(err, IsNil)
+
+	_, err = client.PresignGetObject(&model.PresignRequest{Bucket: "bucket", Object: "object"})
+	c.Assert(err.Error(), Equals, "StatusCode = 442, Resource = /bucket/object, Message = InvalidExpires: the expiry should be positive")
+
+	_, err = client.PresignGetObject(nil)
+	c.Assert(err.Error(), Equals, "StatusCode = 434, Resource = , Message = Request is nil")
+}
+
+func (s *PresignTestSuite) TestPresignTemporaryCredentials(c *C) {
+	provider := &rotatingProvider{}
+	expiry := time.Now().Add(10 * time.Minute)
+	provider.set(credentials.Credentials{AccessKey: "access", SecretKey: "secret", Expires: expiry}, nil)
+	client, err := New(&config.Config{Endpoint: "nos-eastchina1.126.net", Credentials: provider})
+	c.Assert(err, IsNil)
+
+	// URLs expire with the credentials they are signed with.
+	urlStr, err := client.PresignGetObjectWithContext(context.Background(), &model.PresignRequest{
+		Bucket:  "bucket",
+		Object:  "object",
+		Expires: time.Hour,
+	})
+	c.Assert(err, IsNil)
+	u, err := url.Parse(urlStr)
+	c.Assert(err, IsNil)
+	c.Assert(u.Query().Get("Expires"), Equals, strconv.FormatInt(expiry.Unix(), 10))
+
+	provider.set(credentials.Credentials{AccessKey: "access", SecretKey: "secret",
+		Expires: time.Now().Add(-time.Second)}, nil)
+	_, err = client.PresignGetObject(&model.PresignRequest{Bucket: "bucket", Object: "object", Expires: time.Hour})
+	c.Assert(err, ErrorMatches, "StatusCode = 451, .*credentials expired at .*")
+}
@@ -0,0 +1,105 @@
+package nosclient
+
+import (
+	"context"
+	"errors"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/logger"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// doRequest builds, signs and sends a request, retrying it according to the
+// client's retry policy. Every attempt is rebuilt through getNosRequest so it
+// carries a fresh Date and signature; seekable bodies are rewound to the
+// position they had on entry, while other bodies can only be sent once. POST
+// requests are not idempotent, and are only retried if RetryNonIdempotent is
+// set.
+func (client *NosClient) doRequest(ctx context.Context, method, bucket, object string,
+	metadata *model.ObjectMetadata, body io.Reader, params map[string]string,
+	bodyStyle string) (*http.Response, error) {
+
+	var seeker io.Seeker
+	var bodyStart int64
+	if s, ok := body.(io.Seeker); ok {
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err == nil {
+			seeker = s
+			bodyStart = offset
+		}
+	}
+
+	// The transport closes request bodies once they are sent, which would
+	// prevent a later attempt from re-reading something like an *os.File.
+	if _, ok := body.(io.Closer); ok && seeker != nil {
+		body = ioutil.NopCloser(body)
+	}
+
+	maxAttempts := 1
+	if client.retry != nil && (body == nil || seeker != nil) &&
+		(method != "POST" || client.retry.RetryNonIdempotent) {
+		maxAttempts = client.retry.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && seeker != nil {
+			if _, err := seeker.Seek(bodyStart, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+
+		// The start of the attempt is recorded for the duration of its error.
+		request, err := client.getNosRequest(utils.WithRequestStart(ctx, time.Now()), method, bucket, object,
+			metadata, body, params, bodyStyle)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.httpClient.Do(request)
+		if attempt >= maxAttempts || !client.shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := client.retry.Delay(attempt, rand.Float64())
+		if err != nil {
+			client.Log.DebugWith(logger.LogDebugWithRequestRetries, "retrying", method, request.URL.Opaque,
+				"attempt", attempt, "of", maxAttempts, "after", delay, "err=", err)
+		} else {
+			client.Log.DebugWith(logger.LogDebugWithRequestRetries, "retrying", method, request.URL.Opaque,
+				"attempt", attempt, "of", maxAttempts, "after", delay, "resp.StatusCode=", resp.StatusCode)
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// shouldRetry classifies a failed attempt with noserror.ShouldRetry, the
+// classifier of noserror.IsRetryable, under the client's retry policy. An
+// attempt that reached a deadline while ctx, the caller's context, did not
+// timed out on the transport's RequestTimeout, and is retried as a timeout.
+func (client *NosClient) shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return client.retry.IsRetryableClass(noserror.RETRY_ERROR_TIMEOUT)
+	}
+	if err != nil {
+		return noserror.ShouldRetry(client.retry, 0, err)
+	}
+	return noserror.ShouldRetry(client.retry, resp.StatusCode, nil)
+}
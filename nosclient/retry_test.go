@@ -0,0 +1,142 @@
+package nosclient
+
+import (
+	"context"
+	"errors"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/httpclient"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type RetryTestSuite struct{}
+
+var _ = Suite(&RetryTestSuite{})
+
+func newRetryTestClient(c *C, endpoint string, maxAttempts int) *NosClient {
+	conf := &config.Config{
+		Endpoint:  strings.TrimPrefix(endpoint, "http://"),
+		AccessKey: "12345",
+		SecretKey: "12345",
+		Retry: &config.RetryConfig{
+			MaxAttempts: maxAttempts,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    5 * time.Millisecond,
+		},
+	}
+	conf.SetIsSubDomain(false)
+
+	client, err := New(conf)
+	c.Assert(err, IsNil)
+	return client
+}
+
+func (s *RetryTestSuite) TestRetryServerError(c *C) {
+	var calls int32
+	var bodies []string
+	var auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		auths = append(auths, r.Header.Get("Authorization"))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("ETag", "\"etag\"")
+	}))
+	defer server.Close()
+
+	client := newRetryTestClient(c, server.URL, 3)
+	result, err := client.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: "bucket",
+		Object: "object",
+		Body:   strings.NewReader("content"),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Etag, Equals, "etag")
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(3))
+	c.Assert(bodies, DeepEquals, []string{"content", "content", "content"})
+	for _, authorization := range auths {
+		c.Assert(strings.HasPrefix(authorization, "NOS 12345:"), Equals, true)
+	}
+}
+
+func (s *RetryTestSuite) TestRetryExhausted(c *C) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newRetryTestClient(c, server.URL, 2)
+	err := client.DeleteObject(&model.ObjectRequest{Bucket: "bucket", Object: "object"})
+	c.Assert(err, NotNil)
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(2))
+}
+
+func (s *RetryTestSuite) TestNoRetryClientError(c *C) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := newRetryTestClient(c, server.URL, 3)
+	err := client.DeleteObject(&model.ObjectRequest{Bucket: "bucket", Object: "object"})
+	c.Assert(err, NotNil)
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(1))
+}
+
+func (s *RetryTestSuite) TestNoRetryPost(c *C) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newRetryTestClient(c, server.URL, 3)
+	_, err := client.InitMultiUpload(&model.InitMultiUploadRequest{Bucket: "bucket", Object: "object"})
+	c.Assert(err, NotNil)
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(1))
+
+	client.retry.RetryNonIdempotent = true
+	_, err = client.InitMultiUpload(&model.InitMultiUploadRequest{Bucket: "bucket", Object: "object"})
+	c.Assert(err, NotNil)
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(4))
+}
+
+func (s *RetryTestSuite) TestRetryDeadline(c *C) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 || r.URL.Path == "/bucket/slow" {
+			<-r.Context().Done()
+			return
+		}
+	}))
+	defer server.Close()
+
+	// An attempt that outlasts the transport's RequestTimeout is retried.
+	client := newRetryTestClient(c, server.URL, 3)
+	client.httpClient.Transport.(*httpclient.Transport).RequestTimeout = 50 * time.Millisecond
+	err := client.DeleteObject(&model.ObjectRequest{Bucket: "bucket", Object: "object"})
+	c.Assert(err, IsNil)
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(2))
+
+	// The deadline of the caller's context ends the operation.
+	client = newRetryTestClient(c, server.URL, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = client.DeleteObjectWithContext(ctx, &model.ObjectRequest{Bucket: "bucket", Object: "slow"})
+	c.Assert(errors.Is(err, context.DeadlineExceeded), Equals, true, Commentf("%v", err))
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(3))
+}
@@ -0,0 +1,83 @@
+package nosclient
+
+import (
+	"bytes"
+	"context"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"io"
+)
+
+// PutObjectByReader uploads a body of unknown length. The first part of the
+// body is buffered: if the body ends within it, the object is sent with a
+// single PUT, and otherwise as a multipart upload, which is aborted if it
+// fails.
+func (client *NosClient) PutObjectByReader(putObjectRequest *model.PutObjectByReaderRequest) (
+	*model.ObjectResult, error) {
+	return client.PutObjectByReaderWithContext(context.Background(), putObjectRequest)
+}
+
+// PutObjectByReaderWithContext is like PutObjectByReader but carries ctx, which cancels the
+// upload when it is done.
+func (client *NosClient) PutObjectByReaderWithContext(ctx context.Context,
+	putObjectRequest *model.PutObjectByReaderRequest) (*model.ObjectResult, error) {
+
+	if putObjectRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	bucket := putObjectRequest.Bucket
+	object := putObjectRequest.Object
+
+	err := utils.VerifyParamsWithObject(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	if putObjectRequest.Body == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, object, "")
+	}
+
+	uploader := NewUploader(client)
+	uploader.PartSize = putObjectRequest.PartSize
+	if putObjectRequest.Concurrency > 0 {
+		uploader.Concurrency = putObjectRequest.Concurrency
+	}
+	partSize, err := uploader.partSize(0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reading one byte more than a part tells whether the body ends within
+	// the first part.
+	first := make([]byte, partSize+1)
+	n, err := io.ReadFull(putObjectRequest.Body, first)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		metadata := &model.ObjectMetadata{ContentLength: int64(n)}
+		if putObjectRequest.Metadata != nil {
+			metadata.Metadata = putObjectRequest.Metadata.Metadata
+		}
+		return client.PutObjectByStreamWithContext(ctx, &model.PutObjectRequest{
+			Bucket:   bucket,
+			Object:   object,
+			Body:     bytes.NewReader(first[:n]),
+			Metadata: metadata,
+			Acl:      putObjectRequest.Acl,
+		})
+	}
+	if err != nil {
+		return nil, utils.WrapClientError(noserror.ERROR_CODE_READCONTENT_ERROR, bucket, object, err)
+	}
+
+	result, err := uploader.UploadWithContext(ctx, &model.UploadRequest{
+		Bucket:   bucket,
+		Object:   object,
+		Body:     io.MultiReader(bytes.NewReader(first), putObjectRequest.Body),
+		Metadata: putObjectRequest.Metadata,
+		Acl:      putObjectRequest.Acl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.ObjectResult{Etag: result.Etag}, nil
+}
@@ -0,0 +1,518 @@
+package nosclient
+
+import (
+	"context"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syncer mirrors a local directory to a bucket prefix, or a bucket prefix to
+// a local directory, transferring only the files that differ.
+type Syncer struct {
+	// Concurrency is the number of files transferred at the same time.
+	// Files larger than DEFAULT_PARTSIZE are themselves transferred in
+	// parts by an Uploader or a Downloader.
+	Concurrency int
+
+	client *NosClient
+}
+
+func NewSyncer(client *NosClient) *Syncer {
+	return &Syncer{
+		Concurrency: nosconst.DEFAULT_CONCURRENCY,
+		client:      client,
+	}
+}
+
+// syncEntry is a path below the synced directory and prefix, with the file
+// and the object found there, if any.
+type syncEntry struct {
+	key  string
+	path string
+
+	file   *syncFile
+	object *syncObject
+}
+
+type syncFile struct {
+	size    int64
+	modTime time.Time
+}
+
+type syncObject struct {
+	size         int64
+	etag         string
+	lastModified time.Time
+}
+
+// Upload makes the objects below syncRequest.Prefix match the files below
+// syncRequest.LocalDir. On error, the result lists what was done before it.
+func (syncer *Syncer) Upload(syncRequest *model.SyncRequest) (*model.SyncResult, error) {
+	return syncer.UploadWithContext(context.Background(), syncRequest)
+}
+
+// UploadWithContext is like Upload but carries ctx, which cancels the sync
+// when it is done.
+func (syncer *Syncer) UploadWithContext(ctx context.Context, syncRequest *model.SyncRequest) (
+	*model.SyncResult, error) {
+	return syncer.sync(ctx, syncRequest, true)
+}
+
+// Download makes the files below syncRequest.LocalDir match the objects
+// below syncRequest.Prefix, creating the directory if needed. Downloaded
+// files take the modification time of their object. On error, the result
+// lists what was done before it.
+func (syncer *Syncer) Download(syncRequest *model.SyncRequest) (*model.SyncResult, error) {
+	return syncer.DownloadWithContext(context.Background(), syncRequest)
+}
+
+// DownloadWithContext is like Download but carries ctx, which cancels the
+// sync when it is done.
+func (syncer *Syncer) DownloadWithContext(ctx context.Context, syncRequest *model.SyncRequest) (
+	*model.SyncResult, error) {
+	return syncer.sync(ctx, syncRequest, false)
+}
+
+func (syncer *Syncer) sync(ctx context.Context, syncRequest *model.SyncRequest, upload bool) (
+	*model.SyncResult, error) {
+
+	if syncRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	bucket := syncRequest.Bucket
+	err := utils.VerifyParams(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if syncRequest.LocalDir == "" {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, "", "LocalDir is required")
+	}
+
+	filter, err := newSyncFilter(bucket, syncRequest.Include, syncRequest.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := syncer.collect(ctx, syncRequest, filter, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &model.SyncResult{}
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+		deletes  []*syncEntry
+	)
+
+	concurrency := syncer.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	work := make(chan *syncEntry)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range work {
+				action, err := syncer.update(ctx, syncRequest, entry, upload)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else if action != nil {
+					result.Actions = append(result.Actions, *action)
+				} else {
+					result.Unchanged++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, entry := range entries {
+		if (upload && entry.file == nil) || (!upload && entry.object == nil) {
+			if syncRequest.Delete {
+				deletes = append(deletes, entry)
+			}
+			continue
+		}
+		select {
+		case work <- entry:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr == nil && len(deletes) > 0 {
+		if upload {
+			firstErr = syncer.deleteObjects(ctx, syncRequest, deletes, result)
+		} else {
+			firstErr = syncer.deleteFiles(syncRequest, deletes, result)
+		}
+	}
+
+	sort.Slice(result.Actions, func(i, j int) bool {
+		return result.Actions[i].Key < result.Actions[j].Key
+	})
+	return result, firstErr
+}
+
+// collect lists the files and objects to sync, ordered by key.
+func (syncer *Syncer) collect(ctx context.Context, syncRequest *model.SyncRequest, filter *syncFilter,
+	upload bool) ([]*syncEntry, error) {
+
+	bucket := syncRequest.Bucket
+	entries := make(map[string]*syncEntry)
+	entry := func(rel string) *syncEntry {
+		e, ok := entries[rel]
+		if !ok {
+			e = &syncEntry{
+				key:  syncRequest.Prefix + rel,
+				path: filepath.Join(syncRequest.LocalDir, filepath.FromSlash(rel)),
+			}
+			entries[rel] = e
+		}
+		return e
+	}
+
+	root := syncRequest.LocalDir
+	_, err := os.Stat(root)
+	if err == nil {
+		err = filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if filter.match(rel) {
+				entry(rel).file = &syncFile{size: info.Size(), modTime: info.ModTime()}
+			}
+			return nil
+		})
+	} else if os.IsNotExist(err) && !upload {
+		err = nil
+	}
+	if err != nil {
+		return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, "", err)
+	}
+
+	paginator := syncer.client.NewObjectsPaginator(ctx, &model.ListObjectsRequest{
+		Bucket:  bucket,
+		Prefix:  syncRequest.Prefix,
+		MaxKeys: nosconst.DEFAULTVALUE,
+	})
+	for paginator.Next() {
+		for _, contents := range paginator.Page().Contents {
+			rel := strings.TrimPrefix(contents.Key, syncRequest.Prefix)
+			// Keys that are not a clean relative path, such as directory
+			// markers, have no file to be synced with.
+			if !utils.IsRelativePath(rel) || !filter.match(rel) {
+				continue
+			}
+			lastModified, _ := time.Parse(time.RFC3339, contents.LastModified)
+			entry(rel).object = &syncObject{
+				size:         contents.Size,
+				etag:         utils.RemoveQuotes(contents.Etag),
+				lastModified: lastModified,
+			}
+		}
+	}
+	if err := paginator.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(entries))
+	for rel := range entries {
+		keys = append(keys, rel)
+	}
+	sort.Strings(keys)
+
+	result := make([]*syncEntry, len(keys))
+	for i, rel := range keys {
+		result[i] = entries[rel]
+	}
+	return result, nil
+}
+
+// update brings the destination of entry up to date with its source. It
+// returns nil if they are already the same.
+func (syncer *Syncer) update(ctx context.Context, syncRequest *model.SyncRequest, entry *syncEntry,
+	upload bool) (*model.SyncAction, error) {
+
+	changed, err := syncer.changed(ctx, syncRequest, entry, upload)
+	if err != nil || !changed {
+		return nil, err
+	}
+
+	action := &model.SyncAction{
+		Key:  entry.key,
+		Path: entry.path,
+	}
+	if upload {
+		action.Op = nosconst.SYNC_UPLOAD
+		action.Size = entry.file.size
+	} else {
+		action.Op = nosconst.SYNC_DOWNLOAD
+		action.Size = entry.object.size
+	}
+	if syncRequest.DryRun {
+		return action, nil
+	}
+
+	if upload {
+		err = syncer.upload(ctx, syncRequest.Bucket, entry)
+	} else {
+		// The listed size of a compressed object is not the size of the file.
+		action.Size, err = syncer.download(ctx, syncRequest.Bucket, entry)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// changed reports whether the file and the object of entry differ. Objects
+// are listed with the size and ETag of their stored content, so an object
+// that differs from the file by them is compared again by its uncompressed
+// length and MD5 if it is compressed. entry then takes the uncompressed
+// length of the object.
+func (syncer *Syncer) changed(ctx context.Context, syncRequest *model.SyncRequest, entry *syncEntry,
+	upload bool) (bool, error) {
+
+	if entry.file == nil || entry.object == nil {
+		return true, nil
+	}
+	changed, err := syncer.differs(syncRequest, entry, upload)
+	if err != nil || !changed {
+		return changed, err
+	}
+
+	metadata, err := syncer.client.GetObjectMetaDataWithContext(ctx, &model.ObjectRequest{
+		Bucket: syncRequest.Bucket,
+		Object: entry.key,
+	})
+	if noserror.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !isCompressed(metadata) || metadata.ContentLength < 0 {
+		return true, nil
+	}
+
+	entry.object = &syncObject{
+		size:         metadata.ContentLength,
+		etag:         metadata.Metadata[nosconst.X_NOS_META_UNCOMPRESSED_MD5],
+		lastModified: entry.object.lastModified,
+	}
+	return syncer.differs(syncRequest, entry, upload)
+}
+
+// differs compares the file and the object of entry by size, then by MD5 if
+// the object's ETag is one, or else by modification time.
+func (syncer *Syncer) differs(syncRequest *model.SyncRequest, entry *syncEntry, upload bool) (bool, error) {
+	file, object := entry.file, entry.object
+	if file.size != object.size {
+		return true, nil
+	}
+
+	if !syncRequest.CompareModTime && md5Etag.MatchString(object.etag) {
+		f, err := os.Open(entry.path)
+		if err != nil {
+			return false, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, syncRequest.Bucket, entry.key, err)
+		}
+		defer f.Close()
+
+		fingerprint, err := getFileFingerprint(f)
+		if err != nil {
+			return false, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, syncRequest.Bucket, entry.key, err)
+		}
+		return !strings.EqualFold(fingerprint.md5, object.etag), nil
+	}
+
+	if upload {
+		return file.modTime.After(object.lastModified), nil
+	}
+	return object.lastModified.After(file.modTime), nil
+}
+
+func (syncer *Syncer) upload(ctx context.Context, bucket string, entry *syncEntry) error {
+	if entry.file.size <= nosconst.DEFAULT_PARTSIZE {
+		_, err := syncer.client.PutObjectByFileWithContext(ctx, &model.PutObjectRequest{
+			Bucket:   bucket,
+			Object:   entry.key,
+			FilePath: entry.path,
+		})
+		return err
+	}
+
+	_, err := NewUploader(syncer.client).UploadWithContext(ctx, &model.UploadRequest{
+		Bucket:   bucket,
+		Object:   entry.key,
+		FilePath: entry.path,
+	})
+	return err
+}
+
+// download fetches the object of entry into a temporary file next to the
+// destination, which it then replaces, so that an interrupted download never
+// leaves a partial file behind. It returns the size of the file.
+func (syncer *Syncer) download(ctx context.Context, bucket string, entry *syncEntry) (int64, error) {
+	dir := filepath.Dir(entry.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, entry.key, err)
+	}
+
+	temp, err := ioutil.TempFile(dir, "."+filepath.Base(entry.path)+".")
+	if err != nil {
+		return 0, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, entry.key, err)
+	}
+	temp.Close()
+	tempPath := temp.Name()
+
+	metadata, err := NewDownloader(syncer.client).DownloadWithContext(ctx, &model.DownloadRequest{
+		Bucket:   bucket,
+		Object:   entry.key,
+		FilePath: tempPath,
+	})
+	if err != nil {
+		os.Remove(tempPath)
+		return 0, err
+	}
+
+	if !entry.object.lastModified.IsZero() {
+		err = os.Chtimes(tempPath, entry.object.lastModified, entry.object.lastModified)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, entry.path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return 0, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, entry.key, err)
+	}
+	return metadata.ContentLength, nil
+}
+
+// deleteObjects deletes the objects of entries.
+func (syncer *Syncer) deleteObjects(ctx context.Context, syncRequest *model.SyncRequest, entries []*syncEntry,
+	result *model.SyncResult) error {
+
+	if syncRequest.DryRun {
+		for _, entry := range entries {
+			result.Actions = append(result.Actions, deleteAction(entry))
+		}
+		return nil
+	}
+
+	keys := make([]string, len(entries))
+	byKey := make(map[string]*syncEntry, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.key
+		byKey[entry.key] = entry
+	}
+	deleted, err := syncer.client.DeleteObjectsWithContext(ctx, syncRequest.Bucket, keys)
+	for _, key := range deleted {
+		result.Actions = append(result.Actions, deleteAction(byKey[key]))
+	}
+	return err
+}
+
+// deleteFiles deletes the files of entries.
+func (syncer *Syncer) deleteFiles(syncRequest *model.SyncRequest, entries []*syncEntry,
+	result *model.SyncResult) error {
+
+	for _, entry := range entries {
+		if !syncRequest.DryRun {
+			if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+				return utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, syncRequest.Bucket, entry.key, err)
+			}
+		}
+		result.Actions = append(result.Actions, deleteAction(entry))
+	}
+	return nil
+}
+
+func deleteAction(entry *syncEntry) model.SyncAction {
+	action := model.SyncAction{
+		Op:   nosconst.SYNC_DELETE,
+		Key:  entry.key,
+		Path: entry.path,
+	}
+	if entry.file != nil {
+		action.Size = entry.file.size
+	} else {
+		action.Size = entry.object.size
+	}
+	return action
+}
+
+// syncFilter selects the paths synced by their Include and Exclude patterns.
+type syncFilter struct {
+	include []string
+	exclude []string
+}
+
+func newSyncFilter(bucket string, include, exclude []string) (*syncFilter, error) {
+	for _, patterns := range [][]string{include, exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, utils.ProcessClientError(noserror.ERROR_CODE_PATTERN_INVALID, bucket, "", pattern)
+			}
+		}
+	}
+	return &syncFilter{include: include, exclude: exclude}, nil
+}
+
+func (filter *syncFilter) match(rel string) bool {
+	if len(filter.include) > 0 && !matchAny(filter.include, rel) {
+		return false
+	}
+	return !matchAny(filter.exclude, rel)
+}
+
+// matchAny reports whether one of patterns matches rel or, for patterns
+// without a slash, its base name.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
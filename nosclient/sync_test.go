@@ -0,0 +1,241 @@
+package nosclient
+
+import (
+	"errors"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type SyncTestSuite struct {
+	server    *nostest.Server
+	nosClient *NosClient
+	syncer    *Syncer
+}
+
+var _ = Suite(&SyncTestSuite{})
+
+func (s *SyncTestSuite) SetUpTest(c *C) {
+	s.nosClient, s.server = newTestClient(c)
+	s.syncer = NewSyncer(s.nosClient)
+}
+
+func (s *SyncTestSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *SyncTestSuite) writeFiles(c *C, dir string, files map[string]string) {
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		c.Assert(os.MkdirAll(filepath.Dir(file), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(file, []byte(content), 0644), IsNil)
+	}
+}
+
+func (s *SyncTestSuite) getObject(c *C, object string) string {
+	result, err := s.nosClient.GetObject(&model.GetObjectRequest{Bucket: TEST_BUCKET, Object: object})
+	c.Assert(err, IsNil)
+	defer result.Body.Close()
+
+	content, err := ioutil.ReadAll(result.Body)
+	c.Assert(err, IsNil)
+	return string(content)
+}
+
+func (s *SyncTestSuite) listKeys(c *C) string {
+	result, err := s.nosClient.ListObjects(&model.ListObjectsRequest{Bucket: TEST_BUCKET})
+	c.Assert(err, IsNil)
+
+	var keys []string
+	for _, contents := range result.Contents {
+		keys = append(keys, contents.Key)
+	}
+	return strings.Join(keys, ",")
+}
+
+// actions summarizes a result as "op key" lines.
+func actions(result *model.SyncResult) string {
+	var lines []string
+	for _, action := range result.Actions {
+		lines = append(lines, action.Op+" "+action.Key)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *SyncTestSuite) TestUpload(c *C) {
+	dir := c.MkDir()
+	s.writeFiles(c, dir, map[string]string{
+		"index.html":       "index",
+		"static/app.js":    "app",
+		"static/app.js~":   "backup",
+		"static/build.tmp": "tmp",
+	})
+	putTestObject(c, s.nosClient, TEST_BUCKET, "site/stale.html", []byte("stale"))
+	putTestObject(c, s.nosClient, TEST_BUCKET, "other", []byte("untouched"))
+
+	request := &model.SyncRequest{
+		Bucket:   TEST_BUCKET,
+		Prefix:   "site/",
+		LocalDir: dir,
+		Exclude:  []string{"*.tmp", "*~"},
+		Delete:   true,
+		DryRun:   true,
+	}
+	result, err := s.syncer.Upload(request)
+	c.Assert(err, IsNil)
+	c.Assert(actions(result), Equals, "upload site/index.html\ndelete site/stale.html\nupload site/static/app.js")
+	c.Assert(s.listKeys(c), Equals, "other,site/stale.html")
+
+	request.DryRun = false
+	result, err = s.syncer.Upload(request)
+	c.Assert(err, IsNil)
+	c.Assert(actions(result), Equals, "upload site/index.html\ndelete site/stale.html\nupload site/static/app.js")
+	c.Assert(result.Actions[0].Path, Equals, filepath.Join(dir, "index.html"))
+	c.Assert(result.Actions[0].Size, Equals, int64(len("index")))
+	c.Assert(s.listKeys(c), Equals, "other,site/index.html,site/static/app.js")
+	c.Assert(s.getObject(c, "site/static/app.js"), Equals, "app")
+
+	// Only the file that changed is uploaded again, even though both were
+	// touched.
+	s.writeFiles(c, dir, map[string]string{"index.html": "INDEX", "static/app.js": "app"})
+	result, err = s.syncer.Upload(request)
+	c.Assert(err, IsNil)
+	c.Assert(actions(result), Equals, "upload site/index.html")
+	c.Assert(result.Unchanged, Equals, 1)
+	c.Assert(s.getObject(c, "site/index.html"), Equals, "INDEX")
+}
+
+func (s *SyncTestSuite) TestUploadInclude(c *C) {
+	dir := c.MkDir()
+	s.writeFiles(c, dir, map[string]string{
+		"a.html":       "a",
+		"b.css":        "b",
+		"docs/c.html":  "c",
+		"docs/d.txt":   "d",
+		"extra/e.html": "e",
+	})
+	putTestObject(c, s.nosClient, TEST_BUCKET, "notes.txt", []byte("kept"))
+
+	result, err := s.syncer.Upload(&model.SyncRequest{
+		Bucket:   TEST_BUCKET,
+		LocalDir: dir,
+		Include:  []string{"*.html", "docs/*"},
+		Exclude:  []string{"extra/*"},
+		Delete:   true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(actions(result), Equals, "upload a.html\nupload docs/c.html\nupload docs/d.txt")
+	c.Assert(s.listKeys(c), Equals, "a.html,docs/c.html,docs/d.txt,notes.txt")
+}
+
+func (s *SyncTestSuite) TestDownload(c *C) {
+	putTestObject(c, s.nosClient, TEST_BUCKET, "site/index.html", []byte("index"))
+	putTestObject(c, s.nosClient, TEST_BUCKET, "site/static/app.js", []byte("app"))
+	putTestObject(c, s.nosClient, TEST_BUCKET, "site/static/", []byte(""))
+	putTestObject(c, s.nosClient, TEST_BUCKET, "site/../escape", []byte("escape"))
+
+	dir := filepath.Join(c.MkDir(), "site")
+	request := &model.SyncRequest{
+		Bucket:   TEST_BUCKET,
+		Prefix:   "site/",
+		LocalDir: dir,
+		Delete:   true,
+	}
+	result, err := s.syncer.Download(request)
+	c.Assert(err, IsNil)
+	c.Assert(actions(result), Equals, "download site/index.html\ndownload site/static/app.js")
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "static", "app.js"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "app")
+	_, err = os.Stat(filepath.Join(dir, "..", "escape"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	result, err = s.syncer.Download(request)
+	c.Assert(err, IsNil)
+	c.Assert(result.Actions, HasLen, 0)
+	c.Assert(result.Unchanged, Equals, 2)
+
+	s.writeFiles(c, dir, map[string]string{"local.txt": "local"})
+	putTestObject(c, s.nosClient, TEST_BUCKET, "site/index.html", []byte("INDEX"))
+	result, err = s.syncer.Download(request)
+	c.Assert(err, IsNil)
+	c.Assert(actions(result), Equals, "download site/index.html\ndelete site/local.txt")
+	content, err = ioutil.ReadFile(filepath.Join(dir, "index.html"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "INDEX")
+	_, err = os.Stat(filepath.Join(dir, "local.txt"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *SyncTestSuite) TestCompareModTime(c *C) {
+	dir := c.MkDir()
+	s.writeFiles(c, dir, map[string]string{"file": "before"})
+	request := &model.SyncRequest{
+		Bucket:         TEST_BUCKET,
+		LocalDir:       dir,
+		CompareModTime: true,
+	}
+	_, err := s.syncer.Upload(request)
+	c.Assert(err, IsNil)
+
+	// Content of the same size written before the upload goes unnoticed,
+	// content written after it does not.
+	file := filepath.Join(dir, "file")
+	s.writeFiles(c, dir, map[string]string{"file": "BEFORE"})
+	past := time.Now().Add(-time.Hour)
+	c.Assert(os.Chtimes(file, past, past), IsNil)
+	result, err := s.syncer.Upload(request)
+	c.Assert(err, IsNil)
+	c.Assert(result.Actions, HasLen, 0)
+
+	future := time.Now().Add(time.Hour)
+	c.Assert(os.Chtimes(file, future, future), IsNil)
+	result, err = s.syncer.Upload(request)
+	c.Assert(err, IsNil)
+	c.Assert(actions(result), Equals, "upload file")
+	c.Assert(s.getObject(c, "file"), Equals, "BEFORE")
+}
+
+func (s *SyncTestSuite) TestSyncErrors(c *C) {
+	_, err := s.syncer.Upload(nil)
+	c.Assert(err, ErrorMatches, "StatusCode = 434, .*")
+
+	_, err = s.syncer.Upload(&model.SyncRequest{Bucket: TEST_BUCKET})
+	c.Assert(err, ErrorMatches, "StatusCode = 434, .*LocalDir.*")
+
+	_, err = s.syncer.Upload(&model.SyncRequest{
+		Bucket:   TEST_BUCKET,
+		LocalDir: c.MkDir(),
+		Include:  []string{"[a-"},
+	})
+	c.Assert(err, ErrorMatches, "StatusCode = 447, .*")
+
+	_, err = s.syncer.Upload(&model.SyncRequest{
+		Bucket:   TEST_BUCKET,
+		LocalDir: filepath.Join(c.MkDir(), "missing"),
+	})
+	c.Assert(err, ErrorMatches, "StatusCode = 433, .*")
+
+	_, err = s.syncer.Download(&model.SyncRequest{
+		Bucket:   BUCKETNOTEXIST,
+		LocalDir: c.MkDir(),
+	})
+	c.Assert(err, ErrorMatches, ".*NoSuchBucket.*")
+
+	// A file that cannot be read keeps the error of the file system.
+	entry := &syncEntry{
+		key:    "file",
+		path:   filepath.Join(c.MkDir(), "missing"),
+		file:   &syncFile{size: 1},
+		object: &syncObject{size: 1, etag: "d41d8cd98f00b204e9800998ecf8427e"},
+	}
+	_, err = s.syncer.differs(&model.SyncRequest{Bucket: TEST_BUCKET}, entry, true)
+	c.Assert(err, ErrorMatches, "StatusCode = 433, .*")
+	c.Assert(errors.Is(err, os.ErrNotExist), Equals, true)
+}
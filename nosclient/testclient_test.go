@@ -0,0 +1,50 @@
+package nosclient
+
+import (
+	"bytes"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"math/rand"
+)
+
+// newTestClient starts a nostest server with the TEST_BUCKET bucket, and
+// returns a client of it along with the server, which the caller closes.
+// The configure functions adjust the config of the client, such as to send
+// its requests through a proxy of the server.
+func newTestClient(c *C, configure ...func(conf *config.Config)) (*NosClient, *nostest.Server) {
+	server := nostest.NewServer()
+	server.CreateBucket(TEST_BUCKET)
+	return newServerClient(c, server, configure...), server
+}
+
+// newServerClient returns another client of server, configured like those
+// of newTestClient.
+func newServerClient(c *C, server *nostest.Server, configure ...func(conf *config.Config)) *NosClient {
+	conf := server.Config()
+	for _, f := range configure {
+		f(conf)
+	}
+
+	client, err := New(conf)
+	c.Assert(err, IsNil)
+	return client
+}
+
+// putTestObject uploads data as the object key of bucket.
+func putTestObject(c *C, client *NosClient, bucket, key string, data []byte) *model.ObjectResult {
+	result, err := client.PutObjectByStream(&model.PutObjectRequest{
+		Bucket: bucket,
+		Object: key,
+		Body:   bytes.NewReader(data),
+	})
+	c.Assert(err, IsNil)
+	return result
+}
+
+func randomContent(size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(content)
+	return content
+}
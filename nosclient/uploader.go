@@ -0,0 +1,349 @@
+package nosclient
+
+import (
+	"context"
+	"crypto/md5"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Uploader uploads files and streams of any size as multipart uploads,
+// sending several parts at the same time.
+type Uploader struct {
+	// PartSize is the size of every part but the last. If zero, a size
+	// between MIN_FILESIZE and MAX_FILESIZE is chosen from the upload size.
+	PartSize int64
+
+	// Concurrency is the number of parts uploaded at the same time.
+	Concurrency int
+
+	client *NosClient
+}
+
+func NewUploader(client *NosClient) *Uploader {
+	return &Uploader{
+		Concurrency: nosconst.DEFAULT_CONCURRENCY,
+		client:      client,
+	}
+}
+
+// partContent is one part read from the upload source.
+type partContent struct {
+	number int
+	data   []byte
+}
+
+func (uploader *Uploader) Upload(uploadRequest *model.UploadRequest) (*model.CompleteMultiUploadResult, error) {
+	return uploader.UploadWithContext(context.Background(), uploadRequest)
+}
+
+// UploadWithContext is like Upload but carries ctx, which cancels the
+// upload when it is done.
+func (uploader *Uploader) UploadWithContext(ctx context.Context, uploadRequest *model.UploadRequest) (
+	*model.CompleteMultiUploadResult, error) {
+
+	if uploadRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	bucket := uploadRequest.Bucket
+	object := uploadRequest.Object
+
+	err := utils.VerifyParamsWithObject(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	var file *os.File
+	reader := uploadRequest.Body
+	size := uploadRequest.Size
+	if uploadRequest.FilePath != "" {
+		file, err = os.Open(uploadRequest.FilePath)
+		if err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, object, err)
+		}
+		defer file.Close()
+
+		fi, err := file.Stat()
+		if err != nil {
+			return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, object, err)
+		}
+		reader = file
+		size = fi.Size()
+	}
+	if reader == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, object, "")
+	}
+
+	partSize, err := uploader.partSize(size)
+	if err != nil {
+		return nil, err
+	}
+
+	if uploadRequest.CheckpointFile != "" {
+		if file == nil {
+			return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, bucket, object,
+				"CheckpointFile requires FilePath")
+		}
+		return uploader.uploadWithCheckpoint(ctx, uploadRequest, file, partSize)
+	}
+
+	// The metadata is sent before the content is read, so the uncompressed
+	// length and MD5 are only recorded for files, which can be read twice.
+	metadata := uploadRequest.Metadata
+	if codec := uploader.client.compressionCodec(metadata); codec != nil {
+		length, sum := int64(-1), ""
+		if file != nil {
+			fingerprint, err := getFileFingerprint(file)
+			if err != nil {
+				return nil, utils.WrapClientError(noserror.ERROR_CODE_FILE_INVALID, bucket, object, err)
+			}
+			length, sum = fingerprint.size, fingerprint.md5
+		}
+		compressed := compressReader(codec, reader)
+		defer compressed.Close()
+		reader = compressed
+		metadata = compressedMetadata(metadata, codec, length, sum)
+	}
+
+	// The parts are read in order, so the object's MD5 is known once they
+	// have all been read.
+	var objectHash hash.Hash
+	if uploader.client.verifyIntegrity {
+		objectHash = md5.New()
+		reader = io.TeeReader(reader, objectHash)
+	}
+
+	initResult, err := uploader.client.InitMultiUploadWithContext(ctx, &model.InitMultiUploadRequest{
+		Bucket:   bucket,
+		Object:   object,
+		Metadata: metadata,
+		Acl:      uploadRequest.Acl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	uploadId := initResult.UploadId
+
+	parts, err := uploader.uploadParts(ctx, bucket, object, uploadId, func(emit func(partContent) bool) error {
+		return readParts(reader, partSize, 1, emit)
+	}, nil)
+	if err != nil {
+		uploader.abort(bucket, object, uploadId)
+		return nil, err
+	}
+
+	return uploader.complete(ctx, bucket, object, uploadId, parts, hashHex(objectHash))
+}
+
+// partSize returns the part size for an upload of size bytes (0 if unknown).
+func (uploader *Uploader) partSize(size int64) (int64, error) {
+	partSize := uploader.PartSize
+	if partSize == 0 {
+		partSize = nosconst.DEFAULT_PARTSIZE
+		if size > partSize*nosconst.MAX_PARTNUMBER {
+			partSize = (size + nosconst.MAX_PARTNUMBER - 1) / nosconst.MAX_PARTNUMBER
+		}
+	}
+
+	if partSize < nosconst.MIN_FILESIZE || partSize > nosconst.MAX_FILESIZE {
+		return 0, utils.ProcessClientError(noserror.ERROR_CODE_PARTLENGTH_ERROR, "", "", "")
+	}
+
+	if size > partSize*nosconst.MAX_PARTNUMBER {
+		return 0, utils.ProcessClientError(noserror.ERROR_CODE_PARTNUMBER_ERROR, "", "", "")
+	}
+
+	return partSize, nil
+}
+
+// readParts reads reader in parts of partSize bytes, numbered from
+// firstNumber, and passes them to emit until the reader is exhausted or emit
+// returns false. An empty reader still produces one empty part.
+func readParts(reader io.Reader, partSize int64, firstNumber int, emit func(partContent) bool) error {
+	for number := firstNumber; ; number++ {
+		buffer := make([]byte, partSize)
+		n, err := io.ReadFull(reader, buffer)
+		if err == io.EOF && number > firstNumber {
+			return nil
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return utils.WrapClientError(noserror.ERROR_CODE_READCONTENT_ERROR, "", "", err)
+		}
+		if number > nosconst.MAX_PARTNUMBER {
+			return utils.ProcessClientError(noserror.ERROR_CODE_PARTNUMBER_ERROR, "", "", "")
+		}
+
+		if !emit(partContent{number: number, data: buffer[:n]}) || int64(n) < partSize {
+			return nil
+		}
+	}
+}
+
+// readFileParts reads the parts of a file of size bytes, skipping the part
+// numbers in skip, and passes them to emit until emit returns false.
+func readFileParts(file io.ReaderAt, size, partSize int64, skip map[int]model.UploadPart,
+	emit func(partContent) bool) error {
+
+	for number := 1; number <= partCount(size, partSize); number++ {
+		if _, ok := skip[number]; ok {
+			continue
+		}
+
+		offset := int64(number-1) * partSize
+		buffer := make([]byte, expectedPartSize(size, partSize, number))
+		if _, err := file.ReadAt(buffer, offset); err != nil && err != io.EOF {
+			return utils.WrapClientError(noserror.ERROR_CODE_READCONTENT_ERROR, "", "", err)
+		}
+
+		if !emit(partContent{number: number, data: buffer}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// partCount returns the number of parts of an upload of size bytes. An empty
+// upload still has one empty part.
+func partCount(size, partSize int64) int {
+	if size == 0 {
+		return 1
+	}
+	return int((size + partSize - 1) / partSize)
+}
+
+func expectedPartSize(size, partSize int64, number int) int64 {
+	offset := int64(number-1) * partSize
+	if size-offset < partSize {
+		return size - offset
+	}
+	return partSize
+}
+
+// uploadParts uploads the parts produced by produce with a bounded pool of
+// workers. The first failure cancels the remaining work; on success the
+// parts are returned in ascending part number order. If onPart is not nil,
+// it is called, one call at a time, for every part uploaded.
+func (uploader *Uploader) uploadParts(ctx context.Context, bucket, object, uploadId string,
+	produce func(emit func(partContent) bool) error, onPart func(model.UploadPart)) ([]model.UploadPart, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := uploader.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		parts    []model.UploadPart
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	jobs := make(chan partContent)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				result, err := uploader.client.UploadPartWithContext(ctx, &model.UploadPartRequest{
+					Bucket:     bucket,
+					Object:     object,
+					UploadId:   uploadId,
+					PartNumber: job.number,
+					Content:    job.data,
+					PartSize:   int64(len(job.data)),
+				})
+				if err != nil {
+					fail(err)
+					continue
+				}
+
+				part := model.UploadPart{PartNumber: job.number, Etag: result.Etag}
+				mu.Lock()
+				parts = append(parts, part)
+				if onPart != nil {
+					onPart(part)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	err := produce(func(part partContent) bool {
+		select {
+		case jobs <- part:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	close(jobs)
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+// complete completes the upload, aborting it on failure. objectMd5, if not
+// empty, is checked by the server against the completed object.
+func (uploader *Uploader) complete(ctx context.Context, bucket, object, uploadId string,
+	parts []model.UploadPart, objectMd5 string) (*model.CompleteMultiUploadResult, error) {
+
+	result, err := uploader.client.CompleteMultiUploadWithContext(ctx, &model.CompleteMultiUploadRequest{
+		Bucket:    bucket,
+		Object:    object,
+		UploadId:  uploadId,
+		Parts:     parts,
+		ObjectMd5: objectMd5,
+	})
+	if err != nil {
+		uploader.abort(bucket, object, uploadId)
+		return nil, err
+	}
+	return result, nil
+}
+
+// abort cancels a failed upload. It does not use the caller's context, which
+// may be the reason the upload failed.
+func (uploader *Uploader) abort(bucket, object, uploadId string) {
+	err := uploader.client.AbortMultiUpload(&model.AbortMultiUploadRequest{
+		Bucket:   bucket,
+		Object:   object,
+		UploadId: uploadId,
+	})
+	if err != nil {
+		uploader.client.Log.Warn("abort multipart upload", uploadId, "failed:", err)
+	}
+}
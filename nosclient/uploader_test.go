@@ -0,0 +1,195 @@
+package nosclient
+
+import (
+	"bytes"
+	"errors"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+)
+
+type UploaderTestSuite struct {
+	server    *nostest.Server
+	nosClient *NosClient
+}
+
+var _ = Suite(&UploaderTestSuite{})
+
+func (s *UploaderTestSuite) SetUpTest(c *C) {
+	s.nosClient, s.server = newTestClient(c)
+}
+
+func (s *UploaderTestSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *UploaderTestSuite) getContent(c *C, object string) []byte {
+	result, err := s.nosClient.GetObject(&model.GetObjectRequest{Bucket: TEST_BUCKET, Object: object})
+	c.Assert(err, IsNil)
+	defer result.Body.Close()
+
+	content, err := ioutil.ReadAll(result.Body)
+	c.Assert(err, IsNil)
+	return content
+}
+
+func (s *UploaderTestSuite) TestUploadReader(c *C) {
+	content := randomContent(7*nosconst.MIN_FILESIZE + 100)
+
+	uploader := NewUploader(s.nosClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+	uploader.Concurrency = 3
+
+	result, err := uploader.Upload(&model.UploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "uploader/reader",
+		Body:   bytes.NewReader(content),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Key, Equals, "uploader/reader")
+	c.Assert(result.Etag, Matches, "[0-9a-f]{32}-8")
+	c.Assert(s.getContent(c, "uploader/reader"), DeepEquals, content)
+}
+
+func (s *UploaderTestSuite) TestUploadFile(c *C) {
+	content := randomContent(3 * nosconst.MIN_FILESIZE)
+	path := filepath.Join(c.MkDir(), "file")
+	c.Assert(ioutil.WriteFile(path, content, 0644), IsNil)
+
+	uploader := NewUploader(s.nosClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+
+	result, err := uploader.Upload(&model.UploadRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "uploader/file",
+		FilePath: path,
+		Metadata: &model.ObjectMetadata{
+			Metadata: map[string]string{nosconst.CONTENT_TYPE: "text/plain"},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Etag, Matches, ".*-3")
+	c.Assert(s.getContent(c, "uploader/file"), DeepEquals, content)
+
+	metadata, err := s.nosClient.GetObjectMetaData(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: "uploader/file"})
+	c.Assert(err, IsNil)
+	c.Assert(metadata.Metadata[nosconst.CONTENT_TYPE], Equals, "text/plain")
+}
+
+type failingReader struct {
+	reader io.Reader
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err == io.EOF {
+		return n, errors.New("read failed")
+	}
+	return n, err
+}
+
+func (s *UploaderTestSuite) TestUploadAbortOnError(c *C) {
+	uploader := NewUploader(s.nosClient)
+	uploader.PartSize = nosconst.MIN_FILESIZE
+
+	_, err := uploader.Upload(&model.UploadRequest{
+		Bucket: TEST_BUCKET,
+		Object: "uploader/failed",
+		Body:   &failingReader{reader: bytes.NewReader(randomContent(5 * nosconst.MIN_FILESIZE / 2))},
+	})
+	c.Assert(err, NotNil)
+
+	uploads, err := s.nosClient.ListMultiUploads(&model.ListMultiUploadsRequest{Bucket: TEST_BUCKET})
+	c.Assert(err, IsNil)
+	c.Assert(uploads.Uploads, HasLen, 0)
+}
+
+func (s *UploaderTestSuite) TestPartSize(c *C) {
+	uploader := NewUploader(s.nosClient)
+
+	partSize, err := uploader.partSize(0)
+	c.Assert(err, IsNil)
+	c.Assert(partSize, Equals, int64(nosconst.DEFAULT_PARTSIZE))
+
+	partSize, err = uploader.partSize(200 * 1024 * 1024 * 1024)
+	c.Assert(err, IsNil)
+	c.Assert(partSize*nosconst.MAX_PARTNUMBER >= 200*1024*1024*1024, Equals, true)
+
+	uploader.PartSize = 1024
+	_, err = uploader.partSize(0)
+	c.Assert(err.Error(), Equals, "StatusCode = 441, Resource = , Message = InvalidPartLength: the length should be between  16k and 100M")
+
+	uploader.PartSize = nosconst.MIN_FILESIZE
+	_, err = uploader.partSize(nosconst.MIN_FILESIZE*nosconst.MAX_PARTNUMBER + 1)
+	c.Assert(err.Error(), Equals, "StatusCode = 443, Resource = , Message = InvalidPartNumber: an upload has at most 10000 parts")
+}
+
+func (s *UploaderTestSuite) TestPutObjectByReader(c *C) {
+	for _, test := range []struct {
+		object string
+		size   int
+		etag   string
+	}{
+		{"reader/empty", 0, "[0-9a-f]{32}"},
+		{"reader/small", nosconst.MIN_FILESIZE / 2, "[0-9a-f]{32}"},
+		{"reader/exact", nosconst.MIN_FILESIZE, "[0-9a-f]{32}"},
+		{"reader/large", 2*nosconst.MIN_FILESIZE + 1, "[0-9a-f]{32}-3"},
+	} {
+		content := randomContent(test.size)
+		// A pipe hides the length of the content, as a stream would.
+		reader, writer := io.Pipe()
+		go func() {
+			writer.Write(content)
+			writer.Close()
+		}()
+
+		result, err := s.nosClient.PutObjectByReader(&model.PutObjectByReaderRequest{
+			Bucket:   TEST_BUCKET,
+			Object:   test.object,
+			Body:     reader,
+			PartSize: nosconst.MIN_FILESIZE,
+			Metadata: &model.ObjectMetadata{
+				Metadata: map[string]string{nosconst.CONTENT_TYPE: "application/gzip"},
+			},
+		})
+		c.Assert(err, IsNil, Commentf(test.object))
+		c.Assert(result.Etag, Matches, test.etag, Commentf(test.object))
+		c.Assert(s.getContent(c, test.object), DeepEquals, content, Commentf(test.object))
+
+		metadata, err := s.nosClient.GetObjectMetaData(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: test.object})
+		c.Assert(err, IsNil)
+		c.Assert(metadata.Metadata[nosconst.CONTENT_TYPE], Equals, "application/gzip")
+	}
+}
+
+func (s *UploaderTestSuite) TestPutObjectByReaderErrors(c *C) {
+	_, err := s.nosClient.PutObjectByReader(&model.PutObjectByReaderRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "reader/failed",
+		Body:     &failingReader{reader: bytes.NewReader(randomContent(3 * nosconst.MIN_FILESIZE))},
+		PartSize: nosconst.MIN_FILESIZE,
+	})
+	c.Assert(err, ErrorMatches, ".*read failed.*")
+
+	uploads, err := s.nosClient.ListMultiUploads(&model.ListMultiUploadsRequest{Bucket: TEST_BUCKET})
+	c.Assert(err, IsNil)
+	c.Assert(uploads.Uploads, HasLen, 0)
+
+	_, err = s.nosClient.PutObjectByReader(&model.PutObjectByReaderRequest{
+		Bucket: TEST_BUCKET,
+		Object: "reader/nil",
+	})
+	c.Assert(err, ErrorMatches, "StatusCode = 434, .*")
+
+	_, err = s.nosClient.PutObjectByReader(&model.PutObjectByReaderRequest{
+		Bucket:   TEST_BUCKET,
+		Object:   "reader/part",
+		Body:     bytes.NewReader(nil),
+		PartSize: 1,
+	})
+	c.Assert(err, ErrorMatches, "StatusCode = 441, .*")
+}
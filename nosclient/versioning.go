@@ -0,0 +1,165 @@
+package nosclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"net/http"
+	"strconv"
+)
+
+// PutBucketVersioning sets the versioning status of a bucket to
+// nosconst.VERSIONING_ENABLED or nosconst.VERSIONING_SUSPENDED. Once enabled,
+// versioning can be suspended but not turned off.
+func (client *NosClient) PutBucketVersioning(bucketName string, status string) error {
+	return client.PutBucketVersioningWithContext(context.Background(), bucketName, status)
+}
+
+// PutBucketVersioningWithContext is like PutBucketVersioning but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) PutBucketVersioningWithContext(ctx context.Context, bucketName string, status string) error {
+	err := utils.VerifyParams(bucketName)
+	if err != nil {
+		return err
+	}
+
+	if status != nosconst.VERSIONING_ENABLED && status != nosconst.VERSIONING_SUSPENDED {
+		return utils.ProcessClientError(noserror.ERROR_CODE_VERSIONING_INVALID, bucketName, "", status)
+	}
+
+	body, err := xml.Marshal(&model.VersioningConfiguration{Status: status})
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		nosconst.VERSIONING: "",
+	}
+
+	resp, err := client.doRequest(ctx, "PUT", bucketName, "", nil, bytes.NewReader(body), params, nosconst.XML_TYPE)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	} else {
+		err := utils.ProcessServerError(resp, bucketName, "")
+		return err
+	}
+}
+
+// GetBucketVersioning returns the versioning status of a bucket, which is
+// empty if versioning was never enabled.
+func (client *NosClient) GetBucketVersioning(bucketName string) (*model.VersioningConfiguration, error) {
+	return client.GetBucketVersioningWithContext(context.Background(), bucketName)
+}
+
+// GetBucketVersioningWithContext is like GetBucketVersioning but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) GetBucketVersioningWithContext(ctx context.Context, bucketName string) (
+	*model.VersioningConfiguration, error) {
+
+	err := utils.VerifyParams(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		nosconst.VERSIONING: "",
+	}
+
+	resp, err := client.doRequest(ctx, "GET", bucketName, "", nil, nil, params, nosconst.XML_TYPE)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusOK {
+		result := &model.VersioningConfiguration{}
+		err = utils.ParseXmlBody(resp.Body, result)
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	} else {
+		err := utils.ProcessServerError(resp, bucketName, "")
+		return nil, err
+	}
+}
+
+// ListObjectVersions lists the versions and delete markers of the objects in
+// a bucket, ordered by key and, for each key, from the newest version. A
+// truncated result continues from its NextKeyMarker and NextVersionIdMarker.
+func (client *NosClient) ListObjectVersions(listObjectVersionsRequest *model.ListObjectVersionsRequest) (
+	*model.ListVersionsResult, error) {
+	return client.ListObjectVersionsWithContext(context.Background(), listObjectVersionsRequest)
+}
+
+// ListObjectVersionsWithContext is like ListObjectVersions but carries ctx, which cancels the
+// request when it is done.
+func (client *NosClient) ListObjectVersionsWithContext(ctx context.Context,
+	listObjectVersionsRequest *model.ListObjectVersionsRequest) (*model.ListVersionsResult, error) {
+
+	if listObjectVersionsRequest == nil {
+		return nil, utils.ProcessClientError(noserror.ERROR_CODE_REQUEST_ERROR, "", "", "")
+	}
+
+	bucket := listObjectVersionsRequest.Bucket
+	maxKeys := listObjectVersionsRequest.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = 100
+	}
+
+	err := utils.VerifyParams(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		nosconst.VERSIONS:              "",
+		nosconst.LIST_PREFIX:           listObjectVersionsRequest.Prefix,
+		nosconst.LIST_DELIMITER:        listObjectVersionsRequest.Delimiter,
+		nosconst.LIST_KEY_MARKER:       listObjectVersionsRequest.KeyMarker,
+		nosconst.LIST_VERSIONID_MARKER: listObjectVersionsRequest.VersionIdMarker,
+		nosconst.LIST_MAXKEYS:          strconv.Itoa(maxKeys),
+	}
+
+	resp, err := client.doRequest(ctx, "GET", bucket, "", nil, nil, params, nosconst.XML_TYPE)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	client.Log.Debug("resp.StatusCode=", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusOK {
+		result := &model.ListVersionsResult{}
+		err = utils.ParseXmlBody(resp.Body, result)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	} else {
+		err := utils.ProcessServerError(resp, bucket, "")
+		return nil, err
+	}
+}
+
+// versionParams returns the query parameters selecting versionId, or nil
+// for the latest version.
+func versionParams(versionId string) map[string]string {
+	if versionId == "" {
+		return nil
+	}
+	return map[string]string{
+		nosconst.VERSIONID: versionId,
+	}
+}
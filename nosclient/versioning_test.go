@@ -0,0 +1,177 @@
+package nosclient
+
+import (
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nostest"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"strings"
+)
+
+type VersioningTestSuite struct {
+	server    *nostest.Server
+	nosClient *NosClient
+}
+
+var _ = Suite(&VersioningTestSuite{})
+
+func (s *VersioningTestSuite) SetUpTest(c *C) {
+	s.nosClient, s.server = newTestClient(c)
+}
+
+func (s *VersioningTestSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *VersioningTestSuite) getObject(c *C, object, versionId string) string {
+	result, err := s.nosClient.GetObject(&model.GetObjectRequest{
+		Bucket:    TEST_BUCKET,
+		Object:    object,
+		VersionId: versionId,
+	})
+	c.Assert(err, IsNil)
+	defer result.Body.Close()
+
+	content, err := ioutil.ReadAll(result.Body)
+	c.Assert(err, IsNil)
+	return string(content)
+}
+
+func (s *VersioningTestSuite) TestBucketVersioning(c *C) {
+	configuration, err := s.nosClient.GetBucketVersioning(TEST_BUCKET)
+	c.Assert(err, IsNil)
+	c.Assert(configuration.Status, Equals, "")
+
+	err = s.nosClient.PutBucketVersioning(TEST_BUCKET, nosconst.VERSIONING_ENABLED)
+	c.Assert(err, IsNil)
+	configuration, err = s.nosClient.GetBucketVersioning(TEST_BUCKET)
+	c.Assert(err, IsNil)
+	c.Assert(configuration.Status, Equals, nosconst.VERSIONING_ENABLED)
+
+	err = s.nosClient.PutBucketVersioning(TEST_BUCKET, nosconst.VERSIONING_SUSPENDED)
+	c.Assert(err, IsNil)
+	configuration, err = s.nosClient.GetBucketVersioning(TEST_BUCKET)
+	c.Assert(err, IsNil)
+	c.Assert(configuration.Status, Equals, nosconst.VERSIONING_SUSPENDED)
+
+	err = s.nosClient.PutBucketVersioning(TEST_BUCKET, "Disabled")
+	c.Assert(err, ErrorMatches, "StatusCode = 446, .*")
+}
+
+func (s *VersioningTestSuite) TestRecoverOverwrittenObject(c *C) {
+	// An object stored before versioning is enabled becomes the "null" version.
+	result := putTestObject(c, s.nosClient, TEST_BUCKET, "object", []byte("original"))
+	c.Assert(result.VersionId, Equals, "")
+
+	err := s.nosClient.PutBucketVersioning(TEST_BUCKET, nosconst.VERSIONING_ENABLED)
+	c.Assert(err, IsNil)
+
+	first := putTestObject(c, s.nosClient, TEST_BUCKET, "object", []byte("first"))
+	c.Assert(first.VersionId, Not(Equals), "")
+	second := putTestObject(c, s.nosClient, TEST_BUCKET, "object", []byte("second"))
+	c.Assert(second.VersionId, Not(Equals), first.VersionId)
+
+	c.Assert(s.getObject(c, "object", ""), Equals, "second")
+	c.Assert(s.getObject(c, "object", first.VersionId), Equals, "first")
+	c.Assert(s.getObject(c, "object", nosconst.NULL_VERSIONID), Equals, "original")
+
+	metadata, err := s.nosClient.GetObjectMetaData(&model.ObjectRequest{
+		Bucket:    TEST_BUCKET,
+		Object:    "object",
+		VersionId: first.VersionId,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(metadata.ContentLength, Equals, int64(len("first")))
+	c.Assert(metadata.Metadata[nosconst.NOS_VERSION_ID], Equals, first.VersionId)
+
+	// Deleting without a version id only hides the object behind a delete
+	// marker; deleting the marker brings it back.
+	err = s.nosClient.DeleteObject(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: "object"})
+	c.Assert(err, IsNil)
+	exist, err := s.nosClient.DoesObjectExist(&model.ObjectRequest{Bucket: TEST_BUCKET, Object: "object"})
+	c.Assert(err, IsNil)
+	c.Assert(exist, Equals, false)
+
+	versions, err := s.nosClient.ListObjectVersions(&model.ListObjectVersionsRequest{Bucket: TEST_BUCKET})
+	c.Assert(err, IsNil)
+	c.Assert(versions.Versions, HasLen, 3)
+	c.Assert(versions.DeleteMarkers, HasLen, 1)
+	c.Assert(versions.DeleteMarkers[0].IsLatest, Equals, true)
+	c.Assert(versions.Versions[0].VersionId, Equals, second.VersionId)
+	c.Assert(versions.Versions[0].IsLatest, Equals, false)
+	c.Assert(versions.Versions[2].VersionId, Equals, nosconst.NULL_VERSIONID)
+
+	err = s.nosClient.DeleteObject(&model.ObjectRequest{
+		Bucket:    TEST_BUCKET,
+		Object:    "object",
+		VersionId: versions.DeleteMarkers[0].VersionId,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.getObject(c, "object", ""), Equals, "second")
+
+	// Deleting the latest version makes the previous one current.
+	err = s.nosClient.DeleteObject(&model.ObjectRequest{
+		Bucket:    TEST_BUCKET,
+		Object:    "object",
+		VersionId: second.VersionId,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.getObject(c, "object", ""), Equals, "first")
+
+	_, err = s.nosClient.GetObject(&model.GetObjectRequest{
+		Bucket:    TEST_BUCKET,
+		Object:    "object",
+		VersionId: second.VersionId,
+	})
+	c.Assert(err, ErrorMatches, ".*NoSuchVersion.*")
+}
+
+func (s *VersioningTestSuite) TestSuspendedVersioning(c *C) {
+	err := s.nosClient.PutBucketVersioning(TEST_BUCKET, nosconst.VERSIONING_ENABLED)
+	c.Assert(err, IsNil)
+	kept := putTestObject(c, s.nosClient, TEST_BUCKET, "object", []byte("kept"))
+
+	err = s.nosClient.PutBucketVersioning(TEST_BUCKET, nosconst.VERSIONING_SUSPENDED)
+	c.Assert(err, IsNil)
+	result := putTestObject(c, s.nosClient, TEST_BUCKET, "object", []byte("replaced"))
+	c.Assert(result.VersionId, Equals, nosconst.NULL_VERSIONID)
+	result = putTestObject(c, s.nosClient, TEST_BUCKET, "object", []byte("latest"))
+	c.Assert(result.VersionId, Equals, nosconst.NULL_VERSIONID)
+
+	versions, err := s.nosClient.ListObjectVersions(&model.ListObjectVersionsRequest{Bucket: TEST_BUCKET})
+	c.Assert(err, IsNil)
+	c.Assert(versions.Versions, HasLen, 2)
+	c.Assert(s.getObject(c, "object", ""), Equals, "latest")
+	c.Assert(s.getObject(c, "object", kept.VersionId), Equals, "kept")
+}
+
+func (s *VersioningTestSuite) TestListObjectVersionsPages(c *C) {
+	err := s.nosClient.PutBucketVersioning(TEST_BUCKET, nosconst.VERSIONING_ENABLED)
+	c.Assert(err, IsNil)
+
+	for _, object := range []string{"a", "b", "c"} {
+		putTestObject(c, s.nosClient, TEST_BUCKET, object, []byte("1"))
+		putTestObject(c, s.nosClient, TEST_BUCKET, object, []byte("2"))
+	}
+	putTestObject(c, s.nosClient, TEST_BUCKET, "dir/d", []byte("1"))
+
+	request := &model.ListObjectVersionsRequest{Bucket: TEST_BUCKET, Delimiter: "/", MaxKeys: 4}
+	var listed []string
+	for {
+		result, err := s.nosClient.ListObjectVersions(request)
+		c.Assert(err, IsNil)
+		for _, version := range result.Versions {
+			listed = append(listed, version.Key)
+		}
+		for _, prefix := range result.CommonPrefixes {
+			listed = append(listed, prefix.Prefix)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		request.KeyMarker = result.NextKeyMarker
+		request.VersionIdMarker = result.NextVersionIdMarker
+	}
+	c.Assert(strings.Join(listed, ","), Equals, "a,a,b,b,c,c,dir/")
+}
@@ -1,37 +1,97 @@
 package nosconst
 
+import "strings"
+
+
+type Location  int
 
 const (
-    HZ = iota
+	HZ Location = iota
+	BJ
+	GZ
 )
 
-type Location  int
-type Acl int
+var locationConstraints = map[Location]string{
+	HZ: "HZ",
+	BJ: "BJ",
+	GZ: "GZ",
+}
 
+// String returns the LocationConstraint the location is known by in
+// requests and responses, or "" for an unknown location.
+func (location Location) String() string {
+	return locationConstraints[location]
+}
 
-const(
-    PRIVATE = iota
-    PUBLICREAD
+// ParseLocation returns the location with the given LocationConstraint.
+func ParseLocation(locationConstraint string) (Location, bool) {
+	for location, name := range locationConstraints {
+		if strings.EqualFold(name, locationConstraint) {
+			return location, true
+		}
+	}
+	return 0, false
+}
+
+type Acl int
+
+const (
+	PRIVATE Acl = iota
+	PUBLICREAD
 )
 
+var cannedAcls = map[Acl]string{
+	PRIVATE:    "private",
+	PUBLICREAD: "public-read",
+}
+
+// String returns the canned ACL sent in the x-nos-acl header, or "" for an
+// unknown ACL.
+func (acl Acl) String() string {
+	return cannedAcls[acl]
+}
+
+// ParseAcl returns the ACL with the given canned ACL name.
+func ParseAcl(cannedAcl string) (Acl, bool) {
+	for acl, name := range cannedAcls {
+		if strings.EqualFold(name, cannedAcl) {
+			return acl, true
+		}
+	}
+	return 0, false
+}
+
 const (
 	DEFAULT_MAXBUFFERSIZE = 1024 * 1024
 	MAX_FILESIZE          = 100 * 1024 * 1024
 	MIN_FILESIZE          = 16 * 1024
+	DEFAULT_PARTSIZE      = 8 * 1024 * 1024
+	MAX_PARTNUMBER        = 10000
+	DEFAULT_CONCURRENCY   = 5
+	DEFAULT_BLOCKSIZE     = 1024 * 1024
+	DEFAULT_READAHEAD     = 2
+	DEFAULT_CACHEBLOCKS   = 8
+	DEFAULT_CHUNKSIZE     = 64 * 1024
+	MAX_CHUNKSIZE         = 1024 * 1024
 	MAX_FILENUMBER        = 1000
 	DEFAULTVALUE          = 1000
 	MAX_DELETEBODY        = 2 * 1024 * 1024
 
+	// MAX_ERRORBODY is how much of the body of an error response is read.
+	MAX_ERRORBODY = 64 * 1024
+
 	RFC1123_NOS          = "Mon, 02 Jan 2006 15:04:05 Asia/Shanghai"
 	RFC1123_GMT          = "Mon, 02 Jan 2006 15:04:05 GMT"
 	CONTENT_LENGTH       = "Content-Length"
 	CONTENT_TYPE         = "Content-Type"
 	CONTENT_MD5          = "Content-Md5"
+	CONTENT_ENCODING     = "Content-Encoding"
 	LAST_MODIFIED        = "Last-Modified"
 	USER_AGENT           = "User-Agent"
 	DATE                 = "Date"
 	AUTHORIZATION        = "Authorization"
 	RANGE                = "Range"
+	CONTENT_RANGE        = "Content-Range"
 	IfMODIFYSINCE        = "If-Modified-Since"
 	LIST_PREFIX          = "prefix"
 	LIST_DELIMITER       = "delimiter"
@@ -41,21 +101,67 @@ const (
 	MAX_PARTS            = "max-parts"
 	PARTNUMBER           = "partNumber"
 	UPLOADS              = "uploads"
+	LOCATION             = "location"
+	ACL                  = "acl"
+	VERSIONING           = "versioning"
+	VERSIONS             = "versions"
+	VERSIONID            = "versionId"
+
+	LIST_VERSIONID_MARKER = "version-id-marker"
+	VERSIONING_ENABLED    = "Enabled"
+	VERSIONING_SUSPENDED  = "Suspended"
+	NULL_VERSIONID        = "null"
 	PART_NUMBER_MARKER   = "part-number-marker"
 	LIST_KEY_MARKER      = "key-marker"
 	LIST_MAX_UPLOADS     = "max-uploads"
 	LIST_UPLOADID_MARKER = "upload-id-marker"
 
+	PRESIGN_ACCESS_KEY_ID  = "NOSAccessKeyId"
+	PRESIGN_EXPIRES        = "Expires"
+	PRESIGN_SIGNATURE      = "Signature"
+	PRESIGN_SECURITY_TOKEN = "NOSSecurityToken"
+
+	RESPONSE_CONTENT_TYPE        = "response-content-type"
+	RESPONSE_CONTENT_DISPOSITION = "response-content-disposition"
+	RESPONSE_CONTENT_ENCODING    = "response-content-encoding"
+	RESPONSE_CONTENT_LANGUAGE    = "response-content-language"
+	RESPONSE_CACHE_CONTROL       = "response-cache-control"
+	RESPONSE_EXPIRES             = "response-expires"
+
 	ETAG                     = "Etag"
 	NOS_USER_METADATA_PREFIX = "X-Nos-Meta-"
 	NOS_ENTITY_TYPE          = "X-Nos-Entity-Type"
 	NOS_VERSION_ID           = "X-Nos-Version-Id"
+	NOS_DELETE_MARKER        = "X-Nos-Delete-Marker"
 	X_NOS_OBJECT_NAME        = "X-Nos-Object-Name"
 	X_NOS_REQUEST_ID         = "X-Nos-Request-Id"
 	X_NOS_OBJECT_MD5         = "X-Nos-Object-Md5"
 	X_NOS_COPY_SOURCE        = "x-nos-copy-source"
 	X_NOS_MOVE_SOURCE        = "x-nos-move-source"
     X_NOS_ACL                = "x-nos-acl"
+	X_NOS_SECURITY_TOKEN     = "x-nos-security-token"
+
+	ACL_FULL_CONTROL = "FULL_CONTROL"
+	ACL_READ         = "READ"
+	ACL_ALL_USERS    = "http://acs.amazonaws.com/groups/global/AllUsers"
+
+	SYNC_UPLOAD   = "upload"
+	SYNC_DOWNLOAD = "download"
+	SYNC_DELETE   = "delete"
+
+	// Client-side encryption parameters, stored as user metadata.
+	X_NOS_META_ENCRYPTION_ALGORITHM  = NOS_USER_METADATA_PREFIX + "Encryption-Algorithm"
+	X_NOS_META_ENCRYPTION_KEY        = NOS_USER_METADATA_PREFIX + "Encryption-Key"
+	X_NOS_META_ENCRYPTION_KEY_ID     = NOS_USER_METADATA_PREFIX + "Encryption-Key-Id"
+	X_NOS_META_ENCRYPTION_NONCE      = NOS_USER_METADATA_PREFIX + "Encryption-Nonce"
+	X_NOS_META_ENCRYPTION_CHUNK_SIZE = NOS_USER_METADATA_PREFIX + "Encryption-Chunk-Size"
+	ENCRYPTION_AES_GCM_CHUNKED       = "AES-256-GCM-CHUNKED"
+
+	// Compression mode parameters, stored as user metadata.
+	X_NOS_META_COMPRESSION         = NOS_USER_METADATA_PREFIX + "Compression"
+	X_NOS_META_UNCOMPRESSED_LENGTH = NOS_USER_METADATA_PREFIX + "Uncompressed-Length"
+	X_NOS_META_UNCOMPRESSED_MD5    = NOS_USER_METADATA_PREFIX + "Uncompressed-Md5"
+	COMPRESSION_GZIP               = "gzip"
 
 	ORIG_CONTENT_MD5              = "Content-MD5"
 	ORIG_ETAG                     = "ETag"
@@ -0,0 +1,203 @@
+package noserror
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// Sentinel errors classifying the errors returned by the SDK, for use with
+// errors.Is:
+//
+//	if errors.Is(err, noserror.ErrNotFound) { ... }
+//
+// A ServerError matches the sentinel of its status code and NOS error
+// code; the Is functions below are shorthands for errors.Is.
+var (
+	// ErrNotFound matches a missing bucket, object or multipart upload.
+	ErrNotFound = errors.New("not found")
+
+	// ErrAccessDenied matches requests the server refused to authorize,
+	// such as requests with a wrong signature or an expired token.
+	ErrAccessDenied = errors.New("access denied")
+
+	// ErrPreconditionFailed matches requests whose conditions, such as
+	// If-Match, the object does not satisfy.
+	ErrPreconditionFailed = errors.New("precondition failed")
+
+	// ErrNotModified matches conditional reads of an object that has not
+	// changed, such as GetObject with IfModifiedSince.
+	ErrNotModified = errors.New("not modified")
+
+	// ErrThrottled matches requests rejected because the client sends too
+	// many of them.
+	ErrThrottled = errors.New("throttled")
+)
+
+// throttlingCodes are the NOS error codes of throttled requests.
+var throttlingCodes = map[string]bool{
+	"SlowDown":         true,
+	"Throttling":       true,
+	"TooManyRequests":  true,
+	"RequestThrottled": true,
+}
+
+// Is reports whether serverError matches target, a sentinel error above or
+// a ServerError. A ServerError target matches on its StatusCode, if not
+// zero, and on the Code of its NosErr, if set.
+func (serverError *ServerError) Is(target error) bool {
+	code := ""
+	if serverError.NosErr != nil {
+		code = serverError.NosErr.Code
+	}
+
+	switch target {
+	case ErrNotFound:
+		return serverError.StatusCode == http.StatusNotFound
+	case ErrAccessDenied:
+		return serverError.StatusCode == http.StatusUnauthorized || serverError.StatusCode == http.StatusForbidden
+	case ErrPreconditionFailed:
+		return serverError.StatusCode == http.StatusPreconditionFailed
+	case ErrNotModified:
+		return serverError.StatusCode == http.StatusNotModified
+	case ErrThrottled:
+		return serverError.StatusCode == http.StatusTooManyRequests || throttlingCodes[code]
+	}
+
+	if t, ok := target.(*ServerError); ok {
+		return (t.StatusCode == 0 || t.StatusCode == serverError.StatusCode) &&
+			(t.NosErr == nil || t.NosErr.Code == code)
+	}
+	return false
+}
+
+// Is reports whether clientError has the StatusCode of target, a
+// ClientError, so that errors.Is(err, &ClientError{StatusCode: code})
+// matches client errors by code. The code of a client error is the SDK's own
+// and matches none of the sentinel errors above; a client error matches them,
+// and IsRetryable, only through the error that caused it, such as a
+// ServerError or a timeout.
+func (clientError *ClientError) Is(target error) bool {
+	t, ok := target.(*ClientError)
+	return ok && t.StatusCode == clientError.StatusCode
+}
+
+// Unwrap returns the error that caused clientError, if any.
+func (clientError *ClientError) Unwrap() error {
+	return clientError.Err
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+func IsAccessDenied(err error) bool {
+	return errors.Is(err, ErrAccessDenied)
+}
+
+func IsPreconditionFailed(err error) bool {
+	return errors.Is(err, ErrPreconditionFailed)
+}
+
+func IsNotModified(err error) bool {
+	return errors.Is(err, ErrNotModified)
+}
+
+func IsThrottled(err error) bool {
+	return errors.Is(err, ErrThrottled)
+}
+
+// RetryErrorClass identifies a family of transport errors that may be retried.
+type RetryErrorClass int
+
+const (
+	// RETRY_ERROR_TIMEOUT matches connect, read/write and request timeouts.
+	RETRY_ERROR_TIMEOUT RetryErrorClass = iota
+	// RETRY_ERROR_CONNECTION matches refused, reset and prematurely closed
+	// connections.
+	RETRY_ERROR_CONNECTION
+)
+
+// DefaultRetryableStatusCodes and DefaultRetryableErrors are the failures
+// that are retried when a config.RetryConfig does not list its own, and the
+// ones IsRetryable reports: failed, unavailable and throttled requests, and
+// transport errors of every class.
+var (
+	DefaultRetryableStatusCodes = []int{
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+		http.StatusTooManyRequests,
+	}
+	DefaultRetryableErrors = []RetryErrorClass{RETRY_ERROR_TIMEOUT, RETRY_ERROR_CONNECTION}
+)
+
+// RetryPolicy selects the failures that are retried; config.RetryConfig
+// implements it.
+type RetryPolicy interface {
+	IsRetryableStatus(statusCode int) bool
+	IsRetryableClass(class RetryErrorClass) bool
+}
+
+// ShouldRetry reports whether policy retries a request that failed with err
+// or, if err is nil, that got a response with statusCode. A request whose
+// context was canceled or reached its deadline is not retried, although
+// context.DeadlineExceeded is a timeout.
+func ShouldRetry(policy RetryPolicy, statusCode int, err error) bool {
+	if err == nil {
+		return policy.IsRetryableStatus(statusCode)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return policy.IsRetryableClass(RETRY_ERROR_TIMEOUT)
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return policy.IsRetryableClass(RETRY_ERROR_CONNECTION)
+	}
+	return false
+}
+
+// defaultRetryPolicy retries DefaultRetryableStatusCodes and
+// DefaultRetryableErrors.
+type defaultRetryPolicy struct{}
+
+func (defaultRetryPolicy) IsRetryableStatus(statusCode int) bool {
+	for _, code := range DefaultRetryableStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+func (defaultRetryPolicy) IsRetryableClass(class RetryErrorClass) bool {
+	for _, c := range DefaultRetryableErrors {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryable reports whether the operation that returned err may succeed
+// if it is tried again, according to the default retry policy, as decided by
+// ShouldRetry for the status code of a ServerError or for a transport error.
+// Failed operations have already been retried by the client according to
+// its config.RetryConfig.
+func IsRetryable(err error) bool {
+	var serverError *ServerError
+	if errors.As(err, &serverError) {
+		return ShouldRetry(defaultRetryPolicy{}, serverError.StatusCode, nil)
+	}
+	return err != nil && ShouldRetry(defaultRetryPolicy{}, 0, err)
+}
@@ -0,0 +1,117 @@
+package noserror
+
+import (
+	"context"
+	"errors"
+	. "gopkg.in/check.v1"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ErrorsTestSuite struct{}
+
+var _ = Suite(&ErrorsTestSuite{})
+
+func (s *ErrorsTestSuite) SetUpSuite(c *C) {
+	Init()
+}
+
+// timeoutError is the error of a request that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// retryPolicy retries the status codes and error classes it lists.
+type retryPolicy struct {
+	statusCodes []int
+	classes     []RetryErrorClass
+}
+
+func (policy retryPolicy) IsRetryableStatus(statusCode int) bool {
+	for _, code := range policy.statusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+func (policy retryPolicy) IsRetryableClass(class RetryErrorClass) bool {
+	for _, c := range policy.classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ErrorsTestSuite) TestRetryableErrors(c *C) {
+	slowDown := NewServerError(http.StatusServiceUnavailable, "request-1",
+		NewNosError("SlowDown", "Please reduce your request rate.", "/bucket/object", "request-1"))
+	c.Assert(IsThrottled(slowDown), Equals, true)
+	c.Assert(IsRetryable(slowDown), Equals, true)
+
+	tooMany := NewServerError(http.StatusTooManyRequests, "request-2", NewNosError("", "", "", ""))
+	c.Assert(IsThrottled(tooMany), Equals, true)
+	c.Assert(IsRetryable(tooMany), Equals, true)
+
+	notFound := NewServerError(http.StatusNotFound, "request-3", NewNosError("NoSuchKey", "", "", ""))
+	c.Assert(IsRetryable(notFound), Equals, false)
+
+	// Transport errors are retryable too.
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	c.Assert(IsRetryable(refused), Equals, true)
+	c.Assert(IsThrottled(refused), Equals, false)
+	c.Assert(IsRetryable(&net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}), Equals, true)
+	c.Assert(IsRetryable(io.ErrUnexpectedEOF), Equals, true)
+
+	// The caller's deadline and cancellation are final.
+	c.Assert(IsRetryable(context.DeadlineExceeded), Equals, false)
+	c.Assert(IsRetryable(context.Canceled), Equals, false)
+	c.Assert(IsRetryable(&url.Error{Op: "Get", URL: "http://nos.netease.com/", Err: context.DeadlineExceeded}),
+		Equals, false)
+	c.Assert(IsRetryable(WrapClientError(ERROR_CODE_READCONTENT_ERROR, "", context.DeadlineExceeded)), Equals, false)
+
+	c.Assert(IsRetryable(nil), Equals, false)
+	c.Assert(IsRetryable(errors.New("unknown")), Equals, false)
+}
+
+func (s *ErrorsTestSuite) TestShouldRetry(c *C) {
+	policy := retryPolicy{statusCodes: []int{http.StatusBadGateway}, classes: []RetryErrorClass{RETRY_ERROR_TIMEOUT}}
+	c.Assert(ShouldRetry(policy, http.StatusBadGateway, nil), Equals, true)
+	c.Assert(ShouldRetry(policy, http.StatusServiceUnavailable, nil), Equals, false)
+	c.Assert(ShouldRetry(policy, 0, timeoutError{}), Equals, true)
+	c.Assert(ShouldRetry(policy, 0, syscall.ECONNRESET), Equals, false)
+
+	// The status code is that of a response, which failed requests lack.
+	c.Assert(ShouldRetry(policy, http.StatusBadGateway, errors.New("unknown")), Equals, false)
+}
+
+func (s *ErrorsTestSuite) TestClientErrors(c *C) {
+	mismatch := NewClientError(ERROR_CODE_CONTENT_MISMATCH, "/bucket/object", "")
+	c.Assert(errors.Is(mismatch, &ClientError{StatusCode: ERROR_CODE_CONTENT_MISMATCH}), Equals, true)
+	c.Assert(errors.Is(mismatch, &ClientError{StatusCode: ERROR_CODE_CHECKSUM_MISMATCH}), Equals, false)
+	for _, sentinel := range []error{ErrNotFound, ErrAccessDenied, ErrPreconditionFailed, ErrNotModified, ErrThrottled} {
+		c.Assert(errors.Is(mismatch, sentinel), Equals, false, Commentf("%v", sentinel))
+	}
+	c.Assert(IsRetryable(mismatch), Equals, false)
+
+	// A client error is classified by the error that caused it.
+	readError := WrapClientError(ERROR_CODE_READCONTENT_ERROR, "/bucket/object", io.ErrUnexpectedEOF)
+	c.Assert(IsRetryable(readError), Equals, true)
+	c.Assert(errors.Is(readError, &ClientError{StatusCode: ERROR_CODE_READCONTENT_ERROR}), Equals, true)
+
+	credentialsError := WrapClientError(ERROR_CODE_CREDENTIALS_ERROR, "",
+		NewServerError(http.StatusForbidden, "request-1", NewNosError("AccessDenied", "", "", "")))
+	c.Assert(IsAccessDenied(credentialsError), Equals, true)
+	c.Assert(IsRetryable(credentialsError), Equals, false)
+}
@@ -2,7 +2,9 @@ package noserror
 
 import (
 	"encoding/xml"
+	"net/http"
 	"strconv"
+	"time"
 )
 
 const (
@@ -14,6 +16,10 @@ const (
 	ERROR_CODE_CFG_CONNECT_TIMEOUT      = BASE_ERROR_CODE + 21
 	ERROR_CODE_CFG_READWRITE_TIMEOUT    = BASE_ERROR_CODE + 22
 	ERROR_CODE_CFG_MAXIDLECONNECT       = BASE_ERROR_CODE + 23
+	ERROR_CODE_CFG_RETRY                = BASE_ERROR_CODE + 24
+	ERROR_CODE_CFG_SCHEME               = BASE_ERROR_CODE + 25
+	ERROR_CODE_CFG_TLS                  = BASE_ERROR_CODE + 26
+	ERROR_CODE_CFG_SOURCE               = BASE_ERROR_CODE + 27
 	ERROR_CODE_BUCKET_INVALID           = BASE_ERROR_CODE + 30
 	ERROR_CODE_OBJECT_INVALID           = BASE_ERROR_CODE + 31
 	ERROR_CODE_FILELENGTH_INVALID       = BASE_ERROR_CODE + 32
@@ -26,12 +32,26 @@ const (
 	ERROR_CODE_DELETEMULTIOBJECTS_ERROR = BASE_ERROR_CODE + 39
 	ERROR_CODE_OBJECTSBIGGER_ERROR      = BASE_ERROR_CODE + 40
 	ERROR_CODE_PARTLENGTH_ERROR         = BASE_ERROR_CODE + 41
+	ERROR_CODE_EXPIRES_INVALID          = BASE_ERROR_CODE + 42
+	ERROR_CODE_PARTNUMBER_ERROR         = BASE_ERROR_CODE + 43
+	ERROR_CODE_CONTENT_MISMATCH         = BASE_ERROR_CODE + 44
+	ERROR_CODE_ACL_INVALID              = BASE_ERROR_CODE + 45
+	ERROR_CODE_VERSIONING_INVALID       = BASE_ERROR_CODE + 46
+	ERROR_CODE_PATTERN_INVALID          = BASE_ERROR_CODE + 47
+	ERROR_CODE_CHECKSUM_MISMATCH        = BASE_ERROR_CODE + 48
+	ERROR_CODE_ENCRYPTION_ERROR         = BASE_ERROR_CODE + 49
+	ERROR_CODE_COMPRESSION_ERROR        = BASE_ERROR_CODE + 50
+	ERROR_CODE_CREDENTIALS_ERROR        = BASE_ERROR_CODE + 51
 
 	/*short message code*/
 	ERROR_MSG_CFG_ENDPOINT             = "Config: InvalidEndpoint"
 	ERROR_MSG_CFG_CONNECT_TIMEOUT      = "Config: InvalidConnectionTimeout"
 	ERROR_MSG_CFG_READWRITE_TIMEOUT    = "Config: InvalidReadWriteTimeout"
 	ERROR_MSG_CFG_MAXIDLECONNECT       = "Config: InvalidMaxIdleConnect"
+	ERROR_MSG_CFG_RETRY                = "Config: InvalidRetry"
+	ERROR_MSG_CFG_SCHEME               = "Config: InvalidScheme"
+	ERROR_MSG_CFG_TLS                  = "Config: InvalidTLS"
+	ERROR_MSG_CFG_SOURCE               = "Config: InvalidSource"
 	ERROR_MSG_BUCKET_INVALID           = "InvalidBucketName"
 	ERROR_MSG_OBJECT_INVALID           = "InvalidObjectName"
 	ERROR_MSG_FILELENGTH_INVALID       = "InvalidFileSize"
@@ -44,6 +64,16 @@ const (
 	ERROR_MSG_DELETEMULTIOBJECTS_ERROR = "InvalidDeleteMultiObjects"
 	ERROR_MSG_OBJECTSBIGGER_ERROR      = "InvalidObjects: the number is < 1000 and size of body is < 2M"
 	ERROR_MSG_PARTLENGTH_ERROR         = "InvalidPartLength: the length should be between  16k and 100M"
+	ERROR_MSG_EXPIRES_INVALID          = "InvalidExpires: the expiry should be positive"
+	ERROR_MSG_PARTNUMBER_ERROR         = "InvalidPartNumber: an upload has at most 10000 parts"
+	ERROR_MSG_CONTENT_MISMATCH         = "ContentMismatch: the downloaded content does not match the object"
+	ERROR_MSG_ACL_INVALID              = "InvalidAcl"
+	ERROR_MSG_VERSIONING_INVALID       = "InvalidVersioningStatus: the status should be Enabled or Suspended"
+	ERROR_MSG_PATTERN_INVALID          = "InvalidPattern"
+	ERROR_MSG_CHECKSUM_MISMATCH        = "ChecksumMismatch"
+	ERROR_MSG_ENCRYPTION_ERROR         = "EncryptionError"
+	ERROR_MSG_COMPRESSION_ERROR        = "CompressionError"
+	ERROR_MSG_CREDENTIALS_ERROR        = "CredentialsError"
 )
 
 // mErrHttpCodeMap is map of Http Code
@@ -57,6 +87,10 @@ func Init() {
 	mErrMsgMap[ERROR_CODE_CFG_CONNECT_TIMEOUT] = ERROR_MSG_CFG_CONNECT_TIMEOUT
 	mErrMsgMap[ERROR_CODE_CFG_READWRITE_TIMEOUT] = ERROR_MSG_CFG_READWRITE_TIMEOUT
 	mErrMsgMap[ERROR_CODE_CFG_MAXIDLECONNECT] = ERROR_MSG_CFG_MAXIDLECONNECT
+	mErrMsgMap[ERROR_CODE_CFG_RETRY] = ERROR_MSG_CFG_RETRY
+	mErrMsgMap[ERROR_CODE_CFG_SCHEME] = ERROR_MSG_CFG_SCHEME
+	mErrMsgMap[ERROR_CODE_CFG_TLS] = ERROR_MSG_CFG_TLS
+	mErrMsgMap[ERROR_CODE_CFG_SOURCE] = ERROR_MSG_CFG_SOURCE
 	mErrMsgMap[ERROR_CODE_BUCKET_INVALID] = ERROR_MSG_BUCKET_INVALID
 	mErrMsgMap[ERROR_CODE_OBJECT_INVALID] = ERROR_MSG_OBJECT_INVALID
 	mErrMsgMap[ERROR_CODE_FILELENGTH_INVALID] = ERROR_MSG_FILELENGTH_INVALID
@@ -69,6 +103,16 @@ func Init() {
 	mErrMsgMap[ERROR_CODE_DELETEMULTIOBJECTS_ERROR] = ERROR_MSG_DELETEMULTIOBJECTS_ERROR
 	mErrMsgMap[ERROR_CODE_OBJECTSBIGGER_ERROR] = ERROR_MSG_OBJECTSBIGGER_ERROR
 	mErrMsgMap[ERROR_CODE_PARTLENGTH_ERROR] = ERROR_MSG_PARTLENGTH_ERROR
+	mErrMsgMap[ERROR_CODE_EXPIRES_INVALID] = ERROR_MSG_EXPIRES_INVALID
+	mErrMsgMap[ERROR_CODE_PARTNUMBER_ERROR] = ERROR_MSG_PARTNUMBER_ERROR
+	mErrMsgMap[ERROR_CODE_CONTENT_MISMATCH] = ERROR_MSG_CONTENT_MISMATCH
+	mErrMsgMap[ERROR_CODE_ACL_INVALID] = ERROR_MSG_ACL_INVALID
+	mErrMsgMap[ERROR_CODE_VERSIONING_INVALID] = ERROR_MSG_VERSIONING_INVALID
+	mErrMsgMap[ERROR_CODE_PATTERN_INVALID] = ERROR_MSG_PATTERN_INVALID
+	mErrMsgMap[ERROR_CODE_CHECKSUM_MISMATCH] = ERROR_MSG_CHECKSUM_MISMATCH
+	mErrMsgMap[ERROR_CODE_ENCRYPTION_ERROR] = ERROR_MSG_ENCRYPTION_ERROR
+	mErrMsgMap[ERROR_CODE_COMPRESSION_ERROR] = ERROR_MSG_COMPRESSION_ERROR
+	mErrMsgMap[ERROR_CODE_CREDENTIALS_ERROR] = ERROR_MSG_CREDENTIALS_ERROR
 }
 
 type NosError struct {
@@ -100,6 +144,20 @@ type ServerError struct {
 	StatusCode int
 	RequestId  string
 	NosErr     *NosError `json:"Error"`
+
+	// Method and URL are those of the failed request. The signature and
+	// security token of a presigned URL are redacted.
+	Method string `json:"-"`
+	URL    string `json:"-"`
+
+	// Header is the header of the response, and Body the start of its
+	// content, which is kept even when it cannot be parsed.
+	Header http.Header `json:"-"`
+	Body   []byte      `json:"-"`
+
+	// Duration is the time from sending the request to reading its
+	// response, if known.
+	Duration time.Duration `json:"-"`
 }
 
 func NewServerError(errCode int, requestid string, nosErr *NosError) error {
@@ -121,6 +179,9 @@ type ClientError struct {
 	StatusCode int
 	Resource   string
 	Message    string
+
+	// Err is the error that caused this one, such as a failed read, if any.
+	Err error
 }
 
 func NewClientError(errCode int, resource string, msg string) error {
@@ -135,8 +196,48 @@ func NewClientError(errCode int, resource string, msg string) error {
 	return clientError
 }
 
+// WrapClientError is like NewClientError, with the message of err, which is
+// kept as the cause of the returned error.
+func WrapClientError(errCode int, resource string, err error) error {
+	clientError := NewClientError(errCode, resource, err.Error()).(*ClientError)
+	clientError.Err = err
+	return clientError
+}
+
+// NewNotModifiedError returns the error of a conditional read of an object
+// that has not changed since the given time.
+func NewNotModifiedError(resource string, requestid string) error {
+	return NewServerError(http.StatusNotModified, requestid,
+		NewNosError("NotModified", "the object has not been modified", resource, requestid))
+}
+
 func (clientError *ClientError) Error() string {
 	return "StatusCode = " + strconv.Itoa(clientError.StatusCode) +
 		", Resource = " + clientError.Resource +
 		", Message = " + clientError.Message
 }
+
+// ChecksumError reports content whose MD5 does not match the MD5 expected
+// for it, such as a download that does not match the object's ETag.
+type ChecksumError struct {
+	StatusCode int
+	Resource   string
+	Expected   string
+	Actual     string
+}
+
+func NewChecksumError(resource string, expected string, actual string) error {
+	return &ChecksumError{
+		StatusCode: ERROR_CODE_CHECKSUM_MISMATCH,
+		Resource:   resource,
+		Expected:   expected,
+		Actual:     actual,
+	}
+}
+
+func (checksumError *ChecksumError) Error() string {
+	return "StatusCode = " + strconv.Itoa(checksumError.StatusCode) +
+		", Resource = " + checksumError.Resource +
+		", Message = " + ERROR_MSG_CHECKSUM_MISMATCH + ": MD5 " + checksumError.Actual +
+		" does not match " + checksumError.Expected
+}
@@ -0,0 +1,1313 @@
+/*
+Package nostest provides an in-process NOS server for tests.
+
+The server speaks the subset of the NOS REST protocol used by nosclient:
+buckets, objects, ACLs, versioning, copy/move, multi-object delete, listing
+and multipart uploads. Requests must be signed with the server's credentials
+exactly as auth.SignRequest signs them, or carry a presigned query string;
+anonymous reads are only allowed on public-read buckets and objects.
+
+	server := nostest.NewServer()
+	defer server.Close()
+	server.CreateBucket("bucket")
+
+	client, err := nosclient.New(server.Config())
+*/
+package nostest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/auth"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/config"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/credentials"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/utils"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_ACCESS_KEY = "nostest-access-key"
+	DEFAULT_SECRET_KEY = "nostest-secret-key"
+
+	timeFormat = "2006-01-02T15:04:05.000Z"
+)
+
+type Options struct {
+	// AccessKey and SecretKey are the only credentials the server accepts.
+	// They default to DEFAULT_ACCESS_KEY and DEFAULT_SECRET_KEY.
+	AccessKey string
+	SecretKey string
+
+	// SecurityToken, if set, must accompany every signed request, as the
+	// x-nos-security-token header or, in presigned URLs, the
+	// NOSSecurityToken parameter.
+	SecurityToken string
+
+	// Dir, if set, stores object data as files in this directory instead of
+	// in memory.
+	Dir string
+
+	// TLS serves https with a self-signed certificate trusted by Config.
+	TLS bool
+}
+
+type Server struct {
+	// URL is the base URL of the server, e.g. http://127.0.0.1:34567.
+	URL string
+	// Endpoint is URL without its scheme.
+	Endpoint string
+
+	AccessKey     string
+	SecretKey     string
+	SecurityToken string
+
+	httpServer *httptest.Server
+	store      blobStore
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	requestId int64
+	uploadId  int64
+	versionId int64
+}
+
+type bucket struct {
+	name       string
+	acl        string
+	location   string
+	versioning string // "" if versioning was never enabled
+	created    time.Time
+	objects    map[string]*object
+	uploads    map[string]*upload
+
+	// versions holds every version of the keys written since versioning
+	// was enabled, oldest first. objects holds the latest version of each
+	// key, unless it is a delete marker.
+	versions map[string][]*object
+}
+
+type object struct {
+	key          string
+	blob         string
+	size         int64
+	etag         string
+	lastModified time.Time
+	header       http.Header
+	acl          string // "" follows the bucket's ACL
+	versionId    string // "" for objects stored without versioning
+	deleteMarker bool
+}
+
+type upload struct {
+	key       string
+	id        string
+	initiated time.Time
+	header    http.Header
+	acl       string
+	parts     map[int]*part
+}
+
+type part struct {
+	blob         string
+	size         int64
+	etag         string
+	lastModified time.Time
+}
+
+// NewServer starts an in-memory server with the default credentials.
+func NewServer() *Server {
+	return NewServerWithOptions(nil)
+}
+
+func NewServerWithOptions(options *Options) *Server {
+	if options == nil {
+		options = &Options{}
+	}
+
+	server := &Server{
+		AccessKey:     options.AccessKey,
+		SecretKey:     options.SecretKey,
+		SecurityToken: options.SecurityToken,
+		buckets:       map[string]*bucket{},
+	}
+	if server.AccessKey == "" {
+		server.AccessKey = DEFAULT_ACCESS_KEY
+	}
+	if server.SecretKey == "" {
+		server.SecretKey = DEFAULT_SECRET_KEY
+	}
+
+	if options.Dir != "" {
+		server.store = &dirStore{dir: options.Dir}
+	} else {
+		server.store = newMemoryStore()
+	}
+
+	if options.TLS {
+		server.httpServer = httptest.NewTLSServer(server)
+	} else {
+		server.httpServer = httptest.NewServer(server)
+	}
+	server.URL = server.httpServer.URL
+	server.Endpoint = server.httpServer.Listener.Addr().String()
+
+	return server
+}
+
+func (server *Server) Close() {
+	server.httpServer.Close()
+}
+
+// Config returns a client configuration pointing at the server.
+func (server *Server) Config() *config.Config {
+	conf := &config.Config{
+		Endpoint:  server.URL,
+		AccessKey: server.AccessKey,
+		SecretKey: server.SecretKey,
+	}
+	conf.SetIsSubDomain(false)
+	if server.SecurityToken != "" {
+		conf.Credentials = &credentials.StaticProvider{Credentials: credentials.Credentials{
+			AccessKey:     server.AccessKey,
+			SecretKey:     server.SecretKey,
+			SecurityToken: server.SecurityToken,
+		}}
+	}
+
+	if server.httpServer.TLS != nil {
+		roots := x509.NewCertPool()
+		roots.AddCert(server.httpServer.Certificate())
+		conf.TLS = &config.TLSConfig{RootCAs: roots}
+	}
+
+	return conf
+}
+
+// CreateBucket creates a private bucket directly, without a signed request.
+func (server *Server) CreateBucket(name string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if _, ok := server.buckets[name]; !ok {
+		server.buckets[name] = newBucket(name, "private", "HZ")
+	}
+}
+
+func newBucket(name, acl, location string) *bucket {
+	return &bucket{
+		name:     name,
+		acl:      acl,
+		location: location,
+		created:  time.Now(),
+		objects:  map[string]*object{},
+		uploads:  map[string]*upload{},
+		versions: map[string][]*object{},
+	}
+}
+
+// nosRequest is a parsed incoming request.
+type nosRequest struct {
+	*http.Request
+	w             http.ResponseWriter
+	requestId     string
+	bucket        string
+	object        string
+	encodedObject string
+	query         url.Values
+}
+
+func (req *nosRequest) has(param string) bool {
+	_, ok := req.query[param]
+	return ok
+}
+
+func (req *nosRequest) resource() string {
+	return "/" + req.bucket + "/" + req.object
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	server.requestId++
+	requestId := fmt.Sprintf("nostest-%08d", server.requestId)
+	server.mu.Unlock()
+
+	req := &nosRequest{
+		Request:   r,
+		w:         w,
+		requestId: requestId,
+		query:     r.URL.Query(),
+	}
+	w.Header().Set(nosconst.X_NOS_REQUEST_ID, requestId)
+
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+	host := r.Host
+	if strings.HasSuffix(host, "."+server.Endpoint) {
+		req.bucket = strings.TrimSuffix(host, "."+server.Endpoint)
+		req.encodedObject = path
+	} else {
+		parts := strings.SplitN(path, "/", 2)
+		req.bucket = parts[0]
+		if len(parts) > 1 {
+			req.encodedObject = parts[1]
+		}
+	}
+
+	object, err := url.PathUnescape(req.encodedObject)
+	if err != nil {
+		server.writeError(req, http.StatusBadRequest, "InvalidURI", "Couldn't parse the specified URI.")
+		return
+	}
+	req.object = object
+
+	anonymous, ok := server.authenticate(req)
+	if !ok {
+		return
+	}
+	if anonymous && !server.allowAnonymous(req) {
+		server.writeError(req, http.StatusForbidden, "AccessDenied", "Access Denied")
+		return
+	}
+
+	server.route(req)
+}
+
+// authenticate checks the request's header or query string signature. It
+// writes the error response itself and returns ok == false on failure.
+func (server *Server) authenticate(req *nosRequest) (anonymous bool, ok bool) {
+	// sign a copy so that parsing the form never consumes the request body
+	signing := new(http.Request)
+	*signing = *req.Request
+	signing.Body = http.NoBody
+	signing.Form = nil
+	signing.PostForm = nil
+
+	authorization := req.Header.Get(nosconst.AUTHORIZATION)
+	switch {
+	case authorization != "":
+		credential := strings.TrimPrefix(authorization, "NOS ")
+		accessKey := strings.SplitN(credential, ":", 2)[0]
+		if accessKey != server.AccessKey {
+			server.writeError(req, http.StatusForbidden, "InvalidAccessKeyId",
+				"The access key Id you provided does not exist in our records.")
+			return false, false
+		}
+
+		if !server.checkSecurityToken(req, req.Header.Get(nosconst.X_NOS_SECURITY_TOKEN)) {
+			return false, false
+		}
+
+		expected := auth.SignRequest(signing, server.AccessKey, server.SecretKey, req.bucket, req.encodedObject)
+		if authorization != expected {
+			server.writeError(req, http.StatusForbidden, "SignatureDoesNotMatch",
+				"The request signature we calculated does not match the signature you provided.")
+			return false, false
+		}
+		return false, true
+
+	case req.query.Get(nosconst.PRESIGN_SIGNATURE) != "":
+		if req.query.Get(nosconst.PRESIGN_ACCESS_KEY_ID) != server.AccessKey {
+			server.writeError(req, http.StatusForbidden, "InvalidAccessKeyId",
+				"The access key Id you provided does not exist in our records.")
+			return false, false
+		}
+
+		expires, err := strconv.ParseInt(req.query.Get(nosconst.PRESIGN_EXPIRES), 10, 64)
+		if err != nil || time.Now().Unix() > expires {
+			server.writeError(req, http.StatusForbidden, "AccessDenied", "Request has expired")
+			return false, false
+		}
+
+		// The token is signed as the header it replaces.
+		token := req.query.Get(nosconst.PRESIGN_SECURITY_TOKEN)
+		if !server.checkSecurityToken(req, token) {
+			return false, false
+		}
+		if token != "" {
+			signing.Header = signing.Header.Clone()
+			signing.Header.Set(nosconst.X_NOS_SECURITY_TOKEN, token)
+		}
+
+		expected := auth.PresignRequest(signing, server.SecretKey, req.bucket, req.encodedObject, expires)
+		if req.query.Get(nosconst.PRESIGN_SIGNATURE) != expected {
+			server.writeError(req, http.StatusForbidden, "SignatureDoesNotMatch",
+				"The request signature we calculated does not match the signature you provided.")
+			return false, false
+		}
+		return false, true
+	}
+
+	return true, true
+}
+
+// checkSecurityToken checks the security token of a signed request. It
+// writes the error response itself and returns false on failure.
+func (server *Server) checkSecurityToken(req *nosRequest, token string) bool {
+	if token != server.SecurityToken {
+		server.writeError(req, http.StatusForbidden, "InvalidToken",
+			"The provided token is malformed or otherwise invalid.")
+		return false
+	}
+	return true
+}
+
+func (server *Server) allowAnonymous(req *nosRequest) bool {
+	if req.Method != "GET" && req.Method != "HEAD" || req.object == "" {
+		return false
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b, ok := server.buckets[req.bucket]
+	if !ok {
+		return false
+	}
+	acl := b.acl
+	if obj, ok := b.objects[req.object]; ok && obj.acl != "" {
+		acl = obj.acl
+	}
+	return acl == nosconst.PUBLICREAD.String()
+}
+
+func (server *Server) route(req *nosRequest) {
+	if req.bucket == "" {
+		if req.Method == "GET" {
+			server.listBuckets(req)
+		} else {
+			server.writeError(req, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				"The specified method is not allowed against this resource.")
+		}
+		return
+	}
+
+	if req.object == "" {
+		switch {
+		case req.Method == "PUT" && req.has(nosconst.ACL):
+			server.putAcl(req)
+		case req.Method == "GET" && req.has(nosconst.ACL):
+			server.getAcl(req)
+		case req.Method == "PUT" && req.has(nosconst.VERSIONING):
+			server.putBucketVersioning(req)
+		case req.Method == "PUT":
+			server.createBucket(req)
+		case req.Method == "HEAD":
+			server.headBucket(req)
+		case req.Method == "DELETE":
+			server.deleteBucket(req)
+		case req.Method == "GET" && req.has(nosconst.LOCATION):
+			server.getBucketLocation(req)
+		case req.Method == "GET" && req.has(nosconst.VERSIONING):
+			server.getBucketVersioning(req)
+		case req.Method == "GET" && req.has(nosconst.VERSIONS):
+			server.listObjectVersions(req)
+		case req.Method == "GET" && req.has(nosconst.UPLOADS):
+			server.listMultiUploads(req)
+		case req.Method == "GET":
+			server.listObjects(req)
+		case req.Method == "POST" && req.has("delete"):
+			server.deleteMultiObjects(req)
+		default:
+			server.writeError(req, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				"The specified method is not allowed against this resource.")
+		}
+		return
+	}
+
+	switch {
+	case req.Method == "PUT" && req.has(nosconst.ACL):
+		server.putAcl(req)
+	case req.Method == "GET" && req.has(nosconst.ACL):
+		server.getAcl(req)
+	case req.Method == "PUT" && req.has(nosconst.UPLOADID):
+		server.uploadPart(req)
+	case req.Method == "PUT" && req.Header.Get(nosconst.X_NOS_COPY_SOURCE) != "":
+		server.copyObject(req, req.Header.Get(nosconst.X_NOS_COPY_SOURCE), false)
+	case req.Method == "PUT" && req.Header.Get(nosconst.X_NOS_MOVE_SOURCE) != "":
+		server.copyObject(req, req.Header.Get(nosconst.X_NOS_MOVE_SOURCE), true)
+	case req.Method == "PUT":
+		server.putObject(req)
+	case req.Method == "POST" && req.has(nosconst.UPLOADS):
+		server.initMultiUpload(req)
+	case req.Method == "POST" && req.has(nosconst.UPLOADID):
+		server.completeMultiUpload(req)
+	case req.Method == "GET" && req.has(nosconst.UPLOADID):
+		server.listUploadParts(req)
+	case req.Method == "GET" || req.Method == "HEAD":
+		server.getObject(req)
+	case req.Method == "DELETE" && req.has(nosconst.UPLOADID):
+		server.abortMultiUpload(req)
+	case req.Method == "DELETE":
+		server.deleteObject(req)
+	default:
+		server.writeError(req, http.StatusMethodNotAllowed, "MethodNotAllowed",
+			"The specified method is not allowed against this resource.")
+	}
+}
+
+// getBucket must be called with server.mu held. It writes a NoSuchBucket
+// error when the bucket does not exist.
+func (server *Server) getBucket(req *nosRequest) *bucket {
+	b, ok := server.buckets[req.bucket]
+	if !ok {
+		server.writeError(req, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return nil
+	}
+	return b
+}
+
+// lookupObject must be called with server.mu held. It writes a NoSuchKey
+// error and returns nil if the object does not exist.
+func (server *Server) lookupObject(req *nosRequest, b *bucket) *object {
+	obj, ok := b.objects[req.object]
+	if !ok {
+		server.writeError(req, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return nil
+	}
+	return obj
+}
+
+func (server *Server) createBucket(req *nosRequest) {
+	if !utils.VerifyBucketName(req.bucket) {
+		server.writeError(req, http.StatusBadRequest, "InvalidBucketName", "The specified bucket is not valid.")
+		return
+	}
+
+	location := "HZ"
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		server.writeError(req, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	if len(body) > 0 {
+		configuration := &model.CreateBucketRequest{}
+		if err := xml.Unmarshal(body, configuration); err != nil {
+			server.writeError(req, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+		if configuration.Location != "" {
+			location = configuration.Location
+		}
+	}
+
+	acl, ok := server.cannedAcl(req)
+	if !ok {
+		return
+	}
+	if acl == "" {
+		acl = nosconst.PRIVATE.String()
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if _, ok := server.buckets[req.bucket]; ok {
+		server.writeError(req, http.StatusConflict, "BucketAlreadyExists",
+			"The requested bucket name is not available.")
+		return
+	}
+	server.buckets[req.bucket] = newBucket(req.bucket, acl, location)
+}
+
+func (server *Server) listBuckets(req *nosRequest) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	names := make([]string, 0, len(server.buckets))
+	for name := range server.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := &model.ListBucketsResult{
+		Owner: model.Owner{Id: server.AccessKey, DisplayName: server.AccessKey},
+	}
+	for _, name := range names {
+		result.Buckets = append(result.Buckets, model.Bucket{
+			Name:         name,
+			CreationDate: server.buckets[name].created.UTC().Format(timeFormat),
+		})
+	}
+
+	server.writeXml(req, result)
+}
+
+func (server *Server) headBucket(req *nosRequest) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.getBucket(req)
+}
+
+func (server *Server) deleteBucket(req *nosRequest) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	if len(b.objects) > 0 || len(b.uploads) > 0 || len(b.versions) > 0 {
+		server.writeError(req, http.StatusConflict, "BucketNotEmpty",
+			"The bucket you tried to delete is not empty.")
+		return
+	}
+	delete(server.buckets, b.name)
+}
+
+func (server *Server) getBucketLocation(req *nosRequest) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	server.writeXml(req, &model.GetBucketLocationResult{Location: b.location})
+}
+
+// cannedAcl returns the request's x-nos-acl header, which may be empty. It
+// writes an error response and returns ok == false if the ACL is unknown.
+func (server *Server) cannedAcl(req *nosRequest) (acl string, ok bool) {
+	acl = req.Header.Get(nosconst.X_NOS_ACL)
+	if acl == "" {
+		return "", true
+	}
+	if _, ok := nosconst.ParseAcl(acl); !ok {
+		server.writeError(req, http.StatusBadRequest, "InvalidArgument", "Invalid canned ACL "+acl)
+		return "", false
+	}
+	return strings.ToLower(acl), true
+}
+
+// putAcl sets the ACL of a bucket, or of an object if the request names one.
+func (server *Server) putAcl(req *nosRequest) {
+	acl, ok := server.cannedAcl(req)
+	if !ok {
+		return
+	}
+	if acl == "" {
+		server.writeError(req, http.StatusBadRequest, "MissingSecurityHeader",
+			"Your request was missing a required header: x-nos-acl")
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	if req.object == "" {
+		b.acl = acl
+		return
+	}
+
+	obj := server.lookupObject(req, b)
+	if obj == nil {
+		return
+	}
+	obj.acl = acl
+}
+
+// getAcl describes the ACL of a bucket or object as an access control
+// policy, and names the canned ACL in the x-nos-acl header.
+func (server *Server) getAcl(req *nosRequest) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	acl := b.acl
+	if req.object != "" {
+		obj := server.lookupObject(req, b)
+		if obj == nil {
+			return
+		}
+		if obj.acl != "" {
+			acl = obj.acl
+		}
+	}
+
+	owner := model.Owner{Id: server.AccessKey, DisplayName: server.AccessKey}
+	result := &model.AclResult{
+		Owner: owner,
+		Grants: []model.Grant{{
+			Grantee:    model.Grantee{Id: owner.Id, DisplayName: owner.DisplayName},
+			Permission: nosconst.ACL_FULL_CONTROL,
+		}},
+	}
+	if acl == nosconst.PUBLICREAD.String() {
+		result.Grants = append(result.Grants, model.Grant{
+			Grantee:    model.Grantee{URI: nosconst.ACL_ALL_USERS},
+			Permission: nosconst.ACL_READ,
+		})
+	}
+
+	req.w.Header().Set(nosconst.X_NOS_ACL, acl)
+	server.writeXml(req, result)
+}
+
+// readBody reads the request body and checks it against Content-MD5, which
+// may be hex (as sent by nosclient) or base64 encoded.
+func (server *Server) readBody(req *nosRequest) ([]byte, string, bool) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		server.writeError(req, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return nil, "", false
+	}
+
+	sum := md5.Sum(body)
+	etag := hex.EncodeToString(sum[:])
+	if contentMd5 := req.Header.Get(nosconst.CONTENT_MD5); contentMd5 != "" {
+		if !strings.EqualFold(contentMd5, etag) && contentMd5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			server.writeError(req, http.StatusBadRequest, "BadDigest",
+				"The Content-MD5 you specified did not match what we received.")
+			return nil, "", false
+		}
+	}
+	return body, etag, true
+}
+
+// objectHeader picks the headers that are stored with an object.
+func objectHeader(header http.Header) http.Header {
+	stored := http.Header{}
+	for key, values := range header {
+		switch key {
+		case "Content-Type", "Content-Encoding", "Content-Disposition", "Content-Language",
+			"Cache-Control", "Expires":
+			stored[key] = values
+		default:
+			if strings.HasPrefix(key, nosconst.NOS_USER_METADATA_PREFIX) {
+				stored[key] = values
+			}
+		}
+	}
+	return stored
+}
+
+// storeObject must be called with server.mu held.
+func (server *Server) storeObject(b *bucket, obj *object) {
+	if b.versioning != "" {
+		server.addVersion(b, obj)
+		return
+	}
+
+	if old, ok := b.objects[obj.key]; ok {
+		server.store.remove(old.blob)
+	}
+	b.objects[obj.key] = obj
+}
+
+func (server *Server) putObject(req *nosRequest) {
+	acl, ok := server.cannedAcl(req)
+	if !ok {
+		return
+	}
+
+	body, etag, ok := server.readBody(req)
+	if !ok {
+		return
+	}
+
+	blob, err := server.store.put(body)
+	if err != nil {
+		server.writeError(req, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		server.store.remove(blob)
+		return
+	}
+
+	obj := &object{
+		key:          req.object,
+		blob:         blob,
+		size:         int64(len(body)),
+		etag:         etag,
+		lastModified: time.Now(),
+		header:       objectHeader(req.Header),
+		acl:          acl,
+	}
+	server.storeObject(b, obj)
+	setVersionHeader(req, obj)
+	req.w.Header().Set(nosconst.ETAG, "\""+etag+"\"")
+}
+
+func (server *Server) copyObject(req *nosRequest, source string, move bool) {
+	source, err := url.PathUnescape(source)
+	if err != nil {
+		server.writeError(req, http.StatusBadRequest, "InvalidArgument", "Invalid copy source")
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(source, "/"), "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		server.writeError(req, http.StatusBadRequest, "InvalidArgument", "Invalid copy source")
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	dest := server.getBucket(req)
+	if dest == nil {
+		return
+	}
+	src, ok := server.buckets[parts[0]]
+	if !ok {
+		server.writeError(req, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+	srcObject, ok := src.objects[parts[1]]
+	if !ok {
+		server.writeError(req, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	if src == dest && srcObject.key == req.object {
+		return
+	}
+
+	data, err := server.store.get(srcObject.blob)
+	if err == nil {
+		var blob string
+		blob, err = server.store.put(data)
+		if err == nil {
+			copied := *srcObject
+			copied.key = req.object
+			copied.blob = blob
+			copied.lastModified = time.Now()
+			copied.versionId = ""
+			if !move {
+				copied.acl = ""
+			}
+			server.storeObject(dest, &copied)
+		}
+	}
+	if err != nil {
+		server.writeError(req, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	if move {
+		server.deleteLatest(src, srcObject.key)
+	}
+}
+
+func (server *Server) getObject(req *nosRequest) {
+	server.mu.Lock()
+	b := server.getBucket(req)
+	if b == nil {
+		server.mu.Unlock()
+		return
+	}
+	var obj *object
+	if versionId := req.query.Get(nosconst.VERSIONID); versionId != "" {
+		obj = server.lookupVersion(req, b, versionId)
+	} else {
+		obj = server.lookupObject(req, b)
+	}
+	server.mu.Unlock()
+
+	if obj == nil {
+		return
+	}
+
+	data, err := server.store.get(obj.blob)
+	if err != nil {
+		server.writeError(req, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	header := req.w.Header()
+	for key, values := range obj.header {
+		header[key] = values
+	}
+	if header.Get(nosconst.CONTENT_TYPE) == "" {
+		header.Set(nosconst.CONTENT_TYPE, "application/octet-stream")
+	}
+	overrides := map[string]string{
+		nosconst.RESPONSE_CONTENT_TYPE:        "Content-Type",
+		nosconst.RESPONSE_CONTENT_DISPOSITION: "Content-Disposition",
+		nosconst.RESPONSE_CONTENT_ENCODING:    "Content-Encoding",
+		nosconst.RESPONSE_CONTENT_LANGUAGE:    "Content-Language",
+		nosconst.RESPONSE_CACHE_CONTROL:       "Cache-Control",
+		nosconst.RESPONSE_EXPIRES:             "Expires",
+	}
+	for param, key := range overrides {
+		if value := req.query.Get(param); value != "" {
+			header.Set(key, value)
+		}
+	}
+	header.Set(nosconst.ETAG, "\""+obj.etag+"\"")
+	setVersionHeader(req, obj)
+
+	http.ServeContent(req.w, req.Request, "", obj.lastModified, bytes.NewReader(data))
+}
+
+func (server *Server) deleteObject(req *nosRequest) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	if versionId := req.query.Get(nosconst.VERSIONID); versionId != "" {
+		setVersionHeader(req, server.deleteVersion(b, req.object, versionId))
+	} else {
+		setVersionHeader(req, server.deleteLatest(b, req.object))
+	}
+}
+
+func (server *Server) deleteMultiObjects(req *nosRequest) {
+	body, _, ok := server.readBody(req)
+	if !ok {
+		return
+	}
+
+	deleteRequest := &model.DeleteMultiObjects{}
+	if err := xml.Unmarshal(body, deleteRequest); err != nil {
+		server.writeError(req, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+
+	result := &model.DeleteObjectsResult{}
+	for _, deleteObject := range deleteRequest.Objects {
+		server.deleteLatest(b, deleteObject.Key)
+		if !deleteRequest.Quiet {
+			result.Deleted = append(result.Deleted, model.DeleteKey{Key: deleteObject.Key})
+		}
+	}
+
+	server.writeXml(req, result)
+}
+
+// queryInt parses an integer query parameter, returning def if it is absent
+// or not positive.
+func queryInt(req *nosRequest, param string, def int) int {
+	value, err := strconv.Atoi(req.query.Get(param))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
+
+func (server *Server) listObjects(req *nosRequest) {
+	prefix := req.query.Get(nosconst.LIST_PREFIX)
+	delimiter := req.query.Get(nosconst.LIST_DELIMITER)
+	marker := req.query.Get(nosconst.LIST_MARKER)
+	maxKeys := queryInt(req, nosconst.LIST_MAXKEYS, nosconst.DEFAULTVALUE)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+
+	keys := make([]string, 0, len(b.objects))
+	for key := range b.objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := &model.ListObjectsResult{
+		Bucket:  b.name,
+		Prefix:  prefix,
+		MaxKeys: strconv.Itoa(maxKeys),
+	}
+
+	count := 0
+	last := ""
+	for _, key := range keys {
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				commonPrefix := key[:len(prefix)+i+len(delimiter)]
+				if commonPrefix == last || commonPrefix <= marker {
+					continue
+				}
+				if count == maxKeys {
+					result.IsTruncated = true
+					break
+				}
+				result.CommonPrefixes = append(result.CommonPrefixes, model.CommonPrefix{Prefix: commonPrefix})
+				count++
+				last = commonPrefix
+				continue
+			}
+		}
+
+		if count == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		obj := b.objects[key]
+		result.Contents = append(result.Contents, model.Contents{
+			Key:          key,
+			LastModified: obj.lastModified.UTC().Format(timeFormat),
+			Etag:         obj.etag,
+			Size:         obj.size,
+		})
+		count++
+		last = key
+	}
+	if result.IsTruncated {
+		result.NextMarker = last
+	}
+
+	server.writeXml(req, result)
+}
+
+func (server *Server) initMultiUpload(req *nosRequest) {
+	acl, ok := server.cannedAcl(req)
+	if !ok {
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+
+	server.uploadId++
+	id := fmt.Sprintf("%016x%08x", time.Now().UnixNano(), server.uploadId)
+	b.uploads[id] = &upload{
+		key:       req.object,
+		id:        id,
+		initiated: time.Now(),
+		header:    objectHeader(req.Header),
+		acl:       acl,
+		parts:     map[int]*part{},
+	}
+
+	server.writeXml(req, &model.InitMultiUploadResult{
+		Bucket:   b.name,
+		Object:   req.object,
+		UploadId: id,
+	})
+}
+
+// getUpload must be called with server.mu held. It writes a NoSuchUpload
+// error when the upload does not exist.
+func (server *Server) getUpload(req *nosRequest, b *bucket) *upload {
+	u, ok := b.uploads[req.query.Get(nosconst.UPLOADID)]
+	if !ok || u.key != req.object {
+		server.writeError(req, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+		return nil
+	}
+	return u
+}
+
+func (server *Server) uploadPart(req *nosRequest) {
+	partNumber, err := strconv.Atoi(req.query.Get(nosconst.PARTNUMBER))
+	if err != nil || partNumber < 1 || partNumber > 10000 {
+		server.writeError(req, http.StatusBadRequest, "InvalidArgument",
+			"Part number must be an integer between 1 and 10000, inclusive")
+		return
+	}
+
+	body, etag, ok := server.readBody(req)
+	if !ok {
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	u := server.getUpload(req, b)
+	if u == nil {
+		return
+	}
+
+	blob, err := server.store.put(body)
+	if err != nil {
+		server.writeError(req, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	if old, ok := u.parts[partNumber]; ok {
+		server.store.remove(old.blob)
+	}
+	u.parts[partNumber] = &part{
+		blob:         blob,
+		size:         int64(len(body)),
+		etag:         etag,
+		lastModified: time.Now(),
+	}
+	req.w.Header().Set(nosconst.ETAG, "\""+etag+"\"")
+}
+
+func (server *Server) completeMultiUpload(req *nosRequest) {
+	body, _, ok := server.readBody(req)
+	if !ok {
+		return
+	}
+
+	uploadParts := &model.UploadParts{}
+	if err := xml.Unmarshal(body, uploadParts); err != nil || len(uploadParts.Parts) == 0 {
+		server.writeError(req, http.StatusBadRequest, "MalformedXML",
+			"The XML you provided was not well-formed or did not validate against our published schema.")
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	u := server.getUpload(req, b)
+	if u == nil {
+		return
+	}
+
+	var data []byte
+	partSums := md5.New()
+	for i, uploadPart := range uploadParts.Parts {
+		if i > 0 && uploadPart.PartNumber <= uploadParts.Parts[i-1].PartNumber {
+			server.writeError(req, http.StatusBadRequest, "InvalidPartOrder",
+				"The list of parts was not in ascending order.")
+			return
+		}
+		p, ok := u.parts[uploadPart.PartNumber]
+		if !ok || utils.RemoveQuotes(uploadPart.Etag) != p.etag {
+			server.writeError(req, http.StatusBadRequest, "InvalidPart",
+				"One or more of the specified parts could not be found.")
+			return
+		}
+		if i < len(uploadParts.Parts)-1 && p.size < nosconst.MIN_FILESIZE {
+			server.writeError(req, http.StatusBadRequest, "EntityTooSmall",
+				"Your proposed upload is smaller than the minimum allowed object size.")
+			return
+		}
+
+		partData, err := server.store.get(p.blob)
+		if err != nil {
+			server.writeError(req, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		data = append(data, partData...)
+		sum, _ := hex.DecodeString(p.etag)
+		partSums.Write(sum)
+	}
+
+	if objectMd5 := req.Header.Get(nosconst.X_NOS_OBJECT_MD5); objectMd5 != "" {
+		sum := md5.Sum(data)
+		if !strings.EqualFold(objectMd5, hex.EncodeToString(sum[:])) {
+			server.writeError(req, http.StatusBadRequest, "BadDigest",
+				"The Content-MD5 you specified did not match what we received.")
+			return
+		}
+	}
+
+	blob, err := server.store.put(data)
+	if err != nil {
+		server.writeError(req, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	etag := hex.EncodeToString(partSums.Sum(nil)) + "-" + strconv.Itoa(len(uploadParts.Parts))
+	obj := &object{
+		key:          u.key,
+		blob:         blob,
+		size:         int64(len(data)),
+		etag:         etag,
+		lastModified: time.Now(),
+		header:       u.header,
+		acl:          u.acl,
+	}
+	server.storeObject(b, obj)
+	setVersionHeader(req, obj)
+	for _, p := range u.parts {
+		server.store.remove(p.blob)
+	}
+	delete(b.uploads, u.id)
+
+	server.writeXml(req, &model.CompleteMultiUploadResult{
+		Location: server.URL + req.URL.EscapedPath(),
+		Bucket:   b.name,
+		Key:      u.key,
+		Etag:     "\"" + etag + "\"",
+	})
+}
+
+func (server *Server) abortMultiUpload(req *nosRequest) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	u := server.getUpload(req, b)
+	if u == nil {
+		return
+	}
+
+	for _, p := range u.parts {
+		server.store.remove(p.blob)
+	}
+	delete(b.uploads, u.id)
+}
+
+func (server *Server) listUploadParts(req *nosRequest) {
+	maxParts := queryInt(req, nosconst.MAX_PARTS, nosconst.DEFAULTVALUE)
+	marker := queryInt(req, nosconst.PART_NUMBER_MARKER, 0)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	u := server.getUpload(req, b)
+	if u == nil {
+		return
+	}
+
+	numbers := make([]int, 0, len(u.parts))
+	for number := range u.parts {
+		if number > marker {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+
+	result := &model.ListPartsResult{
+		Bucket:           b.name,
+		Key:              u.key,
+		UploadId:         u.id,
+		StorageClass:     "STANDARD",
+		PartNumberMarker: marker,
+		MaxPart:          maxParts,
+	}
+	if len(numbers) > maxParts {
+		numbers = numbers[:maxParts]
+		result.IsTruncated = true
+	}
+	for _, number := range numbers {
+		p := u.parts[number]
+		result.Parts = append(result.Parts, model.UploadPartRet{
+			PartNumber:   number,
+			LastModified: p.lastModified.UTC().Format(timeFormat),
+			Etag:         p.etag,
+			Size:         int(p.size),
+		})
+		result.NextPartNumberMarker = number
+	}
+
+	server.writeXml(req, result)
+}
+
+func (server *Server) listMultiUploads(req *nosRequest) {
+	keyMarker := req.query.Get(nosconst.LIST_KEY_MARKER)
+	uploadIdMarker := req.query.Get(nosconst.LIST_UPLOADID_MARKER)
+	maxUploads := queryInt(req, nosconst.LIST_MAX_UPLOADS, nosconst.DEFAULTVALUE)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+
+	uploads := make([]*upload, 0, len(b.uploads))
+	for _, u := range b.uploads {
+		if u.key > keyMarker || (u.key == keyMarker && uploadIdMarker != "" && u.id > uploadIdMarker) {
+			uploads = append(uploads, u)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		if uploads[i].key != uploads[j].key {
+			return uploads[i].key < uploads[j].key
+		}
+		return uploads[i].id < uploads[j].id
+	})
+
+	result := &model.ListMultiUploadsResult{
+		Bucket: b.name,
+	}
+	if len(uploads) > maxUploads {
+		uploads = uploads[:maxUploads]
+		result.IsTruncated = true
+	}
+	for _, u := range uploads {
+		result.Uploads = append(result.Uploads, model.MultipartUpload{
+			Key:          u.key,
+			UploadId:     u.id,
+			StorageClass: "STANDARD",
+			Initiated:    u.initiated.UTC().Format(timeFormat),
+		})
+		result.NextKeyMarker = u.key
+		result.NextUploadIdMarker = u.id
+	}
+
+	server.writeXml(req, result)
+}
+
+func (server *Server) writeXml(req *nosRequest, value interface{}) {
+	body, err := xml.Marshal(value)
+	if err != nil {
+		server.writeError(req, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	req.w.Header().Set(nosconst.CONTENT_TYPE, "application/xml")
+	req.w.Write([]byte(xml.Header))
+	req.w.Write(body)
+}
+
+func (server *Server) writeError(req *nosRequest, statusCode int, code, message string) {
+	req.w.Header().Set(nosconst.CONTENT_TYPE, "application/xml")
+	req.w.WriteHeader(statusCode)
+	if req.Method == "HEAD" {
+		return
+	}
+
+	body, _ := xml.Marshal(noserror.NewNosError(code, message, req.resource(), req.requestId))
+	req.w.Write([]byte(xml.Header))
+	req.w.Write(body)
+}
@@ -0,0 +1,97 @@
+package nostest
+
+import (
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/auth"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ServerTestSuite struct{}
+
+var _ = Suite(&ServerTestSuite{})
+
+func signedRequest(c *C, server *Server, method, bucket, object, body, secretKey string) *http.Request {
+	request, err := http.NewRequest(method, server.URL+"/"+bucket+"/"+object, strings.NewReader(body))
+	c.Assert(err, IsNil)
+	request.Header.Set(nosconst.DATE, time.Now().UTC().Format(nosconst.RFC1123_GMT))
+	request.Header.Set(nosconst.AUTHORIZATION,
+		auth.SignRequest(request, server.AccessKey, secretKey, bucket, object))
+	return request
+}
+
+func (s *ServerTestSuite) TestSignature(c *C) {
+	server := NewServer()
+	defer server.Close()
+	server.CreateBucket("bucket")
+
+	resp, err := http.DefaultClient.Do(signedRequest(c, server, "PUT", "bucket", "object", "data", server.SecretKey))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.Header.Get(nosconst.ETAG), Equals, "\"8d777f385d3dfec8815d20f7496026dc\"")
+
+	resp, err = http.DefaultClient.Do(signedRequest(c, server, "GET", "bucket", "object", "", "wrong"))
+	c.Assert(err, IsNil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusForbidden)
+	c.Assert(strings.Contains(string(body), "SignatureDoesNotMatch"), Equals, true)
+
+	resp, err = http.Get(server.URL + "/bucket/object")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusForbidden)
+}
+
+func (s *ServerTestSuite) TestPublicRead(c *C) {
+	server := NewServer()
+	defer server.Close()
+
+	request := signedRequest(c, server, "PUT", "public", "", "", server.SecretKey)
+	request.Header.Set(nosconst.X_NOS_ACL, "public-read")
+	request.Header.Set(nosconst.AUTHORIZATION,
+		auth.SignRequest(request, server.AccessKey, server.SecretKey, "public", ""))
+	resp, err := http.DefaultClient.Do(request)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	resp, err = http.DefaultClient.Do(signedRequest(c, server, "PUT", "public", "object", "data", server.SecretKey))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/public/object")
+	c.Assert(err, IsNil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(string(body), Equals, "data")
+}
+
+func (s *ServerTestSuite) TestDirStorage(c *C) {
+	dir := c.MkDir()
+	server := NewServerWithOptions(&Options{Dir: dir})
+	defer server.Close()
+	server.CreateBucket("bucket")
+
+	resp, err := http.DefaultClient.Do(signedRequest(c, server, "PUT", "bucket", "object", "data", server.SecretKey))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, IsNil)
+	c.Assert(len(files), Equals, 1)
+
+	resp, err = http.DefaultClient.Do(signedRequest(c, server, "GET", "bucket", "object", "", server.SecretKey))
+	c.Assert(err, IsNil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(string(body), Equals, "data")
+}
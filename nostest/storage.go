@@ -0,0 +1,81 @@
+package nostest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// blobStore keeps object and part data, either in memory or as files in a
+// directory.
+type blobStore interface {
+	put(data []byte) (string, error)
+	get(id string) ([]byte, error)
+	remove(id string)
+}
+
+type memoryStore struct {
+	mu    sync.Mutex
+	next  int
+	blobs map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{blobs: map[string][]byte{}}
+}
+
+func (store *memoryStore) put(data []byte) (string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.next++
+	id := strconv.Itoa(store.next)
+	store.blobs[id] = append([]byte(nil), data...)
+	return id, nil
+}
+
+func (store *memoryStore) get(id string) ([]byte, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	data, ok := store.blobs[id]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (store *memoryStore) remove(id string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.blobs, id)
+}
+
+type dirStore struct {
+	dir string
+}
+
+func (store *dirStore) put(data []byte) (string, error) {
+	file, err := ioutil.TempFile(store.dir, "blob-")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return filepath.Base(file.Name()), nil
+}
+
+func (store *dirStore) get(id string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(store.dir, id))
+}
+
+func (store *dirStore) remove(id string) {
+	os.Remove(filepath.Join(store.dir, id))
+}
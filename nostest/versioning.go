@@ -0,0 +1,305 @@
+package nostest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (server *Server) putBucketVersioning(req *nosRequest) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		server.writeError(req, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	configuration := &model.VersioningConfiguration{}
+	if err := xml.Unmarshal(body, configuration); err != nil {
+		server.writeError(req, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+	if configuration.Status != nosconst.VERSIONING_ENABLED && configuration.Status != nosconst.VERSIONING_SUSPENDED {
+		server.writeError(req, http.StatusBadRequest, "IllegalVersioningConfigurationException",
+			"The Versioning element must be specified")
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	b.versioning = configuration.Status
+}
+
+func (server *Server) getBucketVersioning(req *nosRequest) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+	server.writeXml(req, &model.VersioningConfiguration{Status: b.versioning})
+}
+
+// setVersionHeader sends the version id of obj, if it has one, and marks
+// delete markers.
+func setVersionHeader(req *nosRequest, obj *object) {
+	if obj == nil || obj.versionId == "" {
+		return
+	}
+	req.w.Header().Set(nosconst.NOS_VERSION_ID, obj.versionId)
+	if obj.deleteMarker {
+		req.w.Header().Set(nosconst.NOS_DELETE_MARKER, "true")
+	}
+}
+
+// history returns the versions of key, oldest first. A key stored before
+// versioning was enabled has a single version, the "null" version.
+// history must be called with server.mu held.
+func (b *bucket) history(key string) []*object {
+	if versions, ok := b.versions[key]; ok {
+		return versions
+	}
+	if obj, ok := b.objects[key]; ok {
+		return []*object{obj}
+	}
+	return nil
+}
+
+// updateLatest makes objects reflect the latest version of key. It must be
+// called with server.mu held.
+func (b *bucket) updateLatest(key string, versions []*object) {
+	if len(versions) == 0 {
+		delete(b.versions, key)
+		delete(b.objects, key)
+		return
+	}
+
+	b.versions[key] = versions
+	if latest := versions[len(versions)-1]; latest.deleteMarker {
+		delete(b.objects, key)
+	} else {
+		b.objects[key] = latest
+	}
+}
+
+func versionIdOf(obj *object) string {
+	if obj.versionId == "" {
+		return nosconst.NULL_VERSIONID
+	}
+	return obj.versionId
+}
+
+// addVersion stores obj as the latest version of its key. With versioning
+// suspended, obj replaces the "null" version. addVersion must be called with
+// server.mu held.
+func (server *Server) addVersion(b *bucket, obj *object) {
+	versions := b.history(obj.key)
+	for _, v := range versions {
+		if v.versionId == "" {
+			v.versionId = nosconst.NULL_VERSIONID
+		}
+	}
+
+	if b.versioning == nosconst.VERSIONING_ENABLED {
+		server.versionId++
+		obj.versionId = fmt.Sprintf("%016x%08x", time.Now().UnixNano(), server.versionId)
+	} else {
+		obj.versionId = nosconst.NULL_VERSIONID
+		versions = server.removeVersion(versions, nosconst.NULL_VERSIONID)
+	}
+
+	b.updateLatest(obj.key, append(versions, obj))
+}
+
+// removeVersion returns versions without the version versionId, whose data
+// it frees.
+func (server *Server) removeVersion(versions []*object, versionId string) []*object {
+	kept := make([]*object, 0, len(versions))
+	for _, v := range versions {
+		if versionIdOf(v) == versionId {
+			if !v.deleteMarker {
+				server.store.remove(v.blob)
+			}
+			continue
+		}
+		kept = append(kept, v)
+	}
+	return kept
+}
+
+// deleteLatest deletes key the way a DELETE without a version id does: by
+// adding a delete marker if the bucket is versioned, which it returns, and by
+// removing the object otherwise. It must be called with server.mu held.
+func (server *Server) deleteLatest(b *bucket, key string) *object {
+	if b.versioning == "" {
+		if obj, ok := b.objects[key]; ok {
+			server.store.remove(obj.blob)
+			delete(b.objects, key)
+		}
+		return nil
+	}
+
+	marker := &object{
+		key:          key,
+		lastModified: time.Now(),
+		deleteMarker: true,
+	}
+	server.addVersion(b, marker)
+	return marker
+}
+
+// deleteVersion permanently deletes one version of key and returns it, or
+// nil if there is no such version. It must be called with server.mu held.
+func (server *Server) deleteVersion(b *bucket, key, versionId string) *object {
+	versions := b.history(key)
+	for _, v := range versions {
+		if versionIdOf(v) == versionId {
+			b.updateLatest(key, server.removeVersion(versions, versionId))
+			return v
+		}
+	}
+	return nil
+}
+
+// lookupVersion must be called with server.mu held. It writes an error and
+// returns nil if the version does not exist or is a delete marker.
+func (server *Server) lookupVersion(req *nosRequest, b *bucket, versionId string) *object {
+	for _, v := range b.history(req.object) {
+		if versionIdOf(v) != versionId {
+			continue
+		}
+		if v.deleteMarker {
+			setVersionHeader(req, v)
+			server.writeError(req, http.StatusMethodNotAllowed, "MethodNotAllowed",
+				"The specified method is not allowed against this resource.")
+			return nil
+		}
+		return v
+	}
+
+	server.writeError(req, http.StatusNotFound, "NoSuchVersion",
+		"The specified version does not exist.")
+	return nil
+}
+
+func (server *Server) listObjectVersions(req *nosRequest) {
+	prefix := req.query.Get(nosconst.LIST_PREFIX)
+	delimiter := req.query.Get(nosconst.LIST_DELIMITER)
+	keyMarker := req.query.Get(nosconst.LIST_KEY_MARKER)
+	versionIdMarker := req.query.Get(nosconst.LIST_VERSIONID_MARKER)
+	maxKeys := queryInt(req, nosconst.LIST_MAXKEYS, nosconst.DEFAULTVALUE)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	b := server.getBucket(req)
+	if b == nil {
+		return
+	}
+
+	keySet := map[string]bool{}
+	for key := range b.objects {
+		keySet[key] = true
+	}
+	for key := range b.versions {
+		keySet[key] = true
+	}
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		if strings.HasPrefix(key, prefix) && key >= keyMarker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := &model.ListVersionsResult{
+		Bucket:          b.name,
+		Prefix:          prefix,
+		KeyMarker:       keyMarker,
+		VersionIdMarker: versionIdMarker,
+		MaxKeys:         maxKeys,
+	}
+
+	count := 0
+	lastPrefix := ""
+	var lastKey, lastVersionId string
+	full := func() bool {
+		if count == maxKeys {
+			result.IsTruncated = true
+			result.NextKeyMarker = lastKey
+			result.NextVersionIdMarker = lastVersionId
+			return true
+		}
+		return false
+	}
+
+listing:
+	for _, key := range keys {
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				commonPrefix := key[:len(prefix)+i+len(delimiter)]
+				if commonPrefix == lastPrefix || commonPrefix <= keyMarker {
+					continue
+				}
+				if full() {
+					break
+				}
+				result.CommonPrefixes = append(result.CommonPrefixes, model.CommonPrefix{Prefix: commonPrefix})
+				count++
+				lastPrefix = commonPrefix
+				lastKey, lastVersionId = commonPrefix, ""
+				continue
+			}
+		}
+
+		versions := b.history(key)
+		skipping := key == keyMarker
+		if skipping && versionIdMarker == "" {
+			continue
+		}
+		for i := len(versions) - 1; i >= 0; i-- {
+			v := versions[i]
+			if skipping {
+				skipping = versionIdOf(v) != versionIdMarker
+				continue
+			}
+			if full() {
+				break listing
+			}
+
+			lastModified := v.lastModified.UTC().Format(timeFormat)
+			if v.deleteMarker {
+				result.DeleteMarkers = append(result.DeleteMarkers, model.DeleteMarker{
+					Key:          key,
+					VersionId:    versionIdOf(v),
+					IsLatest:     i == len(versions)-1,
+					LastModified: lastModified,
+				})
+			} else {
+				result.Versions = append(result.Versions, model.ObjectVersion{
+					Key:          key,
+					VersionId:    versionIdOf(v),
+					IsLatest:     i == len(versions)-1,
+					LastModified: lastModified,
+					Etag:         v.etag,
+					Size:         v.size,
+				})
+			}
+			count++
+			lastKey, lastVersionId = key, versionIdOf(v)
+		}
+	}
+
+	server.writeXml(req, result)
+}
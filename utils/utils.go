@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"github.com/NetEase-Object-Storage/nos-golang-sdk/model"
@@ -10,12 +11,21 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"path"
 	"strconv"
 	"strings"
 	"unicode"
 	"runtime"
+	"time"
 )
 
+// IsRelativePath reports whether rel, a slash-separated path, can name a file
+// below the directory it is relative to.
+func IsRelativePath(rel string) bool {
+	return rel != "" && path.Clean(rel) == rel && !path.IsAbs(rel) &&
+		rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
 // VerifyObjectName check if the BucketName is legal
 func VerifyBucketName(bucketName string) bool {
 	if bucketName == "" {
@@ -176,6 +186,56 @@ func ProcessClientError(statCode int, bucket, object string, msg string) error {
 	return clientError
 }
 
+// WrapClientError is like ProcessClientError, with the message of err, which
+// is kept as the cause of the returned error.
+func WrapClientError(statCode int, bucket, object string, err error) error {
+	var resource string
+	if bucket != "" {
+		resource += "/" + bucket
+	}
+	if object != "" {
+		resource += "/" + object
+	}
+	return noserror.WrapClientError(statCode, resource, err)
+}
+
+type requestStartKey struct{}
+
+// WithRequestStart returns a copy of ctx recording start as the time the
+// request it carries is sent, from which ProcessServerError measures the
+// duration of the request.
+func WithRequestStart(ctx context.Context, start time.Time) context.Context {
+	return context.WithValue(ctx, requestStartKey{}, start)
+}
+
+// redactedParams are the query parameters left out of the URLs of errors.
+var redactedParams = []string{nosconst.PRESIGN_SIGNATURE, nosconst.PRESIGN_SECURITY_TOKEN}
+
+// redactURL returns the URL of request, without the values of its
+// redactedParams.
+func redactURL(request *http.Request) string {
+	u := *request.URL
+	if strings.Contains(u.Opaque, "://") {
+		// The opaque URL of NOS requests is the URL without its query.
+		opaque, err := url.Parse(u.Opaque)
+		if err == nil {
+			opaque.RawQuery = u.RawQuery
+			u = *opaque
+		}
+	}
+
+	query := u.Query()
+	for _, param := range redactedParams {
+		if _, ok := query[param]; ok {
+			query.Set(param, "REDACTED")
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// ProcessServerError returns the error of response, whose body it reads, up
+// to MAX_ERRORBODY bytes, but does not close.
 func ProcessServerError(response *http.Response, bucketName, objectName string) error {
 	var nosErr *noserror.NosError
 
@@ -186,9 +246,20 @@ func ProcessServerError(response *http.Response, bucketName, objectName string)
 	serverError := &noserror.ServerError{
 		StatusCode: response.StatusCode,
 		RequestId:  requestId,
+		Header:     response.Header.Clone(),
+	}
+	var start time.Time
+	if request := response.Request; request != nil {
+		serverError.Method = request.Method
+		serverError.URL = redactURL(request)
+		start, _ = request.Context().Value(requestStartKey{}).(time.Time)
 	}
 
-	content, err := ioutil.ReadAll(response.Body)
+	content, err := ioutil.ReadAll(io.LimitReader(response.Body, nosconst.MAX_ERRORBODY))
+	serverError.Body = content
+	if !start.IsZero() {
+		serverError.Duration = time.Since(start)
+	}
 	if err != nil {
 		nosErr = noserror.NewNosError("", noserror.ERROR_MSG_READCONTENT_ERROR, resource, requestId)
 		serverError.NosErr = nosErr
@@ -1,11 +1,16 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/nosconst"
+	"github.com/NetEase-Object-Storage/nos-golang-sdk/noserror"
 	. "gopkg.in/check.v1"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 )
 
 func Test(t *testing.T) { TestingT(t) }
@@ -127,10 +132,10 @@ func (s *UtilsTestSuite) TestVerifyObjectName(c *C) {
 }
 
 func (s *UtilsTestSuite) TestProcessClientError(c *C) {
-	err := ProcessClientError(400, "", "")
+	err := ProcessClientError(400, "", "", "")
 	c.Assert(err.Error(), Equals, "StatusCode = 400, Resource = , Message = ")
 
-	err = ProcessClientError(400, "123", "123")
+	err = ProcessClientError(400, "123", "123", "")
 	c.Assert(err.Error(), Equals, "StatusCode = 400, Resource = /123/123, Message = ")
 }
 
@@ -180,3 +185,34 @@ func (s *UtilsTestSuite) TestProcessServerError(c *C) {
 	err = ProcessServerError(response, "123", "123")
 	c.Assert(err, NotNil)
 }
+
+func (s *UtilsTestSuite) TestServerErrorDetails(c *C) {
+	ctx := WithRequestStart(context.Background(), time.Now().Add(-time.Millisecond))
+	request, err := http.NewRequestWithContext(ctx, "DELETE",
+		"http://nos.netease.com/bucket/dir%2Fobject?"+nosconst.VERSIONID+"=v1", nil)
+	c.Assert(err, IsNil)
+	request.URL.Opaque = "http://nos.netease.com/bucket/dir%2Fobject"
+
+	response := &http.Response{
+		StatusCode: http.StatusBadGateway,
+		Header: http.Header{
+			"X-Nos-Request-Id": {"request-1"},
+			"X-Upstream":       {"gateway-7"},
+		},
+		Body:    ioutil.NopCloser(strings.NewReader("<html>bad gateway</html>" + strings.Repeat(".", nosconst.MAX_ERRORBODY))),
+		Request: request,
+	}
+
+	err = ProcessServerError(response, "bucket", "dir/object")
+	var serverError *noserror.ServerError
+	c.Assert(errors.As(err, &serverError), Equals, true, Commentf("%v", err))
+	c.Assert(serverError.StatusCode, Equals, http.StatusBadGateway)
+	c.Assert(serverError.RequestId, Equals, "request-1")
+	c.Assert(serverError.NosErr.Message, Equals, noserror.ERROR_MSG_PARSEXML_ERROR)
+	c.Assert(serverError.Method, Equals, "DELETE")
+	c.Assert(serverError.URL, Equals, "http://nos.netease.com/bucket/dir%2Fobject?"+nosconst.VERSIONID+"=v1")
+	c.Assert(serverError.Header.Get("X-Upstream"), Equals, "gateway-7")
+	c.Assert(serverError.Body, HasLen, nosconst.MAX_ERRORBODY)
+	c.Assert(strings.HasPrefix(string(serverError.Body), "<html>bad gateway</html>"), Equals, true)
+	c.Assert(serverError.Duration > 0, Equals, true)
+}